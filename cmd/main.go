@@ -38,6 +38,7 @@ func main() {
 		zap.Int("version", 1),
 	)
 	db := db.ConnectDatabase(logger.Log, dsn, cfg.DBName)
+	defer db.Close()
 	redis := store.Config{
 		Addr:      fmt.Sprintf("%s:%d", cfg.RedisHost, cfg.RedisPort),
 		Password:  cfg.RedisPassword,
@@ -48,7 +49,7 @@ func main() {
 		logger.Log.Warn("redis_init_partial", zap.Error(err))
 	}
 	defer cs.Close(logger.Log)
-	router := api.InitRouter(db, logger.Log, cs, cfg)
+	router, shutdown := api.InitRouter(db, logger.Log, cs, cfg)
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", cfg.ServerPort),
 		Handler: router,
@@ -68,5 +69,8 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Log.Error("Server forced to shutdown", zap.Error(err))
 	}
+	if err := shutdown(ctx); err != nil {
+		logger.Log.Error("Background work not flushed", zap.Error(err))
+	}
 	logger.Log.Info("Server exiting")
 }
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdminUserHandler struct {
+	authService *service.AuthService
+}
+
+func NewAdminUserHandler(authService *service.AuthService) *AdminUserHandler {
+	return &AdminUserHandler{authService: authService}
+}
+
+type UserListSuccess = dto.BaseResponse[dto.PaginationResponse[service.UserView]]
+
+// @BasePath /api/v1
+// SearchUsers godoc
+// @Summary      Search users
+// @Description  Search users by name with pagination and sorting
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        query      query     string  false  "Part of the full name"
+// @Param        sort_by    query     string  false  "full_name, email or created_at"  default(full_name)
+// @Param        order_by   query     string  false  "asc or desc"                     default(asc)
+// @Param        page       query     int     false  "Page"       default(1)
+// @Param        page_size  query     int     false  "Page size"  default(20)
+// @Success      200        {object}  UserListSuccess
+// @Failure      400        {object}  dto.ErrorResponse
+// @Failure      401        {object}  dto.ErrorResponse
+// @Failure      403        {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/users [get]
+func (h *AdminUserHandler) Search(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req dto.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid pagination", traceID, reqTime, err))
+		return
+	}
+	users, total, err := h.authService.SearchUsers(c.Request.Context(), req.Query, req.SortBy, req.OrderBy, req.PageSize, req.Offset())
+	if err != nil {
+		writeError(c, err, "Search users failed", traceID, reqTime)
+		return
+	}
+	page := dto.NewPaginationResponse(users, total, req.Page, req.PageSize)
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Search users success", traceID, page, reqTime))
+}
+
+type UserDetailSuccess = dto.BaseResponse[service.UserDetail]
+
+// @BasePath /api/v1
+// GetUser godoc
+// @Summary      Get a user
+// @Description  A user with roles, devices and live sessions
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path      string  true  "User ID"
+// @Success      200      {object}  UserDetailSuccess
+// @Failure      400      {object}  dto.ErrorResponse
+// @Failure      403      {object}  dto.ErrorResponse
+// @Failure      404      {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/users/{user_id} [get]
+func (h *AdminUserHandler) Get(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	userID, ok := userIDParam(c, traceID, reqTime)
+	if !ok {
+		return
+	}
+	detail, err := h.authService.GetUserDetail(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err, "Get user failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Get user success", traceID, *detail, reqTime))
+}
+
+type AdminUpdateUserRequest struct {
+	FullName  *string `json:"full_name" binding:"omitempty,max=255"`
+	Bio       *string `json:"bio" binding:"omitempty,max=1024"`
+	AvatarURL *string `json:"avatar_url" binding:"omitempty,max=2048,http_url|len=0"`
+	// Email takes effect immediately; the user is asked to verify it again.
+	Email *string `json:"email" binding:"omitempty,email"`
+}
+
+type UserSuccess = dto.BaseResponse[service.UserView]
+
+// @BasePath /api/v1
+// UpdateUser godoc
+// @Summary      Update a user
+// @Description  Edit profile fields of a user; omitted fields are unchanged
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path      string                  true  "User ID"
+// @Param        data     body      AdminUpdateUserRequest  true  "Profile fields"
+// @Success      200      {object}  UserSuccess
+// @Failure      400      {object}  dto.ErrorResponse
+// @Failure      403      {object}  dto.ErrorResponse
+// @Failure      404      {object}  dto.ErrorResponse
+// @Failure      409      {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/users/{user_id} [patch]
+func (h *AdminUserHandler) Update(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	userID, ok := userIDParam(c, traceID, reqTime)
+	if !ok {
+		return
+	}
+	var req AdminUpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid user payload", traceID, reqTime, err))
+		return
+	}
+	u, err := h.authService.UpdateUser(c.Request.Context(), userID, service.UserUpdate{
+		FullName:  req.FullName,
+		Bio:       req.Bio,
+		AvatarURL: req.AvatarURL,
+		Email:     req.Email,
+	})
+	if err != nil {
+		writeError(c, err, "Update user failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Update user success", traceID, *u, reqTime))
+}
+
+type UserActionSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// ActivateUser godoc
+// @Summary      Activate a user
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path      string  true  "User ID"
+// @Success      200      {object}  UserActionSuccess
+// @Failure      400      {object}  dto.ErrorResponse
+// @Failure      403      {object}  dto.ErrorResponse
+// @Failure      404      {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/users/{user_id}/activate [post]
+func (h *AdminUserHandler) Activate(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+// @BasePath /api/v1
+// DeactivateUser godoc
+// @Summary      Deactivate a user
+// @Description  Deactivate a user and revoke all of their sessions and tokens
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path      string  true  "User ID"
+// @Success      200      {object}  UserActionSuccess
+// @Failure      400      {object}  dto.ErrorResponse
+// @Failure      403      {object}  dto.ErrorResponse
+// @Failure      404      {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/users/{user_id}/deactivate [post]
+func (h *AdminUserHandler) Deactivate(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+func (h *AdminUserHandler) setActive(c *gin.Context, active bool) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	userID, ok := userIDParam(c, traceID, reqTime)
+	if !ok {
+		return
+	}
+	action := "Activate user"
+	if !active {
+		action = "Deactivate user"
+	}
+	if err := h.authService.SetUserActive(c.Request.Context(), userID, active); err != nil {
+		writeError(c, err, action+" failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, action+" success", traceID, reqTime))
+}
+
+// @BasePath /api/v1
+// ForcePasswordReset godoc
+// @Summary      Force a password reset
+// @Description  Invalidate the user's password, revoke all sessions and mail a reset link
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path      string  true  "User ID"
+// @Success      200      {object}  UserActionSuccess
+// @Failure      400      {object}  dto.ErrorResponse
+// @Failure      403      {object}  dto.ErrorResponse
+// @Failure      404      {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/users/{user_id}/password-reset [post]
+func (h *AdminUserHandler) ForcePasswordReset(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	userID, ok := userIDParam(c, traceID, reqTime)
+	if !ok {
+		return
+	}
+	if err := h.authService.ForcePasswordReset(c.Request.Context(), userID); err != nil {
+		writeError(c, err, "Force password reset failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Force password reset success", traceID, reqTime))
+}
+
+// @BasePath /api/v1
+// RestoreUser godoc
+// @Summary      Restore a deleted user
+// @Description  Undo a self-service account deletion before the personal data is purged
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path      string  true  "User ID"
+// @Success      200      {object}  UserActionSuccess
+// @Failure      400      {object}  dto.ErrorResponse
+// @Failure      403      {object}  dto.ErrorResponse
+// @Failure      404      {object}  dto.ErrorResponse
+// @Failure      409      {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/users/{user_id}/restore [post]
+func (h *AdminUserHandler) Restore(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	userID, ok := userIDParam(c, traceID, reqTime)
+	if !ok {
+		return
+	}
+	if err := h.authService.RestoreUser(c.Request.Context(), userID); err != nil {
+		writeError(c, err, "Restore user failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Restore user success", traceID, reqTime))
+}
+
+func userIDParam(c *gin.Context, traceID string, reqTime time.Time) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeBadRequest, "Invalid user id", traceID, reqTime, err))
+		return uuid.Nil, false
+	}
+	return userID, true
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APITokenHandler struct {
+	authService *service.AuthService
+}
+
+func NewAPITokenHandler(authService *service.AuthService) *APITokenHandler {
+	return &APITokenHandler{authService: authService}
+}
+
+type CreateAPITokenRequest struct {
+	Name   string   `json:"name" binding:"required,max=64"`
+	Kind   string   `json:"kind" binding:"omitempty,oneof=personal service"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,required"`
+	// 0 picks 90 days for personal tokens; service keys must set it.
+	// Tokens live at most 365 days.
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=0"`
+}
+
+type RenameAPITokenRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+type APITokenSuccess = dto.BaseResponse[service.APIToken]
+type APITokenListSuccess = dto.BaseResponse[[]service.APIToken]
+type NewAPITokenSuccess = dto.BaseResponse[service.NewAPIToken]
+type APITokenEmptySuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// ListAPITokens godoc
+// @Summary      List my API tokens
+// @Tags         profile
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  APITokenListSuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Router       /api/v1/me/tokens [get]
+func (h *APITokenHandler) List(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	tokens, err := h.authService.ListAPITokens(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		writeError(c, err, "List API tokens failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "List API tokens success", traceID, tokens, reqTime))
+}
+
+// @BasePath /api/v1
+// CreateAPIToken godoc
+// @Summary      Create an API token
+// @Description  Creates a personal access token (sbd_pat_...) or, for administrators who logged in within the last 10 minutes, a service API key (sbd_svc_...) with an explicit expiry. Scopes are permissions the user holds. The token is only shown in this response; send it as "Authorization: Bearer <token>".
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        data  body      CreateAPITokenRequest  true  "Token settings"
+// @Success      200   {object}  NewAPITokenSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      401   {object}  dto.ErrorResponse
+// @Failure      403   {object}  dto.ErrorResponse
+// @Router       /api/v1/me/tokens [post]
+func (h *APITokenHandler) Create(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid API token payload", traceID, reqTime, err))
+		return
+	}
+	token, err := h.authService.CreateAPIToken(c.Request.Context(), middleware.GetUserID(c), middleware.GetSessionID(c), model.APITokenCmd{
+		Kind:      req.Kind,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresIn: time.Duration(req.ExpiresInDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		writeError(c, err, "Create API token failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Create API token success", traceID, token, reqTime))
+}
+
+// @BasePath /api/v1
+// GetAPIToken godoc
+// @Summary      Get one of my API tokens
+// @Tags         profile
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Token ID"
+// @Success      200  {object}  APITokenSuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Failure      404  {object}  dto.ErrorResponse
+// @Router       /api/v1/me/tokens/{id} [get]
+func (h *APITokenHandler) Get(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	token, err := h.authService.GetAPIToken(c.Request.Context(), middleware.GetUserID(c), c.Param("id"))
+	if err != nil {
+		writeError(c, err, "Get API token failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Get API token success", traceID, token, reqTime))
+}
+
+// @BasePath /api/v1
+// RenameAPIToken godoc
+// @Summary      Rename one of my API tokens
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string                 true  "Token ID"
+// @Param        data  body      RenameAPITokenRequest  true  "New name"
+// @Success      200   {object}  APITokenEmptySuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      401   {object}  dto.ErrorResponse
+// @Failure      404   {object}  dto.ErrorResponse
+// @Router       /api/v1/me/tokens/{id} [patch]
+func (h *APITokenHandler) Rename(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req RenameAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid API token payload", traceID, reqTime, err))
+		return
+	}
+	if err := h.authService.RenameAPIToken(c.Request.Context(), middleware.GetUserID(c), c.Param("id"), req.Name); err != nil {
+		writeError(c, err, "Rename API token failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Rename API token success", traceID, reqTime))
+}
+
+// @BasePath /api/v1
+// DeleteAPIToken godoc
+// @Summary      Revoke one of my API tokens
+// @Tags         profile
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Token ID"
+// @Success      200  {object}  APITokenEmptySuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Failure      404  {object}  dto.ErrorResponse
+// @Router       /api/v1/me/tokens/{id} [delete]
+func (h *APITokenHandler) Delete(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	if err := h.authService.DeleteAPIToken(c.Request.Context(), middleware.GetUserID(c), c.Param("id")); err != nil {
+		writeError(c, err, "Delete API token failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Delete API token success", traceID, reqTime))
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AuditHandler struct {
+	authService *service.AuthService
+}
+
+func NewAuditHandler(authService *service.AuthService) *AuditHandler {
+	return &AuditHandler{authService: authService}
+}
+
+type AuditEventQuery struct {
+	dto.PaginationRequest
+	Type    string    `form:"type"`
+	UserID  string    `form:"user_id" binding:"omitempty,uuid"`
+	ActorID string    `form:"actor_id" binding:"omitempty,uuid"`
+	IP      string    `form:"ip" binding:"omitempty,ip"`
+	Since   time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until   time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type AuditEventListSuccess = dto.BaseResponse[dto.PaginationResponse[service.AuditRecord]]
+
+// @BasePath /api/v1
+// ListSecurityEvents godoc
+// @Summary      List my security events
+// @Description  Logins, failed logins, logouts, password changes and other security events of the current user, newest first
+// @Tags         audit
+// @Produce      json
+// @Security     BearerAuth
+// @Param        type       query     string  false  "Event type, e.g. login.failure"
+// @Param        since      query     string  false  "RFC 3339 lower bound (inclusive)"
+// @Param        until      query     string  false  "RFC 3339 upper bound (exclusive)"
+// @Param        page       query     int     false  "Page"       default(1)
+// @Param        page_size  query     int     false  "Page size"  default(20)
+// @Success      200        {object}  AuditEventListSuccess
+// @Failure      400        {object}  dto.ErrorResponse
+// @Failure      401        {object}  dto.ErrorResponse
+// @Router       /api/v1/me/security-events [get]
+func (h *AuditHandler) Mine(c *gin.Context) {
+	var q AuditEventQuery
+	if !h.bindQuery(c, &q) {
+		return
+	}
+	userID, _ := uuid.Parse(middleware.GetUserID(c))
+	h.list(c, q, service.AuditFilter{
+		SubjectID: userID,
+		Type:      q.Type,
+		Since:     q.Since,
+		Until:     q.Until,
+	})
+}
+
+// @BasePath /api/v1
+// ListAuditEvents godoc
+// @Summary      Search the audit log
+// @Description  Security events of all users, newest first, filtered by subject, actor, type, IP and time range
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id    query     string  false  "Subject user ID"
+// @Param        actor_id   query     string  false  "Actor user ID"
+// @Param        type       query     string  false  "Event type, e.g. refresh.reuse"
+// @Param        ip         query     string  false  "Client IP"
+// @Param        since      query     string  false  "RFC 3339 lower bound (inclusive)"
+// @Param        until      query     string  false  "RFC 3339 upper bound (exclusive)"
+// @Param        page       query     int     false  "Page"       default(1)
+// @Param        page_size  query     int     false  "Page size"  default(20)
+// @Success      200        {object}  AuditEventListSuccess
+// @Failure      400        {object}  dto.ErrorResponse
+// @Failure      401        {object}  dto.ErrorResponse
+// @Failure      403        {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/audit-events [get]
+func (h *AuditHandler) List(c *gin.Context) {
+	var q AuditEventQuery
+	if !h.bindQuery(c, &q) {
+		return
+	}
+	f := service.AuditFilter{Type: q.Type, IP: q.IP, Since: q.Since, Until: q.Until}
+	f.SubjectID, _ = uuid.Parse(q.UserID)
+	f.ActorID, _ = uuid.Parse(q.ActorID)
+	h.list(c, q, f)
+}
+
+func (h *AuditHandler) bindQuery(c *gin.Context, q *AuditEventQuery) bool {
+	if err := c.ShouldBindQuery(q); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid audit query", middleware.TraceID(c), time.Now().UTC(), err))
+		return false
+	}
+	return true
+}
+
+func (h *AuditHandler) list(c *gin.Context, q AuditEventQuery, f service.AuditFilter) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	events, total, err := h.authService.ListAuditEvents(c.Request.Context(), f, q.PageSize, q.Offset())
+	if err != nil {
+		writeError(c, err, "List audit events failed", traceID, reqTime)
+		return
+	}
+	page := dto.NewPaginationResponse(events, total, q.Page, q.PageSize)
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "List audit events success", traceID, page, reqTime))
+}
@@ -4,11 +4,11 @@ import (
 	"net/http"
 	"time"
 
+	"seno-blackdragon/internal/api/middleware"
 	"seno-blackdragon/internal/model"
 	"seno-blackdragon/internal/service"
 	"seno-blackdragon/pkg/dto"
 	"seno-blackdragon/pkg/enum"
-	"seno-blackdragon/pkg/middleware"
 	"seno-blackdragon/pkg/pass"
 
 	"github.com/gin-gonic/gin"
@@ -30,14 +30,29 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	TokenType    string `json:"token_type"` // e.g. "Bearer"
-	Expires      int64  `json:"expires"`    // seconds until access token expires
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"` // e.g. "Bearer"
+	Expires      int64  `json:"expires,omitempty"`    // seconds until access token expires
+
+	// Set instead of the tokens when the account has a second factor.
+	MFARequired bool     `json:"mfa_required,omitempty"`
+	MFAToken    string   `json:"mfa_token,omitempty"`
+	MFAExpires  int64    `json:"mfa_expires,omitempty"`
+	MFAMethods  []string `json:"mfa_methods,omitempty"`
 }
 
 type LoginSuccess = dto.BaseResponse[LoginResponse]
 
+func tokenResponse(token *model.TokenPair) LoginResponse {
+	return LoginResponse{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    "Bearer",
+		Expires:      token.Expired,
+	}
+}
+
 // @BasePath /api/v1
 // Login godoc
 // @Summary      Login
@@ -49,13 +64,12 @@ type LoginSuccess = dto.BaseResponse[LoginResponse]
 // @Success      200   {object}  LoginSuccess
 // @Failure      400   {object}  dto.ErrorResponse
 // @Failure      401   {object}  dto.ErrorResponse
+// @Failure      423   {object}  dto.ErrorResponse
+// @Failure      429   {object}  dto.ErrorResponse
 // @Router       /api/v1/auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	reqTime := time.Now().UTC()
-	traceID := c.GetString(middleware.ContextKeyTraceID)
-	if traceID == "" {
-		traceID = c.GetHeader(middleware.HeaderKeyTraceID)
-	}
+	traceID := middleware.TraceID(c)
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeInvalidToken,
@@ -68,25 +82,35 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 	cmd := model.LoginCmd{
-		Email:    req.Email,
-		Password: req.Password,
-		DeviceID: req.DeviceID,
-		IP:       c.ClientIP(),
-		UA:       c.GetHeader("User-Agent"),
+		Email:      req.Email,
+		Password:   req.Password,
+		DeviceID:   req.DeviceID,
+		DeviceMeta: req.DeviceMeta,
+		IP:         c.ClientIP(),
+		UA:         c.GetHeader("User-Agent"),
 	}
-	token, err := h.authService.Login(c.Request.Context(), cmd)
+	result, err := h.authService.Login(c.Request.Context(), cmd)
 	if err != nil {
-		dto.WriteJSON(c, http.StatusUnauthorized, dto.NewError(http.StatusUnauthorized, enum.CodeAuth,
-			"Invalid email or password", traceID, reqTime, err))
+		writeError(c, err, "Login failed", traceID, reqTime)
 		return
 	}
-	resp := LoginResponse{
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		TokenType:    "Bearer",
-		Expires:      token.Expired,
+	writeLoginResult(c, result, traceID, reqTime)
+}
+
+// writeLoginResult answers a successful first factor with the token pair or,
+// for accounts with a second factor, the MFA challenge.
+func writeLoginResult(c *gin.Context, result *model.LoginResult, traceID string, reqTime time.Time) {
+	if ch := result.MFAChallenge; ch != nil {
+		resp := LoginResponse{
+			MFARequired: true,
+			MFAToken:    ch.Token,
+			MFAExpires:  ch.Expired,
+			MFAMethods:  ch.Methods,
+		}
+		dto.Ok(c, dto.NewSuccess(http.StatusOK, "MFA required", traceID, resp, reqTime))
+		return
 	}
-	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Login success", traceID, resp, reqTime))
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Login success", traceID, tokenResponse(result.Token), reqTime))
 }
 
 type RegisterRequest struct {
@@ -109,13 +133,12 @@ type RegisterSuccess = dto.BaseResponse[dto.EmptyData]
 // @Success      200   {object}  RegisterSuccess
 // @Failure      400   {object}  dto.ErrorResponse
 // @Failure      401   {object}  dto.ErrorResponse
+// @Failure      409   {object}  dto.ErrorResponse
+// @Failure      429   {object}  dto.ErrorResponse
 // @Router       /api/v1/auth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
 	reqTime := time.Now().UTC()
-	traceID := c.GetString(middleware.ContextKeyTraceID)
-	if traceID == "" {
-		traceID = c.GetHeader(middleware.HeaderKeyTraceID)
-	}
+	traceID := middleware.TraceID(c)
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeInvalidToken,
@@ -129,8 +152,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 	_, err := h.authService.Register(c.Request.Context(), req.FullName, req.Bio, req.Email, req.Password)
 	if err != nil {
-		dto.WriteJSON(c, http.StatusBadRequest, dto.NewError(http.StatusBadRequest, enum.CodeBusinessError,
-			"Register user failed", traceID, reqTime, err))
+		writeError(c, err, "Register user failed", traceID, reqTime)
 		return
 	}
 
@@ -138,48 +160,101 @@ func (h *AuthHandler) Register(c *gin.Context) {
 }
 
 type RefreshTokenRequest struct {
-	RefreshToken string `json:"token_type"`
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
-type RefreshTokenSuccess = dto.BaseResponse[RefreshTokenRequest]
+type RefreshTokenSuccess = dto.BaseResponse[LoginResponse]
 
 // @BasePath /api/v1
-// Register godoc
+// Refresh godoc
 // @Summary      Refresh token
-// @Description  Refresh token
+// @Description  Rotate a refresh token and issue a new access token
 // @Tags         auth
 // @Accept       json
 // @Produce      json
-// @Param        data  body      RefreshTokenRequest  true  "Register User"
+// @Param        data  body      RefreshTokenRequest  true  "Refresh token"
 // @Success      200   {object}  RefreshTokenSuccess
 // @Failure      400   {object}  dto.ErrorResponse
 // @Failure      401   {object}  dto.ErrorResponse
-// @Router       /api/v1/auth/register [post]
+// @Failure      409   {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/refresh [post]
 func (h *AuthHandler) Refresh(c *gin.Context) {
 	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
 	var req RefreshTokenRequest
-	traceID := c.GetString(middleware.ContextKeyTraceID)
-	if traceID == "" {
-		traceID = c.GetHeader(middleware.HeaderKeyTraceID)
-	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeBadRequest, "Invalid refresh token request", traceID, reqTime, err))
 		return
 	}
-	if req.RefreshToken == "" {
-		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeBadRequest, "Invalid refresh token", traceID, reqTime, enum.ErrWrongType))
+	token, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		writeError(c, err, "Refresh token failed", traceID, reqTime)
 		return
 	}
-	token, err := h.authService.Refresh(c, req.RefreshToken)
-	if err != nil {
-		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeBadRequest, "error", traceID, reqTime, err))
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Refresh token success", traceID, tokenResponse(token), reqTime))
+}
+
+type LogoutSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// Logout godoc
+// @Summary      Logout
+// @Description  End the current session and revoke its refresh token family
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200   {object}  LogoutSuccess
+// @Failure      401   {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	if err := h.authService.Logout(c.Request.Context(), middleware.GetUserID(c), middleware.GetSessionID(c)); err != nil {
+		writeError(c, err, "Logout failed", traceID, reqTime)
+		return
 	}
-	resp := LoginResponse{
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		TokenType:    "Bearer",
-		Expires:      token.Expired,
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Logout success", traceID, reqTime))
+}
+
+// @BasePath /api/v1
+// LogoutDevice godoc
+// @Summary      Logout device
+// @Description  Revoke every session and refresh token family of one of my devices
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Param        device_id  path      string  true  "Device ID"
+// @Success      200        {object}  LogoutSuccess
+// @Failure      401        {object}  dto.ErrorResponse
+// @Failure      404        {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/logout/device/{device_id} [post]
+func (h *AuthHandler) LogoutDevice(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	deviceID := c.Param("device_id")
+	if err := h.authService.LogoutDevice(c.Request.Context(), middleware.GetUserID(c), deviceID); err != nil {
+		writeError(c, err, "Logout device failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Logout device success", traceID, reqTime))
+}
+
+// @BasePath /api/v1
+// LogoutAll godoc
+// @Summary      Logout everywhere
+// @Description  Revoke all sessions, refresh tokens and personal access tokens of the current user
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200   {object}  LogoutSuccess
+// @Failure      401   {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/logout/all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	if err := h.authService.LogoutAll(c.Request.Context(), middleware.GetUserID(c)); err != nil {
+		writeError(c, err, "Logout all failed", traceID, reqTime)
+		return
 	}
-	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Refresh token success", traceID, resp, reqTime))
-	return
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Logout all success", traceID, reqTime))
 }
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeviceHandler struct {
+	authService *service.AuthService
+}
+
+func NewDeviceHandler(authService *service.AuthService) *DeviceHandler {
+	return &DeviceHandler{authService: authService}
+}
+
+type DeviceListSuccess = dto.BaseResponse[dto.PaginationResponse[service.DeviceItem]]
+
+// @BasePath /api/v1
+// ListDevices godoc
+// @Summary      List my devices
+// @Description  List devices the current user has logged in from; the caller's device is marked as current
+// @Tags         devices
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page       query     int  false  "Page"       default(1)
+// @Param        page_size  query     int  false  "Page size"  default(20)
+// @Success      200        {object}  DeviceListSuccess
+// @Failure      400        {object}  dto.ErrorResponse
+// @Failure      401        {object}  dto.ErrorResponse
+// @Router       /api/v1/me/devices [get]
+func (h *DeviceHandler) List(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req dto.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid pagination", traceID, reqTime, err))
+		return
+	}
+	items, err := h.authService.ListDevices(c.Request.Context(), middleware.GetUserID(c), middleware.GetDeviceID(c))
+	if err != nil {
+		writeError(c, err, "List devices failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "List devices success", traceID, dto.Paginate(items, req), reqTime))
+}
+
+type UpdateDeviceRequest struct {
+	Name    *string `json:"name" binding:"omitempty,max=64"`
+	Trusted *bool   `json:"trusted"`
+}
+
+type DeviceSuccess = dto.BaseResponse[service.Device]
+
+// @BasePath /api/v1
+// UpdateDevice godoc
+// @Summary      Update a device
+// @Description  Rename a device and/or mark it as trusted
+// @Tags         devices
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        device_id  path      string               true  "Device ID"
+// @Param        data       body      UpdateDeviceRequest  true  "Device fields"
+// @Success      200        {object}  DeviceSuccess
+// @Failure      400        {object}  dto.ErrorResponse
+// @Failure      401        {object}  dto.ErrorResponse
+// @Failure      404        {object}  dto.ErrorResponse
+// @Router       /api/v1/me/devices/{device_id} [patch]
+func (h *DeviceHandler) Update(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req UpdateDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid device payload", traceID, reqTime, err))
+		return
+	}
+	dev, err := h.authService.UpdateDevice(c.Request.Context(), middleware.GetUserID(c), c.Param("device_id"), service.DeviceUpdate{
+		Name:    req.Name,
+		Trusted: req.Trusted,
+	})
+	if err != nil {
+		writeError(c, err, "Update device failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Update device success", traceID, *dev, reqTime))
+}
+
+type BlockDeviceSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// BlockDevice godoc
+// @Summary      Block a device
+// @Description  Block a device: its sessions and refresh tokens are revoked and it can no longer log in
+// @Tags         devices
+// @Produce      json
+// @Security     BearerAuth
+// @Param        device_id  path      string  true  "Device ID"
+// @Success      200        {object}  BlockDeviceSuccess
+// @Failure      401        {object}  dto.ErrorResponse
+// @Failure      404        {object}  dto.ErrorResponse
+// @Router       /api/v1/me/devices/{device_id}/block [post]
+func (h *DeviceHandler) Block(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	if err := h.authService.BlockDevice(c.Request.Context(), middleware.GetUserID(c), c.Param("device_id")); err != nil {
+		writeError(c, err, "Block device failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Block device success", traceID, reqTime))
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailHandler struct {
+	authService *service.AuthService
+}
+
+func NewEmailHandler(authService *service.AuthService) *EmailHandler {
+	return &EmailHandler{authService: authService}
+}
+
+type VerifyEmailSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// VerifyEmail godoc
+// @Summary      Verify email
+// @Description  Confirm ownership of the email with the token sent at registration
+// @Tags         auth
+// @Produce      json
+// @Param        token  query     string  true  "Verification token"
+// @Success      200    {object}  VerifyEmailSuccess
+// @Failure      400    {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/verify-email [get]
+func (h *EmailHandler) Verify(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	token := c.Query("token")
+	if token == "" {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Missing verification token", traceID, reqTime, enum.ErrVerifyTokenInvalid))
+		return
+	}
+	if err := h.authService.VerifyEmail(c.Request.Context(), token); err != nil {
+		writeError(c, err, "Verify email failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Verify email success", traceID, reqTime))
+}
+
+type ConfirmEmailChangeSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// ConfirmEmailChange godoc
+// @Summary      Confirm email change
+// @Description  Commit a new email address with the token sent to it; the address counts as verified
+// @Tags         auth
+// @Produce      json
+// @Param        token  query     string  true  "Confirmation token"
+// @Success      200    {object}  ConfirmEmailChangeSuccess
+// @Failure      400    {object}  dto.ErrorResponse
+// @Failure      409    {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/email-change/confirm [get]
+func (h *EmailHandler) ConfirmChange(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	token := c.Query("token")
+	if token == "" {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Missing confirmation token", traceID, reqTime, enum.ErrVerifyTokenInvalid))
+		return
+	}
+	if err := h.authService.ConfirmEmailChange(c.Request.Context(), token); err != nil {
+		writeError(c, err, "Confirm email change failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Confirm email change success", traceID, reqTime))
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResendVerificationSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// ResendVerification godoc
+// @Summary      Resend verification email
+// @Description  Send a new verification token if the account exists and is unverified. Throttled per account; always succeeds to avoid account enumeration.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        data  body      ResendVerificationRequest  true  "Account email"
+// @Success      200   {object}  ResendVerificationSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/verify-email/resend [post]
+func (h *EmailHandler) Resend(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid resend payload", traceID, reqTime, err))
+		return
+	}
+	if err := h.authService.ResendEmailVerification(c.Request.Context(), req.Email); err != nil {
+		writeError(c, err, "Resend verification failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "If the account needs verification, an email has been sent", traceID, reqTime))
+}
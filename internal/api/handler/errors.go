@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorStatus maps service sentinel errors to an HTTP status and an enum.Code*.
+// Anything unknown is treated as an internal error.
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, enum.ErrInvalidToken):
+		return http.StatusUnauthorized, enum.CodeInvalidToken
+	case errors.Is(err, enum.ErrWrongType):
+		return http.StatusUnauthorized, enum.CodeWrongTokenType
+	case errors.Is(err, enum.ErrWrongAlgorithm):
+		return http.StatusUnauthorized, enum.CodeUnexpectedAlg
+	case errors.Is(err, enum.ErrInvalidCredentials):
+		return http.StatusUnauthorized, enum.CodeInvalidCredentials
+	case errors.Is(err, enum.ErrMissingToken):
+		return http.StatusUnauthorized, enum.CodeMissingToken
+
+	case errors.Is(err, enum.ErrRefreshNotActive):
+		return http.StatusUnauthorized, enum.CodeRefreshNotActive
+	case errors.Is(err, enum.ErrRefreshRevoked):
+		return http.StatusUnauthorized, enum.CodeRefreshRevoked
+	case errors.Is(err, enum.ErrFamilyBlocked):
+		return http.StatusUnauthorized, enum.CodeFamilyBlocked
+	case errors.Is(err, enum.ErrRotationRace):
+		return http.StatusConflict, enum.CodeRotationRace
+
+	case errors.Is(err, enum.ErrSessionRevoked):
+		return http.StatusUnauthorized, enum.CodeSessionRevoked
+	case errors.Is(err, enum.ErrDeviceBlocked):
+		return http.StatusForbidden, enum.CodeDeviceBlocked
+	case errors.Is(err, enum.ErrDeviceNotFound):
+		return http.StatusNotFound, enum.CodeDeviceNotFound
+	case errors.Is(err, enum.ErrReauthRequired):
+		return http.StatusForbidden, enum.CodeReauthRequired
+
+	case errors.Is(err, enum.ErrMFAInvalidCode):
+		return http.StatusUnauthorized, enum.CodeMFAInvalidCode
+	case errors.Is(err, enum.ErrMFAChallengeInvalid):
+		return http.StatusUnauthorized, enum.CodeMFAChallengeInvalid
+	case errors.Is(err, enum.ErrMFANotEnrolled):
+		return http.StatusBadRequest, enum.CodeMFANotEnrolled
+	case errors.Is(err, enum.ErrMFAAlreadyEnabled):
+		return http.StatusConflict, enum.CodeMFAAlreadyEnabled
+
+	case errors.Is(err, enum.ErrEmailNotVerified):
+		return http.StatusForbidden, enum.CodeEmailNotVerified
+	case errors.Is(err, enum.ErrVerifyTokenInvalid):
+		return http.StatusBadRequest, enum.CodeVerifyTokenInvalid
+
+	case errors.Is(err, enum.ErrResetTokenInvalid):
+		return http.StatusBadRequest, enum.CodeResetTokenInvalid
+	case errors.Is(err, enum.ErrWeakPassword):
+		return http.StatusBadRequest, enum.CodeWeakPassword
+
+	case errors.Is(err, enum.ErrForbidden):
+		return http.StatusForbidden, enum.CodeForbidden
+	case errors.Is(err, enum.ErrRoleNotFound):
+		return http.StatusNotFound, enum.CodeRoleNotFound
+
+	case errors.Is(err, enum.ErrOAuthInvalidRequest), errors.Is(err, enum.ErrOAuthUnsupportedResponseType):
+		return http.StatusBadRequest, enum.CodeOAuthInvalidRequest
+	case errors.Is(err, enum.ErrOAuthInvalidClient):
+		return http.StatusBadRequest, enum.CodeOAuthInvalidClient
+	case errors.Is(err, enum.ErrOAuthUnauthorizedClient):
+		return http.StatusBadRequest, enum.CodeOAuthUnauthorizedClient
+	case errors.Is(err, enum.ErrOAuthInvalidScope):
+		return http.StatusBadRequest, enum.CodeOAuthInvalidScope
+	case errors.Is(err, enum.ErrOAuthClientNotFound):
+		return http.StatusNotFound, enum.CodeOAuthClientNotFound
+	case errors.Is(err, enum.ErrInsufficientScope):
+		return http.StatusForbidden, enum.CodeInsufficientScope
+	case errors.Is(err, enum.ErrOIDCDisabled):
+		return http.StatusNotFound, enum.CodeOIDCDisabled
+
+	case errors.Is(err, enum.ErrSocialProviderNotFound):
+		return http.StatusNotFound, enum.CodeSocialProviderNotFound
+	case errors.Is(err, enum.ErrSocialStateInvalid):
+		return http.StatusBadRequest, enum.CodeSocialStateInvalid
+	case errors.Is(err, enum.ErrSocialLoginFailed):
+		return http.StatusUnauthorized, enum.CodeSocialLoginFailed
+	case errors.Is(err, enum.ErrSocialAccountExists):
+		return http.StatusConflict, enum.CodeSocialAccountExists
+	case errors.Is(err, enum.ErrIdentityLinked):
+		return http.StatusConflict, enum.CodeIdentityLinked
+	case errors.Is(err, enum.ErrIdentityNotFound):
+		return http.StatusNotFound, enum.CodeIdentityNotFound
+	case errors.Is(err, enum.ErrLastLoginMethod):
+		return http.StatusConflict, enum.CodeLastLoginMethod
+
+	case errors.Is(err, enum.ErrPasskeyChallengeInvalid):
+		return http.StatusBadRequest, enum.CodePasskeyChallengeInvalid
+	case errors.Is(err, enum.ErrPasskeyInvalid):
+		return http.StatusUnauthorized, enum.CodePasskeyInvalid
+	case errors.Is(err, enum.ErrPasskeyNotFound):
+		return http.StatusNotFound, enum.CodePasskeyNotFound
+	case errors.Is(err, enum.ErrPasskeyExists):
+		return http.StatusConflict, enum.CodePasskeyExists
+	case errors.Is(err, enum.ErrLoginCodeInvalid):
+		return http.StatusUnauthorized, enum.CodeLoginCodeInvalid
+
+	case errors.Is(err, enum.ErrAPITokenInvalid):
+		return http.StatusUnauthorized, enum.CodeAPITokenInvalid
+	case errors.Is(err, enum.ErrAPITokenNotFound):
+		return http.StatusNotFound, enum.CodeAPITokenNotFound
+	case errors.Is(err, enum.ErrAPITokenKind):
+		return http.StatusBadRequest, enum.CodeAPITokenKind
+	case errors.Is(err, enum.ErrAPITokenScope):
+		return http.StatusBadRequest, enum.CodeAPITokenScope
+	case errors.Is(err, enum.ErrAPITokenExpiry):
+		return http.StatusBadRequest, enum.CodeAPITokenExpiry
+
+	case errors.Is(err, enum.ErrRateLimited):
+		return http.StatusTooManyRequests, enum.CodeRateLimited
+	case errors.Is(err, enum.ErrAccountLocked):
+		return http.StatusLocked, enum.CodeAccountLocked
+	case errors.Is(err, enum.ErrIPBlocked):
+		return http.StatusForbidden, enum.CodeIPBlocked
+	case errors.Is(err, enum.ErrInvalidCIDR), errors.Is(err, enum.ErrInvalidIPList):
+		return http.StatusBadRequest, enum.CodeValidationFailed
+	case errors.Is(err, enum.ErrIPRuleNotFound):
+		return http.StatusNotFound, enum.CodeIPRuleNotFound
+
+	case errors.Is(err, enum.ErrUserNotFound):
+		return http.StatusNotFound, enum.CodeUserNotFound
+	case errors.Is(err, enum.ErrEmailAlready):
+		return http.StatusConflict, enum.CodeEmailConflict
+	case errors.Is(err, enum.ErrUserModified):
+		return http.StatusConflict, enum.CodeUserModified
+	case errors.Is(err, enum.ErrAccountDisabled):
+		return http.StatusForbidden, enum.CodeAccountDisabled
+	case errors.Is(err, enum.ErrInvalidSort):
+		return http.StatusBadRequest, enum.CodeValidationFailed
+	default:
+		return http.StatusInternalServerError, enum.CodeInternalError
+	}
+}
+
+// writeError writes the standard error envelope for a service error. Errors
+// that know when to retry (lockouts) also set Retry-After.
+func writeError(c *gin.Context, err error, message, traceID string, reqTime time.Time) {
+	status, code := errorStatus(err)
+	var ra interface{ RetryAfter() time.Duration }
+	if errors.As(err, &ra) {
+		c.Header("Retry-After", strconv.Itoa(max(int(math.Ceil(ra.RetryAfter().Seconds())), 1)))
+	}
+	dto.WriteJSON(c, status, dto.NewError(status, code, message, traceID, reqTime, err))
+}
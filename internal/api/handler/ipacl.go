@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IPRuleHandler struct {
+	authService *service.AuthService
+}
+
+func NewIPRuleHandler(authService *service.AuthService) *IPRuleHandler {
+	return &IPRuleHandler{authService: authService}
+}
+
+type IPRulesSuccess = dto.BaseResponse[service.IPRules]
+
+// @BasePath /api/v1
+// ListIPRules godoc
+// @Summary      List IP rules
+// @Description  List the CIDR allow and deny lists shared by all instances. Expired bans are not shown.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  IPRulesSuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Failure      403  {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/ip-rules [get]
+func (h *IPRuleHandler) List(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	rules, err := h.authService.ListIPRules(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "List ip rules failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "List ip rules success", traceID, *rules, reqTime))
+}
+
+type AddIPRuleRequest struct {
+	List       string `json:"list" binding:"required,oneof=allow deny"`
+	CIDR       string `json:"cidr" binding:"required"`
+	Note       string `json:"note" binding:"omitempty,max=200"`
+	TTLSeconds int    `json:"ttl_seconds" binding:"omitempty,min=0"` // deny only; 0 = permanent
+}
+
+type IPRuleSuccess = dto.BaseResponse[service.IPRule]
+
+// @BasePath /api/v1
+// AddIPRule godoc
+// @Summary      Add an IP rule
+// @Description  Add an address or CIDR to the allow or deny list. A deny rule with ttl_seconds is a temporary ban. Once the allow list has an entry, only allow-listed addresses can reach the API.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        data  body      AddIPRuleRequest  true  "Rule"
+// @Success      200   {object}  IPRuleSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      403   {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/ip-rules [post]
+func (h *IPRuleHandler) Add(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req AddIPRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid ip rule payload", traceID, reqTime, err))
+		return
+	}
+	var ttl time.Duration
+	if req.List == service.IPListDeny {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	rule, err := h.authService.AddIPRule(c.Request.Context(), req.List, req.CIDR, req.Note, ttl)
+	if err != nil {
+		writeError(c, err, "Add ip rule failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Add ip rule success", traceID, *rule, reqTime))
+}
+
+type RemoveIPRuleSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// RemoveIPRule godoc
+// @Summary      Remove an IP rule
+// @Description  Remove an address or CIDR from the allow or deny list, e.g. to lift a ban early
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        list  query     string  true  "allow or deny"
+// @Param        cidr  query     string  true  "Address or CIDR"
+// @Success      200   {object}  RemoveIPRuleSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      403   {object}  dto.ErrorResponse
+// @Failure      404   {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/ip-rules [delete]
+func (h *IPRuleHandler) Remove(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	if err := h.authService.RemoveIPRule(c.Request.Context(), c.Query("list"), c.Query("cidr")); err != nil {
+		writeError(c, err, "Remove ip rule failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Remove ip rule success", traceID, reqTime))
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"seno-blackdragon/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	authService *service.AuthService
+}
+
+func NewJWKSHandler(authService *service.AuthService) *JWKSHandler {
+	return &JWKSHandler{authService: authService}
+}
+
+// JWKS godoc
+// @Summary      JSON Web Key Set
+// @Description  Public keys that verify access tokens, selected by the kid header. Retiring keys stay listed until their tokens expire.
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  keyring.JWKSet
+// @Router       /.well-known/jwks.json [get]
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	// plain RFC 7517 document, not the API envelope: verifiers parse it as-is
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
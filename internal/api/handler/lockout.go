@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type LockoutHandler struct {
+	authService *service.AuthService
+}
+
+func NewLockoutHandler(authService *service.AuthService) *LockoutHandler {
+	return &LockoutHandler{authService: authService}
+}
+
+type LockoutListSuccess = dto.BaseResponse[dto.PaginationResponse[service.LockoutEvent]]
+
+// @BasePath /api/v1
+// ListLockouts godoc
+// @Summary      List login lockouts
+// @Description  Recent temporary lockouts of emails and IPs caused by repeated failed logins, newest first
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page       query     int  false  "Page"       default(1)
+// @Param        page_size  query     int  false  "Page size"  default(20)
+// @Success      200        {object}  LockoutListSuccess
+// @Failure      401        {object}  dto.ErrorResponse
+// @Failure      403        {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/lockouts [get]
+func (h *LockoutHandler) List(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req dto.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid pagination", traceID, reqTime, err))
+		return
+	}
+	events, err := h.authService.ListLockoutEvents(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "List lockouts failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "List lockouts success", traceID, dto.Paginate(events, req), reqTime))
+}
+
+type UnlockUserSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// UnlockUser godoc
+// @Summary      Unlock a user
+// @Description  Lift a login lockout of the user's email and reset its failure counter
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path      string  true  "User ID"
+// @Success      200      {object}  UnlockUserSuccess
+// @Failure      400      {object}  dto.ErrorResponse
+// @Failure      403      {object}  dto.ErrorResponse
+// @Failure      404      {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/users/{user_id}/lockout [delete]
+func (h *LockoutHandler) Unlock(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeBadRequest, "Invalid user id", traceID, reqTime, err))
+		return
+	}
+	if err := h.authService.UnlockUser(c.Request.Context(), userID); err != nil {
+		writeError(c, err, "Unlock user failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Unlock user success", traceID, reqTime))
+}
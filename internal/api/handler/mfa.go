@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	authService *service.AuthService
+}
+
+func NewMFAHandler(authService *service.AuthService) *MFAHandler {
+	return &MFAHandler{authService: authService}
+}
+
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// @BasePath /api/v1
+// Verify godoc
+// @Summary      Verify MFA
+// @Description  Complete a two-stage login with a TOTP code or a recovery code
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        data  body      MFAVerifyRequest  true  "MFA challenge answer"
+// @Success      200   {object}  LoginSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      401   {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/mfa/verify [post]
+func (h *MFAHandler) Verify(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid MFA payload", traceID, reqTime, err))
+		return
+	}
+	token, err := h.authService.VerifyMFA(c.Request.Context(), model.MFAVerifyCmd{
+		ChallengeToken: req.MFAToken,
+		Code:           req.Code,
+		RecoveryCode:   req.RecoveryCode,
+	})
+	if err != nil {
+		writeError(c, err, "MFA verification failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Login success", traceID, tokenResponse(token), reqTime))
+}
+
+type TOTPEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TOTPEnrollSuccess = dto.BaseResponse[TOTPEnrollResponse]
+
+// @BasePath /api/v1
+// EnrollTOTP godoc
+// @Summary      Start TOTP enrollment
+// @Description  Generate a TOTP secret and otpauth:// URI; it is activated by the confirm step
+// @Tags         mfa
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  TOTPEnrollSuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Failure      409  {object}  dto.ErrorResponse
+// @Router       /api/v1/me/mfa/totp/enroll [post]
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	enrollment, err := h.authService.EnrollTOTP(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		writeError(c, err, "TOTP enrollment failed", traceID, reqTime)
+		return
+	}
+	resp := TOTPEnrollResponse{Secret: enrollment.Secret, OTPAuthURI: enrollment.URI}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "TOTP enrollment started", traceID, resp, reqTime))
+}
+
+type TOTPConfirmRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type TOTPConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TOTPConfirmSuccess = dto.BaseResponse[TOTPConfirmResponse]
+
+// @BasePath /api/v1
+// ConfirmTOTP godoc
+// @Summary      Confirm TOTP enrollment
+// @Description  Activate TOTP with a first valid code; returns single-use recovery codes (shown once)
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        data  body      TOTPConfirmRequest  true  "TOTP code"
+// @Success      200   {object}  TOTPConfirmSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      401   {object}  dto.ErrorResponse
+// @Failure      409   {object}  dto.ErrorResponse
+// @Router       /api/v1/me/mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req TOTPConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid TOTP payload", traceID, reqTime, err))
+		return
+	}
+	codes, err := h.authService.ConfirmTOTP(c.Request.Context(), middleware.GetUserID(c), req.Code)
+	if err != nil {
+		writeError(c, err, "TOTP confirmation failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "TOTP enabled", traceID, TOTPConfirmResponse{RecoveryCodes: codes}, reqTime))
+}
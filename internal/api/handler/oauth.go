@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthHandler struct {
+	authService *service.AuthService
+	consentURL  string
+}
+
+// NewOAuthHandler serves the OAuth2 endpoints. consentURL is the frontend
+// page that asks the user to approve a client.
+func NewOAuthHandler(authService *service.AuthService, consentURL string) *OAuthHandler {
+	return &OAuthHandler{authService: authService, consentURL: consentURL}
+}
+
+// Consent godoc
+// @Summary      OAuth2 authorization endpoint
+// @Description  Where clients send the browser. Redirects to the consent page with the query unchanged; the page logs the user in if needed and calls POST /api/v1/oauth/authorize.
+// @Tags         oauth
+// @Param        response_type          query  string  true   "code"
+// @Param        client_id              query  string  true   "Client ID"
+// @Param        redirect_uri           query  string  false  "Registered redirect URI"
+// @Param        scope                  query  string  false  "Space separated scopes"
+// @Param        state                  query  string  false  "Opaque client state"
+// @Param        code_challenge         query  string  true   "PKCE challenge"
+// @Param        code_challenge_method  query  string  true   "S256"
+// @Param        nonce                  query  string  false  "OpenID Connect nonce"
+// @Success      302
+// @Router       /api/v1/oauth/authorize [get]
+func (h *OAuthHandler) Consent(c *gin.Context) {
+	to, err := url.Parse(h.consentURL)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	to.RawQuery = c.Request.URL.RawQuery
+	c.Redirect(http.StatusFound, to.String())
+}
+
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type" binding:"required"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge" binding:"required"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method" binding:"required,eq=S256"`
+	Nonce               string `form:"nonce" json:"nonce" binding:"max=255"`
+}
+
+type AuthorizeResponse struct {
+	// RedirectTo is the client's redirect URI with code and state appended.
+	RedirectTo string `json:"redirect_to"`
+}
+
+type AuthorizeSuccess = dto.BaseResponse[AuthorizeResponse]
+
+// @BasePath /api/v1
+// Authorize godoc
+// @Summary      Authorize an OAuth client
+// @Description  Called by the consent page once the user approved. Issues a single-use authorization code bound to the PKCE challenge; only S256 is accepted.
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        data  body      AuthorizeRequest  true  "Authorization request"
+// @Success      200   {object}  AuthorizeSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      401   {object}  dto.ErrorResponse
+// @Failure      403   {object}  dto.ErrorResponse
+// @Router       /api/v1/oauth/authorize [post]
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req AuthorizeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid authorization request", traceID, reqTime, err))
+		return
+	}
+	to, err := h.authService.Authorize(c.Request.Context(), middleware.GetUserID(c), middleware.GetSessionID(c), service.AuthorizeRequest{
+		ResponseType:        req.ResponseType,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+	})
+	if err != nil {
+		writeError(c, err, "Authorize failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Authorize success", traceID, AuthorizeResponse{RedirectTo: to}, reqTime))
+}
+
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+}
+
+// OAuthErrorResponse is the RFC 6749 section 5.2 error body.
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// Token godoc
+// @Summary      OAuth2 token endpoint
+// @Description  RFC 6749 token endpoint for the authorization_code (with PKCE), refresh_token and client_credentials grants. Clients authenticate with HTTP Basic or client_id/client_secret form fields; public clients send only client_id.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "authorization_code, refresh_token or client_credentials"
+// @Param        code           formData  string  false  "Authorization code"
+// @Param        redirect_uri   formData  string  false  "Redirect URI sent to authorize"
+// @Param        code_verifier  formData  string  false  "PKCE code verifier"
+// @Param        refresh_token  formData  string  false  "Refresh token"
+// @Param        scope          formData  string  false  "Requested scopes (client_credentials)"
+// @Param        client_id      formData  string  false  "Client id when not using HTTP Basic"
+// @Param        client_secret  formData  string  false  "Client secret when not using HTTP Basic"
+// @Success      200  {object}  service.OAuthToken
+// @Failure      400  {object}  OAuthErrorResponse
+// @Failure      401  {object}  OAuthErrorResponse
+// @Router       /api/v1/oauth/token [post]
+func (h *OAuthHandler) Token(c *gin.Context) {
+	var req OAuthTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		writeOAuthError(c, enum.ErrOAuthInvalidRequest)
+		return
+	}
+	id, secret, ok := clientCredentials(c)
+	if !ok {
+		writeOAuthError(c, enum.ErrOAuthInvalidClient)
+		return
+	}
+	tok, err := h.authService.Token(c.Request.Context(), service.TokenRequest{
+		GrantType:    req.GrantType,
+		ClientID:     id,
+		ClientSecret: secret,
+		Code:         req.Code,
+		RedirectURI:  req.RedirectURI,
+		CodeVerifier: req.CodeVerifier,
+		RefreshToken: req.RefreshToken,
+		Scope:        req.Scope,
+	}, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+	// plain RFC 6749 response, not the API envelope
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, tok)
+}
+
+type OAuthTokenInput struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// Revoke godoc
+// @Summary      Revoke an OAuth token
+// @Description  RFC 7009. Revoking an access or refresh token ends the session it belongs to. Unknown tokens are ignored.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Token to revoke"
+// @Param        token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Success      200
+// @Failure      400  {object}  OAuthErrorResponse
+// @Failure      401  {object}  OAuthErrorResponse
+// @Router       /api/v1/oauth/revoke [post]
+func (h *OAuthHandler) Revoke(c *gin.Context) {
+	var req OAuthTokenInput
+	if err := c.ShouldBind(&req); err != nil {
+		writeOAuthError(c, enum.ErrOAuthInvalidRequest)
+		return
+	}
+	id, secret, ok := clientCredentials(c)
+	if !ok {
+		writeOAuthError(c, enum.ErrOAuthInvalidClient)
+		return
+	}
+	if err := h.authService.RevokeToken(c.Request.Context(), id, secret, req.Token); err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// Introspect godoc
+// @Summary      Introspect an OAuth token
+// @Description  RFC 7662, for confidential clients. A token is active while its session, device and account are.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Token to inspect"
+// @Param        token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Success      200  {object}  service.Introspection
+// @Failure      400  {object}  OAuthErrorResponse
+// @Failure      401  {object}  OAuthErrorResponse
+// @Router       /api/v1/oauth/introspect [post]
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	var req OAuthTokenInput
+	if err := c.ShouldBind(&req); err != nil {
+		writeOAuthError(c, enum.ErrOAuthInvalidRequest)
+		return
+	}
+	id, secret, ok := clientCredentials(c)
+	if !ok {
+		writeOAuthError(c, enum.ErrOAuthInvalidClient)
+		return
+	}
+	res, err := h.authService.Introspect(c.Request.Context(), id, secret, req.Token)
+	if err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, res)
+}
+
+// clientCredentials reads the client id and secret from HTTP Basic, whose
+// parts are form-encoded (RFC 6749 section 2.3.1), or from the form body.
+func clientCredentials(c *gin.Context) (string, string, bool) {
+	id, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		return c.PostForm("client_id"), c.PostForm("client_secret"), true
+	}
+	id, err := url.QueryUnescape(id)
+	if err != nil {
+		return "", "", false
+	}
+	secret, err = url.QueryUnescape(secret)
+	if err != nil {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+// oauthErrorCode maps service errors to RFC 6749 error codes. Anything that
+// makes a grant unusable is invalid_grant; unknown errors are server errors.
+func oauthErrorCode(err error) (int, string) {
+	switch {
+	case errors.Is(err, enum.ErrOAuthInvalidClient):
+		return http.StatusUnauthorized, "invalid_client"
+	case errors.Is(err, enum.ErrOAuthInvalidRequest):
+		return http.StatusBadRequest, "invalid_request"
+	case errors.Is(err, enum.ErrOAuthUnauthorizedClient):
+		return http.StatusBadRequest, "unauthorized_client"
+	case errors.Is(err, enum.ErrOAuthUnsupportedGrant):
+		return http.StatusBadRequest, "unsupported_grant_type"
+	case errors.Is(err, enum.ErrOAuthInvalidScope):
+		return http.StatusBadRequest, "invalid_scope"
+	case errors.Is(err, enum.ErrOAuthInvalidGrant),
+		errors.Is(err, enum.ErrInvalidToken),
+		errors.Is(err, enum.ErrWrongType),
+		errors.Is(err, enum.ErrWrongAlgorithm),
+		errors.Is(err, enum.ErrRefreshNotActive),
+		errors.Is(err, enum.ErrRefreshRevoked),
+		errors.Is(err, enum.ErrFamilyBlocked),
+		errors.Is(err, enum.ErrRotationRace),
+		errors.Is(err, enum.ErrSessionRevoked),
+		errors.Is(err, enum.ErrDeviceBlocked),
+		errors.Is(err, enum.ErrUserNotFound),
+		errors.Is(err, enum.ErrAccountDisabled):
+		return http.StatusBadRequest, "invalid_grant"
+	default:
+		return http.StatusInternalServerError, "server_error"
+	}
+}
+
+func writeOAuthError(c *gin.Context, err error) {
+	status, code := oauthErrorCode(err)
+	body := OAuthErrorResponse{Error: code}
+	if status == http.StatusInternalServerError {
+		c.Error(err)
+	} else {
+		body.ErrorDescription = err.Error()
+	}
+	if status == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, body)
+}
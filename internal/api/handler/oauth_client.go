@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthClientHandler struct {
+	authService *service.AuthService
+}
+
+func NewOAuthClientHandler(authService *service.AuthService) *OAuthClientHandler {
+	return &OAuthClientHandler{authService: authService}
+}
+
+type OAuthClientListSuccess = dto.BaseResponse[[]service.OAuthClient]
+
+// @BasePath /api/v1
+// ListOAuthClients godoc
+// @Summary      List OAuth clients
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  OAuthClientListSuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Failure      403  {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/oauth-clients [get]
+func (h *OAuthClientHandler) List(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	clients, err := h.authService.ListOAuthClients(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "List OAuth clients failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "List OAuth clients success", traceID, clients, reqTime))
+}
+
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=255"`
+	RedirectURIs []string `json:"redirect_uris" binding:"omitempty,dive,required,max=2048"`
+	GrantTypes   []string `json:"grant_types" binding:"required,min=1"`
+	Scopes       []string `json:"scopes"`
+	// FirstParty clients get the full API; others only reach the routes
+	// their scopes open.
+	FirstParty bool `json:"first_party"`
+	// Confidential clients get a secret; leave it false for mobile apps
+	// and SPAs, which rely on PKCE alone.
+	Confidential bool `json:"confidential"`
+}
+
+type NewOAuthClientSuccess = dto.BaseResponse[service.NewOAuthClient]
+
+// @BasePath /api/v1
+// CreateOAuthClient godoc
+// @Summary      Register an OAuth client
+// @Description  The client secret of a confidential client is only returned here
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        data  body      CreateOAuthClientRequest  true  "Client"
+// @Success      200   {object}  NewOAuthClientSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      403   {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/oauth-clients [post]
+func (h *OAuthClientHandler) Create(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid OAuth client payload", traceID, reqTime, err))
+		return
+	}
+	client, err := h.authService.CreateOAuthClient(c.Request.Context(), service.OAuthClientInput{
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		GrantTypes:   req.GrantTypes,
+		Scopes:       req.Scopes,
+		FirstParty:   req.FirstParty,
+		Confidential: req.Confidential,
+	})
+	if err != nil {
+		writeError(c, err, "Create OAuth client failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Create OAuth client success", traceID, *client, reqTime))
+}
+
+type OAuthClientActionSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// DeleteOAuthClient godoc
+// @Summary      Delete an OAuth client
+// @Description  The client can no longer obtain or refresh tokens; issued access tokens expire on their own
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        client_id  path      string  true  "Client ID"
+// @Success      200        {object}  OAuthClientActionSuccess
+// @Failure      403        {object}  dto.ErrorResponse
+// @Failure      404        {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/oauth-clients/{client_id} [delete]
+func (h *OAuthClientHandler) Delete(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	if err := h.authService.DeleteOAuthClient(c.Request.Context(), c.Param("client_id")); err != nil {
+		writeError(c, err, "Delete OAuth client failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Delete OAuth client success", traceID, reqTime))
+}
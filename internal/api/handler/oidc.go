@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	authService *service.AuthService
+}
+
+func NewOIDCHandler(authService *service.AuthService) *OIDCHandler {
+	return &OIDCHandler{authService: authService}
+}
+
+// Discovery godoc
+// @Summary      OpenID Connect discovery
+// @Description  OpenID Provider Metadata; endpoint URLs are based on OIDC_ISSUER. Not found unless JWT_KEY_FILES holds an asymmetric key.
+// @Tags         oauth
+// @Produce      json
+// @Success      200  {object}  service.OIDCDiscovery
+// @Failure      404  {object}  dto.ErrorResponse
+// @Router       /.well-known/openid-configuration [get]
+func (h *OIDCHandler) Discovery(c *gin.Context) {
+	d, err := h.authService.Discovery()
+	if err != nil {
+		writeError(c, err, "Discovery failed", middleware.TraceID(c), time.Now().UTC())
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, d)
+}
+
+// UserInfo godoc
+// @Summary      OpenID Connect UserInfo
+// @Description  Claims about the user, filtered by the token's scopes. Needs a token with the openid scope.
+// @Tags         oauth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  service.UserInfo
+// @Failure      401  {object}  dto.ErrorResponse
+// @Failure      403  {object}  dto.ErrorResponse
+// @Router       /api/v1/userinfo [get]
+func (h *OIDCHandler) UserInfo(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	info, err := h.authService.UserInfo(c.Request.Context(), middleware.GetUserID(c), middleware.GetScopes(c))
+	if err != nil {
+		writeError(c, err, "User info failed", traceID, reqTime)
+		return
+	}
+	// plain OpenID Connect response, not the API envelope
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, info)
+}
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/webauthn"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasskeyHandler struct {
+	authService *service.AuthService
+}
+
+func NewPasskeyHandler(authService *service.AuthService) *PasskeyHandler {
+	return &PasskeyHandler{authService: authService}
+}
+
+// Credential requests follow PublicKeyCredential.toJSON(): binary fields are
+// base64url strings.
+
+type AttestationResponse struct {
+	ClientDataJSON    webauthn.Base64URL `json:"clientDataJSON" binding:"required" swaggertype:"string"`
+	AttestationObject webauthn.Base64URL `json:"attestationObject" binding:"required" swaggertype:"string"`
+	Transports        []string           `json:"transports"`
+}
+
+type PasskeyRegisterRequest struct {
+	Name     string              `json:"name" binding:"omitempty,max=64"`
+	Type     string              `json:"type" binding:"required,eq=public-key"`
+	Response AttestationResponse `json:"response" binding:"required"`
+}
+
+type AssertionResponse struct {
+	ClientDataJSON    webauthn.Base64URL `json:"clientDataJSON" binding:"required" swaggertype:"string"`
+	AuthenticatorData webauthn.Base64URL `json:"authenticatorData" binding:"required" swaggertype:"string"`
+	Signature         webauthn.Base64URL `json:"signature" binding:"required" swaggertype:"string"`
+	UserHandle        webauthn.Base64URL `json:"userHandle" swaggertype:"string"`
+}
+
+type PasskeyLoginRequest struct {
+	RawID      webauthn.Base64URL `json:"rawId" binding:"required" swaggertype:"string"`
+	Type       string             `json:"type" binding:"required,eq=public-key"`
+	Response   AssertionResponse  `json:"response" binding:"required"`
+	DeviceID   string             `json:"device_id"`
+	DeviceMeta map[string]string  `json:"device_meta"`
+}
+
+type RenamePasskeyRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+type PasskeyCreationOptionsSuccess = dto.BaseResponse[webauthn.CreationOptions]
+type PasskeyRequestOptionsSuccess = dto.BaseResponse[webauthn.RequestOptions]
+type PasskeySuccess = dto.BaseResponse[service.Passkey]
+type PasskeyListSuccess = dto.BaseResponse[[]service.Passkey]
+type PasskeyEmptySuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// BeginPasskeyLogin godoc
+// @Summary      Start a passkey login
+// @Description  Returns options for navigator.credentials.get. The challenge is valid for one attempt within five minutes.
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  PasskeyRequestOptionsSuccess
+// @Failure      429  {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/passkey/login/begin [post]
+func (h *PasskeyHandler) BeginLogin(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	opts, err := h.authService.BeginPasskeyLogin(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "Passkey login failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Passkey login started", traceID, opts, reqTime))
+}
+
+// @BasePath /api/v1
+// FinishPasskeyLogin godoc
+// @Summary      Complete a passkey login
+// @Description  Verifies the assertion. A passkey that verified the user counts as two factors; otherwise accounts with MFA get the usual challenge.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        data  body      PasskeyLoginRequest  true  "Assertion from navigator.credentials.get"
+// @Success      200   {object}  LoginSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      401   {object}  dto.ErrorResponse
+// @Failure      429   {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/passkey/login/finish [post]
+func (h *PasskeyHandler) FinishLogin(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req PasskeyLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid passkey payload", traceID, reqTime, err))
+		return
+	}
+	result, err := h.authService.FinishPasskeyLogin(c.Request.Context(), model.PasskeyLoginCmd{
+		CredentialID:      req.RawID,
+		ClientDataJSON:    req.Response.ClientDataJSON,
+		AuthenticatorData: req.Response.AuthenticatorData,
+		Signature:         req.Response.Signature,
+		UserHandle:        req.Response.UserHandle,
+	}, model.LoginCmd{
+		DeviceID:   req.DeviceID,
+		DeviceMeta: req.DeviceMeta,
+		IP:         c.ClientIP(),
+		UA:         c.GetHeader("User-Agent"),
+	})
+	if err != nil {
+		writeError(c, err, "Passkey login failed", traceID, reqTime)
+		return
+	}
+	writeLoginResult(c, result, traceID, reqTime)
+}
+
+// @BasePath /api/v1
+// ListPasskeys godoc
+// @Summary      List my passkeys
+// @Tags         profile
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  PasskeyListSuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Router       /api/v1/me/passkeys [get]
+func (h *PasskeyHandler) List(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	passkeys, err := h.authService.ListPasskeys(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		writeError(c, err, "List passkeys failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "List passkeys success", traceID, passkeys, reqTime))
+}
+
+// @BasePath /api/v1
+// BeginPasskeyRegistration godoc
+// @Summary      Start registering a passkey
+// @Description  Returns options for navigator.credentials.create.
+// @Tags         profile
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  PasskeyCreationOptionsSuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Failure      403  {object}  dto.ErrorResponse
+// @Router       /api/v1/me/passkeys/register/begin [post]
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	opts, err := h.authService.BeginPasskeyRegistration(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		writeError(c, err, "Register passkey failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Register passkey started", traceID, opts, reqTime))
+}
+
+// @BasePath /api/v1
+// FinishPasskeyRegistration godoc
+// @Summary      Complete registering a passkey
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        data  body      PasskeyRegisterRequest  true  "Credential from navigator.credentials.create"
+// @Success      200   {object}  PasskeySuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      401   {object}  dto.ErrorResponse
+// @Failure      409   {object}  dto.ErrorResponse
+// @Router       /api/v1/me/passkeys/register/finish [post]
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req PasskeyRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid passkey payload", traceID, reqTime, err))
+		return
+	}
+	passkey, err := h.authService.FinishPasskeyRegistration(c.Request.Context(), middleware.GetUserID(c), model.PasskeyRegistrationCmd{
+		Name:              req.Name,
+		ClientDataJSON:    req.Response.ClientDataJSON,
+		AttestationObject: req.Response.AttestationObject,
+		Transports:        req.Response.Transports,
+	})
+	if err != nil {
+		writeError(c, err, "Register passkey failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Register passkey success", traceID, passkey, reqTime))
+}
+
+// @BasePath /api/v1
+// RenamePasskey godoc
+// @Summary      Rename one of my passkeys
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string                true  "Passkey ID"
+// @Param        data  body      RenamePasskeyRequest  true  "New name"
+// @Success      200   {object}  PasskeyEmptySuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      401   {object}  dto.ErrorResponse
+// @Failure      404   {object}  dto.ErrorResponse
+// @Router       /api/v1/me/passkeys/{id} [patch]
+func (h *PasskeyHandler) Rename(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req RenamePasskeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid passkey payload", traceID, reqTime, err))
+		return
+	}
+	if err := h.authService.RenamePasskey(c.Request.Context(), middleware.GetUserID(c), c.Param("id"), req.Name); err != nil {
+		writeError(c, err, "Rename passkey failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Rename passkey success", traceID, reqTime))
+}
+
+// @BasePath /api/v1
+// DeletePasskey godoc
+// @Summary      Delete one of my passkeys
+// @Description  Refused with 409 when it is the only way to log in.
+// @Tags         profile
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Passkey ID"
+// @Success      200  {object}  PasskeyEmptySuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Failure      404  {object}  dto.ErrorResponse
+// @Failure      409  {object}  dto.ErrorResponse
+// @Router       /api/v1/me/passkeys/{id} [delete]
+func (h *PasskeyHandler) Delete(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	if err := h.authService.DeletePasskey(c.Request.Context(), middleware.GetUserID(c), c.Param("id")); err != nil {
+		writeError(c, err, "Delete passkey failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Delete passkey success", traceID, reqTime))
+}
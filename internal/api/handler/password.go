@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordHandler struct {
+	authService *service.AuthService
+}
+
+func NewPasswordHandler(authService *service.AuthService) *PasswordHandler {
+	return &PasswordHandler{authService: authService}
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ForgotPasswordSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// ForgotPassword godoc
+// @Summary      Request a password reset
+// @Description  Send a single-use reset token to the email if an account exists. Always succeeds to avoid account enumeration.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        data  body      ForgotPasswordRequest  true  "Account email"
+// @Success      200   {object}  ForgotPasswordSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/password/forgot [post]
+func (h *PasswordHandler) Forgot(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid forgot password payload", traceID, reqTime, err))
+		return
+	}
+	if err := h.authService.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		writeError(c, err, "Forgot password failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "If the account exists, a reset link has been sent", traceID, reqTime))
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type ResetPasswordSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password with a reset token. The token is single use and every session of the user is logged out.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        data  body      ResetPasswordRequest  true  "Reset token and new password"
+// @Success      200   {object}  ResetPasswordSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/password/reset [post]
+func (h *PasswordHandler) Reset(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid reset password payload", traceID, reqTime, err))
+		return
+	}
+	if err := h.authService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword); err != nil {
+		writeError(c, err, "Reset password failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Reset password success", traceID, reqTime))
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ChangePasswordSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// ChangePassword godoc
+// @Summary      Change password
+// @Description  Change the password of the current user. All other sessions are revoked.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        data  body      ChangePasswordRequest  true  "Current and new password"
+// @Success      200   {object}  ChangePasswordSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      401   {object}  dto.ErrorResponse
+// @Router       /api/v1/me/password [post]
+func (h *PasswordHandler) Change(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid change password payload", traceID, reqTime, err))
+		return
+	}
+	err := h.authService.ChangePassword(c.Request.Context(), middleware.GetUserID(c), middleware.GetSessionID(c), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		writeError(c, err, "Change password failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Change password success", traceID, reqTime))
+}
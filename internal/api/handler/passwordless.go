@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordlessHandler struct {
+	authService *service.AuthService
+}
+
+func NewPasswordlessHandler(authService *service.AuthService) *PasswordlessHandler {
+	return &PasswordlessHandler{authService: authService}
+}
+
+type PasswordlessStartRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type PasswordlessStartSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// PasswordlessStart godoc
+// @Summary      Email me a login code
+// @Description  Sends a single-use login code and link to the email. Always succeeds to avoid account enumeration.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        data  body      PasswordlessStartRequest  true  "Account email"
+// @Success      200   {object}  PasswordlessStartSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      429   {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/passwordless/start [post]
+func (h *PasswordlessHandler) Start(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req PasswordlessStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid passwordless payload", traceID, reqTime, err))
+		return
+	}
+	if err := h.authService.StartEmailLogin(c.Request.Context(), req.Email); err != nil {
+		writeError(c, err, "Passwordless login failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "If the email can log in, a code has been sent", traceID, reqTime))
+}
+
+// PasswordlessVerifyRequest takes the emailed code with its email, or the
+// token from the emailed link.
+type PasswordlessVerifyRequest struct {
+	Email      string            `json:"email" binding:"omitempty,email"`
+	Code       string            `json:"code" binding:"omitempty,numeric,len=6"`
+	Token      string            `json:"token"`
+	DeviceID   string            `json:"device_id"`
+	DeviceMeta map[string]string `json:"device_meta"`
+}
+
+// @BasePath /api/v1
+// PasswordlessVerify godoc
+// @Summary      Log in with an emailed code or link
+// @Description  Creates the account on first use when sign-up by email is enabled. Accounts with MFA get the usual challenge.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        data  body      PasswordlessVerifyRequest  true  "Code and email, or link token"
+// @Success      200   {object}  LoginSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      401   {object}  dto.ErrorResponse
+// @Failure      429   {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/passwordless/verify [post]
+func (h *PasswordlessHandler) Verify(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req PasswordlessVerifyRequest
+	err := c.ShouldBindJSON(&req)
+	if err == nil && req.Token == "" && (req.Email == "" || req.Code == "") {
+		err = errors.New("email and code, or token, are required")
+	}
+	if err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid passwordless payload", traceID, reqTime, err))
+		return
+	}
+	result, err := h.authService.VerifyEmailLogin(c.Request.Context(), model.LoginCodeCmd{
+		Email: req.Email,
+		Code:  req.Code,
+		Token: req.Token,
+	}, model.LoginCmd{
+		DeviceID:   req.DeviceID,
+		DeviceMeta: req.DeviceMeta,
+		IP:         c.ClientIP(),
+		UA:         c.GetHeader("User-Agent"),
+	})
+	if err != nil {
+		writeError(c, err, "Passwordless login failed", traceID, reqTime)
+		return
+	}
+	writeLoginResult(c, result, traceID, reqTime)
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProfileHandler struct {
+	authService *service.AuthService
+}
+
+func NewProfileHandler(authService *service.AuthService) *ProfileHandler {
+	return &ProfileHandler{authService: authService}
+}
+
+type ProfileSuccess = dto.BaseResponse[service.Profile]
+
+// @BasePath /api/v1
+// GetProfile godoc
+// @Summary      Get my profile
+// @Tags         profile
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  ProfileSuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Router       /api/v1/me [get]
+func (h *ProfileHandler) Get(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	p, err := h.authService.GetProfile(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		writeError(c, err, "Get profile failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Get profile success", traceID, *p, reqTime))
+}
+
+type UpdateProfileRequest struct {
+	FullName  *string `json:"full_name" binding:"omitempty,max=255"`
+	Bio       *string `json:"bio" binding:"omitempty,max=1024"`
+	AvatarURL *string `json:"avatar_url" binding:"omitempty,max=2048,http_url|len=0"`
+	// Email is only committed after the link sent to the new address is opened.
+	Email *string `json:"email" binding:"omitempty,email"`
+	// UpdatedAt is the updated_at value the client last read; optional.
+	UpdatedAt *time.Time `json:"updated_at"`
+	// CurrentPassword is required with email. Accounts without a password
+	// must have logged in within the last 10 minutes instead.
+	CurrentPassword string `json:"current_password"`
+}
+
+// @BasePath /api/v1
+// UpdateProfile godoc
+// @Summary      Update my profile
+// @Description  Edit full name, bio and avatar URL. A new email needs current_password (or, for accounts without one, a login within the last 10 minutes) and is confirmed through a link sent to it before it replaces the current one; the current address is told and other sessions are logged out on confirmation. Send updated_at from the last read to reject concurrent edits with 409.
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        data  body      UpdateProfileRequest  true  "Profile fields"
+// @Success      200   {object}  ProfileSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      401   {object}  dto.ErrorResponse
+// @Failure      403   {object}  dto.ErrorResponse
+// @Failure      409   {object}  dto.ErrorResponse
+// @Router       /api/v1/me [patch]
+func (h *ProfileHandler) Update(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid profile payload", traceID, reqTime, err))
+		return
+	}
+	p, err := h.authService.UpdateProfile(c.Request.Context(), middleware.GetUserID(c), middleware.GetSessionID(c), service.ProfileUpdate{
+		FullName:        req.FullName,
+		Bio:             req.Bio,
+		AvatarURL:       req.AvatarURL,
+		Email:           req.Email,
+		UpdatedAt:       req.UpdatedAt,
+		CurrentPassword: req.CurrentPassword,
+	})
+	if err != nil {
+		writeError(c, err, "Update profile failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Update profile success", traceID, *p, reqTime))
+}
+
+type DeleteAccountRequest struct {
+	// Password is required unless the account has none; such accounts must
+	// have logged in within the last 10 minutes instead.
+	Password string `json:"password"`
+}
+
+type AccountDeletionSuccess = dto.BaseResponse[service.AccountDeletion]
+
+// @BasePath /api/v1
+// DeleteAccount godoc
+// @Summary      Delete my account
+// @Description  Deactivate the account and log out everywhere. Needs the current password or, for accounts without one, a login within the last 10 minutes (passkey, emailed code or social login). Personal data is purged after the grace period; until then an administrator can restore the account.
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        data  body      DeleteAccountRequest  true  "Current password"
+// @Success      200   {object}  AccountDeletionSuccess
+// @Failure      400   {object}  dto.ErrorResponse
+// @Failure      401   {object}  dto.ErrorResponse
+// @Failure      403   {object}  dto.ErrorResponse
+// @Router       /api/v1/me [delete]
+func (h *ProfileHandler) Delete(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid delete account payload", traceID, reqTime, err))
+		return
+	}
+	del, err := h.authService.DeleteAccount(c.Request.Context(), middleware.GetUserID(c), middleware.GetSessionID(c), req.Password)
+	if err != nil {
+		writeError(c, err, "Delete account failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Delete account success", traceID, *del, reqTime))
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type RoleHandler struct {
+	authService *service.AuthService
+}
+
+func NewRoleHandler(authService *service.AuthService) *RoleHandler {
+	return &RoleHandler{authService: authService}
+}
+
+type RoleResponse struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type RoleListSuccess = dto.BaseResponse[[]RoleResponse]
+
+// @BasePath /api/v1
+// ListRoles godoc
+// @Summary      List roles
+// @Description  List all roles
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  RoleListSuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Failure      403  {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/roles [get]
+func (h *RoleHandler) List(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	roles, err := h.authService.ListRoles(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "List roles failed", traceID, reqTime)
+		return
+	}
+	resp := make([]RoleResponse, 0, len(roles))
+	for _, r := range roles {
+		resp = append(resp, RoleResponse{ID: r.ID.String(), Name: r.Name, Description: r.Description})
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "List roles success", traceID, resp, reqTime))
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type UserRolesResponse struct {
+	UserID string   `json:"user_id"`
+	Roles  []string `json:"roles"`
+}
+
+type UserRolesSuccess = dto.BaseResponse[UserRolesResponse]
+
+// @BasePath /api/v1
+// AssignRole godoc
+// @Summary      Assign a role
+// @Description  Grant a role to a user; it is added to access tokens from the next login or refresh
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path      string             true  "User ID"
+// @Param        data     body      AssignRoleRequest  true  "Role"
+// @Success      200      {object}  UserRolesSuccess
+// @Failure      400      {object}  dto.ErrorResponse
+// @Failure      403      {object}  dto.ErrorResponse
+// @Failure      404      {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/users/{user_id}/roles [post]
+func (h *RoleHandler) Assign(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeBadRequest, "Invalid user id", traceID, reqTime, err))
+		return
+	}
+	var req AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid role payload", traceID, reqTime, err))
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.authService.AssignRole(ctx, userID, req.Role); err != nil {
+		writeError(c, err, "Assign role failed", traceID, reqTime)
+		return
+	}
+	roles, err := h.authService.GetUserRoles(ctx, userID)
+	if err != nil {
+		writeError(c, err, "Assign role failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Assign role success", traceID, UserRolesResponse{UserID: userID.String(), Roles: roles}, reqTime))
+}
+
+// @BasePath /api/v1
+// RevokeRole godoc
+// @Summary      Revoke a role
+// @Description  Remove a role from a user
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path      string  true  "User ID"
+// @Param        role     path      string  true  "Role name"
+// @Success      200      {object}  UserRolesSuccess
+// @Failure      400      {object}  dto.ErrorResponse
+// @Failure      403      {object}  dto.ErrorResponse
+// @Failure      404      {object}  dto.ErrorResponse
+// @Router       /api/v1/admin/users/{user_id}/roles/{role} [delete]
+func (h *RoleHandler) Revoke(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeBadRequest, "Invalid user id", traceID, reqTime, err))
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.authService.RevokeRole(ctx, userID, c.Param("role")); err != nil {
+		writeError(c, err, "Revoke role failed", traceID, reqTime)
+		return
+	}
+	roles, err := h.authService.GetUserRoles(ctx, userID)
+	if err != nil {
+		writeError(c, err, "Revoke role failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Revoke role success", traceID, UserRolesResponse{UserID: userID.String(), Roles: roles}, reqTime))
+}
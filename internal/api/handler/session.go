@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	authService *service.AuthService
+}
+
+func NewSessionHandler(authService *service.AuthService) *SessionHandler {
+	return &SessionHandler{authService: authService}
+}
+
+type SessionListSuccess = dto.BaseResponse[dto.PaginationResponse[service.SessionItem]]
+
+// @BasePath /api/v1
+// ListSessions godoc
+// @Summary      List my sessions
+// @Description  List active sessions of the current user; the caller's session is marked as current
+// @Tags         sessions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page       query     int  false  "Page"       default(1)
+// @Param        page_size  query     int  false  "Page size"  default(20)
+// @Success      200        {object}  SessionListSuccess
+// @Failure      400        {object}  dto.ErrorResponse
+// @Failure      401        {object}  dto.ErrorResponse
+// @Router       /api/v1/me/sessions [get]
+func (h *SessionHandler) List(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	var req dto.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid pagination", traceID, reqTime, err))
+		return
+	}
+	items, err := h.authService.ListSessions(c.Request.Context(), middleware.GetUserID(c), middleware.GetSessionID(c))
+	if err != nil {
+		writeError(c, err, "List sessions failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "List sessions success", traceID, dto.Paginate(items, req), reqTime))
+}
+
+type RevokeSessionSuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// RevokeSession godoc
+// @Summary      Revoke a session
+// @Description  Revoke one of my sessions and its refresh token family
+// @Tags         sessions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        sid  path      string  true  "Session ID"
+// @Success      200  {object}  RevokeSessionSuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Router       /api/v1/me/sessions/{sid} [delete]
+func (h *SessionHandler) Revoke(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	if err := h.authService.RevokeSession(c.Request.Context(), middleware.GetUserID(c), c.Param("sid")); err != nil {
+		writeError(c, err, "Revoke session failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Revoke session success", traceID, reqTime))
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SocialHandler struct {
+	authService *service.AuthService
+}
+
+func NewSocialHandler(authService *service.AuthService) *SocialHandler {
+	return &SocialHandler{authService: authService}
+}
+
+// socialStateCookie binds a social login to the browser that started it. It
+// holds the hash of the state and is scoped to the callback path.
+const (
+	socialStateCookie     = "sbd_social_state"
+	socialStateCookiePath = "/api/v1/auth/social"
+)
+
+func setSocialStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     socialStateCookie,
+		Value:    value,
+		Path:     socialStateCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https",
+		// Lax still sends it on the top-level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+type SocialProvidersSuccess = dto.BaseResponse[[]string]
+
+// @BasePath /api/v1
+// SocialProviders godoc
+// @Summary      List social login providers
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  SocialProvidersSuccess
+// @Router       /api/v1/auth/social [get]
+func (h *SocialHandler) Providers(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "List providers success", traceID, h.authService.SocialProviders(), reqTime))
+}
+
+// SocialLogin godoc
+// @Summary      Start a social login
+// @Description  Redirects the browser to the provider and sets the sbd_social_state cookie. The provider sends it back to the configured redirect URL with code and state, which are passed to the callback from the same browser.
+// @Tags         auth
+// @Param        provider   path   string  true   "Provider name"
+// @Param        device_id  query  string  false  "Device ID of the client"
+// @Success      302
+// @Failure      404  {object}  dto.ErrorResponse
+// @Failure      429  {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/social/{provider} [get]
+func (h *SocialHandler) Start(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	start, err := h.authService.StartSocialLogin(c.Request.Context(), c.Param("provider"), c.Query("device_id"))
+	if err != nil {
+		writeError(c, err, "Social login failed", traceID, reqTime)
+		return
+	}
+	setSocialStateCookie(c, start.Binding, int(service.SocialStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, start.RedirectTo)
+}
+
+type SocialCallbackRequest struct {
+	Code  string `form:"code" binding:"required"`
+	State string `form:"state" binding:"required"`
+}
+
+// SocialCallbackResponse carries the login result, or the linked identity
+// when the flow was started from POST /me/identities/{provider}.
+type SocialCallbackResponse struct {
+	LoginResponse
+	Identity *service.Identity `json:"identity,omitempty"`
+}
+
+type SocialCallbackSuccess = dto.BaseResponse[SocialCallbackResponse]
+
+// @BasePath /api/v1
+// SocialCallback godoc
+// @Summary      Complete a social login
+// @Description  Exchanges the provider's code. Needs the sbd_social_state cookie set when the flow started (400 otherwise). An unknown external account signs up a new user, unless a local account has the same email: that user must log in and link the provider instead (409).
+// @Tags         auth
+// @Produce      json
+// @Param        provider  path   string  true  "Provider name"
+// @Param        code      query  string  true  "Authorization code from the provider"
+// @Param        state     query  string  true  "State from the provider redirect"
+// @Success      200  {object}  SocialCallbackSuccess
+// @Failure      400  {object}  dto.ErrorResponse
+// @Failure      401  {object}  dto.ErrorResponse
+// @Failure      404  {object}  dto.ErrorResponse
+// @Failure      409  {object}  dto.ErrorResponse
+// @Router       /api/v1/auth/social/{provider}/callback [get]
+func (h *SocialHandler) Callback(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	binding, _ := c.Cookie(socialStateCookie)
+	// the state is single use, so is the cookie
+	setSocialStateCookie(c, "", -1)
+	if c.Query("error") != "" {
+		// the user cancelled or the provider refused
+		writeError(c, enum.ErrSocialLoginFailed, "Social login failed", traceID, reqTime)
+		return
+	}
+	var req SocialCallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		dto.BadRequest(c, dto.NewError(http.StatusBadRequest, enum.CodeValidationFailed, "Invalid callback parameters", traceID, reqTime, err))
+		return
+	}
+	result, err := h.authService.SocialCallback(c.Request.Context(), c.Param("provider"), req.Code, req.State, binding, model.LoginCmd{
+		IP: c.ClientIP(),
+		UA: c.GetHeader("User-Agent"),
+	})
+	if err != nil {
+		writeError(c, err, "Social login failed", traceID, reqTime)
+		return
+	}
+	if result.Linked != nil {
+		dto.Ok(c, dto.NewSuccess(http.StatusOK, "Link identity success", traceID, SocialCallbackResponse{Identity: result.Linked}, reqTime))
+		return
+	}
+	writeLoginResult(c, result.Login, traceID, reqTime)
+}
+
+type IdentityListSuccess = dto.BaseResponse[[]service.Identity]
+
+// @BasePath /api/v1
+// ListIdentities godoc
+// @Summary      List my linked identities
+// @Tags         profile
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  IdentityListSuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Router       /api/v1/me/identities [get]
+func (h *SocialHandler) ListIdentities(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	idents, err := h.authService.ListIdentities(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		writeError(c, err, "List identities failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "List identities success", traceID, idents, reqTime))
+}
+
+// @BasePath /api/v1
+// LinkIdentity godoc
+// @Summary      Link a provider to my account
+// @Description  Returns the provider URL to send the browser to, and sets the sbd_social_state cookie the callback needs. The callback links the external account while this session is still live.
+// @Tags         profile
+// @Produce      json
+// @Security     BearerAuth
+// @Param        provider  path  string  true  "Provider name"
+// @Success      200  {object}  AuthorizeSuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Failure      404  {object}  dto.ErrorResponse
+// @Router       /api/v1/me/identities/{provider} [post]
+func (h *SocialHandler) Link(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	start, err := h.authService.StartSocialLink(c.Request.Context(), middleware.GetUserID(c), middleware.GetSessionID(c), c.Param("provider"))
+	if err != nil {
+		writeError(c, err, "Link identity failed", traceID, reqTime)
+		return
+	}
+	setSocialStateCookie(c, start.Binding, int(service.SocialStateTTL.Seconds()))
+	dto.Ok(c, dto.NewSuccess(http.StatusOK, "Link identity started", traceID, AuthorizeResponse{RedirectTo: start.RedirectTo}, reqTime))
+}
+
+type UnlinkIdentitySuccess = dto.BaseResponse[dto.EmptyData]
+
+// @BasePath /api/v1
+// UnlinkIdentity godoc
+// @Summary      Unlink a provider from my account
+// @Description  Refused with 409 when it is the only way to log in; set a password through a reset first.
+// @Tags         profile
+// @Produce      json
+// @Security     BearerAuth
+// @Param        provider  path  string  true  "Provider name"
+// @Success      200  {object}  UnlinkIdentitySuccess
+// @Failure      401  {object}  dto.ErrorResponse
+// @Failure      404  {object}  dto.ErrorResponse
+// @Failure      409  {object}  dto.ErrorResponse
+// @Router       /api/v1/me/identities/{provider} [delete]
+func (h *SocialHandler) Unlink(c *gin.Context) {
+	reqTime := time.Now().UTC()
+	traceID := middleware.TraceID(c)
+	if err := h.authService.UnlinkIdentity(c.Request.Context(), middleware.GetUserID(c), c.Param("provider")); err != nil {
+		writeError(c, err, "Unlink identity failed", traceID, reqTime)
+		return
+	}
+	dto.Ok(c, dto.NewSuccessEmpty(http.StatusOK, "Unlink identity success", traceID, reqTime))
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"seno-blackdragon/internal/api/middleware"
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/oidcclient"
+	"seno-blackdragon/pkg/pass"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const testUserID = "6a3c1d2e-8f4b-4e2a-b1c0-9d8e7f6a5b4c"
+
+// socialEnv routes the social handlers to a mock provider whose token
+// endpoint always fails; exchanges counts the codes it was asked to redeem.
+type socialEnv struct {
+	router    *gin.Engine
+	redis     *redis.Client
+	exchanges atomic.Int32
+}
+
+func newSocialEnv(t *testing.T) *socialEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	env := &socialEnv{}
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"jwks_uri":               srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		env.exchanges.Add(1)
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+	})
+
+	mr := miniredis.RunT(t)
+	env.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { env.redis.Close() })
+	env.redis.Set(context.Background(), keys.UserVer(testUserID), 1, 0)
+	as := service.NewAuthService(nil, nil, nil, nil, nil, nil, nil, nil, pass.Config{Algo: pass.AlgoBcrypt, BcryptCost: 4},
+		env.redis, nil, service.JWTConfig{}, service.Policy{
+			SocialProviders: map[string]*oidcclient.Provider{
+				"mock": oidcclient.New(oidcclient.Config{Issuer: srv.URL, ClientID: "cli", RedirectURL: "http://app.test/cb"}, srv.Client()),
+			},
+		}, zap.NewNop())
+
+	h := NewSocialHandler(as)
+	env.router = gin.New()
+	env.router.GET("/api/v1/auth/social/:provider", h.Start)
+	env.router.GET("/api/v1/auth/social/:provider/callback", h.Callback)
+	env.router.POST("/api/v1/me/identities/:provider", func(c *gin.Context) {
+		c.Set(middleware.ContextKeyUserID, testUserID)
+		c.Set(middleware.ContextKeySessionID, "SID_TEST")
+	}, h.Link)
+	return env
+}
+
+func (env *socialEnv) do(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	return w
+}
+
+func stateCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, ck := range w.Result().Cookies() {
+		if ck.Name == socialStateCookie {
+			return ck
+		}
+	}
+	t.Fatalf("no %s cookie in %v", socialStateCookie, w.Header().Values("Set-Cookie"))
+	return nil
+}
+
+// startLogin starts a login and returns the state sent to the provider and
+// the binding cookie.
+func (env *socialEnv) startLogin(t *testing.T) (string, *http.Cookie) {
+	t.Helper()
+	w := env.do(httptest.NewRequest(http.MethodGet, "/api/v1/auth/social/mock", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("start: status %d: %s", w.Code, w.Body)
+	}
+	loc, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc.Query().Get("state"), stateCookie(t, w)
+}
+
+func TestSocialStartSetsStateCookie(t *testing.T) {
+	env := newSocialEnv(t)
+	state, ck := env.startLogin(t)
+	sum := sha256.Sum256([]byte(state))
+	if ck.Value != hex.EncodeToString(sum[:]) {
+		t.Errorf("cookie holds %q, want the hash of state %q", ck.Value, state)
+	}
+	if !ck.HttpOnly || ck.SameSite != http.SameSiteLaxMode || ck.Path != socialStateCookiePath || ck.MaxAge <= 0 {
+		t.Errorf("unexpected cookie attributes %+v", ck)
+	}
+
+	w := env.do(httptest.NewRequest(http.MethodPost, "/api/v1/me/identities/mock", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("link: status %d: %s", w.Code, w.Body)
+	}
+	var res dto.BaseResponse[AuthorizeResponse]
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	loc, _ := url.Parse(res.Data.RedirectTo)
+	sum = sha256.Sum256([]byte(loc.Query().Get("state")))
+	if ck := stateCookie(t, w); ck.Value != hex.EncodeToString(sum[:]) || ck.Path != socialStateCookiePath {
+		t.Errorf("link cookie %+v does not bind its state", ck)
+	}
+}
+
+func TestSocialCallbackNeedsStateCookie(t *testing.T) {
+	cases := []struct {
+		name      string
+		cookie    func(own *http.Cookie) *http.Cookie
+		wantCode  int
+		wantErr   string
+		exchanged bool
+	}{
+		{"missing cookie", func(*http.Cookie) *http.Cookie { return nil }, http.StatusBadRequest, enum.CodeSocialStateInvalid, false},
+		{"cookie of another flow", func(*http.Cookie) *http.Cookie {
+			sum := sha256.Sum256([]byte("attacker-state"))
+			return &http.Cookie{Name: socialStateCookie, Value: hex.EncodeToString(sum[:])}
+		}, http.StatusBadRequest, enum.CodeSocialStateInvalid, false},
+		{"raw state as cookie", nil, http.StatusBadRequest, enum.CodeSocialStateInvalid, false},
+		// binding holds, so the code reaches the provider (which refuses it)
+		{"matching cookie", func(own *http.Cookie) *http.Cookie { return own }, http.StatusUnauthorized, enum.CodeSocialLoginFailed, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newSocialEnv(t)
+			state, own := env.startLogin(t)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/social/mock/callback?code=c1&state="+url.QueryEscape(state), nil)
+			var ck *http.Cookie
+			if tc.cookie != nil {
+				ck = tc.cookie(own)
+			} else {
+				ck = &http.Cookie{Name: socialStateCookie, Value: state}
+			}
+			if ck != nil {
+				req.AddCookie(ck)
+			}
+			w := env.do(req)
+
+			var res dto.ErrorResponse
+			_ = json.Unmarshal(w.Body.Bytes(), &res)
+			code := ""
+			if res.Error != nil {
+				code = res.Error.Code
+			}
+			if w.Code != tc.wantCode || code != tc.wantErr {
+				t.Fatalf("got %d %q, want %d %q: %s", w.Code, code, tc.wantCode, tc.wantErr, w.Body)
+			}
+			if got := env.exchanges.Load() > 0; got != tc.exchanged {
+				t.Errorf("code exchanged = %v, want %v", got, tc.exchanged)
+			}
+			// a rejected callback must not burn the victim's pending state
+			pending := env.redis.Exists(context.Background(), keys.SocialState(state)).Val() == 1
+			if pending == tc.exchanged {
+				t.Errorf("state still pending = %v", pending)
+			}
+			if ck := stateCookie(t, w); ck.MaxAge >= 0 {
+				t.Errorf("callback did not clear the cookie: %+v", ck)
+			}
+		})
+	}
+}
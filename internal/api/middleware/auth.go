@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HeaderAuthorization = "Authorization"
+	bearerScheme        = "Bearer"
+
+	// Identity keys set on the gin context by AuthMiddleware.
+	ContextKeyUserID    = "user_id"
+	ContextKeyDeviceID  = "device_id"
+	ContextKeySessionID = "session_id"
+	ContextKeyClientID  = "client_id"
+	ContextKeyTokenID   = "api_token_id"
+	ContextKeyRoles     = "roles"
+	ContextKeyClaims    = "access_claims"
+)
+
+// AuthMiddleware authenticates requests with a JWT access token and enforces
+// real-time revocation: the token must reference a live session (sid) whose
+// device is not blocked, carry the current user version (uv) and belong to an
+// active, undeleted account. Stateless checks (signature, alg, iss, exp, typ) run
+// first so that garbage tokens never hit Redis.
+//
+// Tokens issued to third-party OAuth clients are only accepted when they were
+// granted one of scopes; routes that list none are closed to them.
+//
+// Personal access tokens and service API keys (sbd_...) are accepted too and
+// set the same identity, without a session or device; see apiTokenAuth.
+func AuthMiddleware(authService *service.AuthService, scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqTime := time.Now().UTC()
+		traceID := TraceID(c)
+
+		raw, ok := bearerToken(c.GetHeader(HeaderAuthorization))
+		if !ok {
+			abortAuth(c, http.StatusUnauthorized, enum.CodeMissingToken, "Missing bearer token", traceID, reqTime, enum.ErrMissingToken)
+			return
+		}
+		if service.IsAPIToken(raw) {
+			apiTokenAuth(c, authService, raw, scopes, traceID, reqTime)
+			return
+		}
+		claims, err := authService.ParseAccessToken(raw)
+		if err != nil {
+			abortAuth(c, http.StatusUnauthorized, tokenErrorCode(err), "Invalid access token", traceID, reqTime, err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		sess, err := authService.GetSession(ctx, claims.SessionID)
+		if err != nil {
+			abortAuth(c, http.StatusInternalServerError, enum.CodeInternalError, "Unable to load session", traceID, reqTime, err)
+			return
+		}
+		if sess == nil || sess.Status != model.Active || sess.UserID != claims.Subject || sess.DeviceID != claims.DeviceID {
+			abortAuth(c, http.StatusUnauthorized, enum.CodeSessionRevoked, "Session is no longer valid", traceID, reqTime, enum.ErrSessionRevoked)
+			return
+		}
+		if sess.Delegated && !hasAnyScope(splitScope(claims.Scope), scopes) {
+			abortAuth(c, http.StatusForbidden, enum.CodeInsufficientScope, "Token scope does not allow this request", traceID, reqTime, enum.ErrInsufficientScope)
+			return
+		}
+		if err := authService.CheckUserVersion(ctx, claims.Subject, claims.Uv); err != nil {
+			if errors.Is(err, enum.ErrInvalidToken) {
+				abortAuth(c, http.StatusUnauthorized, enum.CodeInvalidToken, "Access token has been revoked", traceID, reqTime, err)
+			} else {
+				abortAuth(c, http.StatusInternalServerError, enum.CodeInternalError, "Unable to check token version", traceID, reqTime, err)
+			}
+			return
+		}
+		if err := authService.CheckUserActive(ctx, claims.Subject); err != nil {
+			if errors.Is(err, enum.ErrAccountDisabled) {
+				abortAuth(c, http.StatusUnauthorized, enum.CodeAccountDisabled, "Account is disabled", traceID, reqTime, err)
+			} else {
+				abortAuth(c, http.StatusInternalServerError, enum.CodeInternalError, "Unable to check account state", traceID, reqTime, err)
+			}
+			return
+		}
+		dev, err := authService.GetDevice(ctx, claims.DeviceID)
+		if err != nil {
+			abortAuth(c, http.StatusInternalServerError, enum.CodeInternalError, "Unable to load device", traceID, reqTime, err)
+			return
+		}
+		if dev != nil && dev.Status == model.Block {
+			abortAuth(c, http.StatusUnauthorized, enum.CodeDeviceBlocked, "Device is blocked", traceID, reqTime, enum.ErrDeviceBlocked)
+			return
+		}
+
+		perms, err := authService.PermissionsForRoles(ctx, claims.Roles)
+		if err != nil {
+			abortAuth(c, http.StatusInternalServerError, enum.CodeInternalError, "Unable to load permissions", traceID, reqTime, err)
+			return
+		}
+		if err := authService.TouchSession(ctx, claims.SessionID, sess, c.ClientIP()); err != nil {
+			c.Error(err)
+		}
+
+		c.Set(ContextKeyUserID, claims.Subject)
+		c.Set(ContextKeyDeviceID, claims.DeviceID)
+		c.Set(ContextKeySessionID, claims.SessionID)
+		c.Set(ContextKeyClientID, claims.ClientID)
+		c.Set(ContextKeyRoles, claims.Roles)
+		c.Set(ContextKeyPermissions, perms)
+		c.Set(ContextKeyScopes, splitScope(claims.Scope))
+		c.Set(ContextKeyClaims, claims)
+
+		ri := service.RequestInfoFrom(ctx)
+		ri.UserID, ri.DeviceID, ri.SessionID = claims.Subject, claims.DeviceID, claims.SessionID
+		c.Request = c.Request.WithContext(service.WithRequestInfo(ctx, ri))
+		c.Next()
+	}
+}
+
+// apiTokenAuth authenticates a personal access token or service API key. The
+// token carries no roles and its permissions are the user's, cut down to the
+// token's scopes, so role-gated routes stay closed to it. Routes that list
+// OAuth scopes need the matching permission, see APITokenIdentity.AllowsScopes.
+func apiTokenAuth(c *gin.Context, authService *service.AuthService, raw string, scopes []string, traceID string, reqTime time.Time) {
+	ctx := c.Request.Context()
+	ident, err := authService.AuthenticateAPIToken(ctx, raw)
+	if err != nil {
+		switch {
+		case errors.Is(err, enum.ErrAPITokenInvalid):
+			abortAuth(c, http.StatusUnauthorized, enum.CodeAPITokenInvalid, "Invalid API token", traceID, reqTime, err)
+		case errors.Is(err, enum.ErrAccountDisabled):
+			abortAuth(c, http.StatusUnauthorized, enum.CodeAccountDisabled, "Account is disabled", traceID, reqTime, err)
+		default:
+			abortAuth(c, http.StatusInternalServerError, enum.CodeInternalError, "Unable to check API token", traceID, reqTime, err)
+		}
+		return
+	}
+	if !ident.AllowsScopes(scopes) {
+		abortAuth(c, http.StatusForbidden, enum.CodeInsufficientScope, "Token scope does not allow this request", traceID, reqTime, enum.ErrInsufficientScope)
+		return
+	}
+
+	c.Set(ContextKeyUserID, ident.UserID)
+	c.Set(ContextKeyTokenID, ident.TokenID)
+	c.Set(ContextKeyRoles, []string{})
+	c.Set(ContextKeyPermissions, ident.Permissions)
+	c.Set(ContextKeyScopes, ident.Scopes)
+
+	ri := service.RequestInfoFrom(ctx)
+	ri.UserID = ident.UserID
+	c.Request = c.Request.WithContext(service.WithRequestInfo(ctx, ri))
+	c.Next()
+}
+
+// RequireSession rejects requests authenticated with an API token, for
+// routes that must be reached from a login, such as managing API tokens.
+// Must run after AuthMiddleware.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetSessionID(c) == "" {
+			abortAuth(c, http.StatusForbidden, enum.CodeForbidden, "Not allowed with an API token", TraceID(c), time.Now().UTC(), enum.ErrForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetUserID returns the authenticated user id, or "" outside AuthMiddleware.
+func GetUserID(c *gin.Context) string { return c.GetString(ContextKeyUserID) }
+
+// GetDeviceID returns the device id bound to the access token.
+func GetDeviceID(c *gin.Context) string { return c.GetString(ContextKeyDeviceID) }
+
+// GetSessionID returns the session id (sid claim) of the access token.
+func GetSessionID(c *gin.Context) string { return c.GetString(ContextKeySessionID) }
+
+// GetClientID returns the OAuth client the token was issued to, or "" for
+// first-party logins.
+func GetClientID(c *gin.Context) string { return c.GetString(ContextKeyClientID) }
+
+// GetAPITokenID returns the API token the request was made with, or "" for
+// JWT access tokens.
+func GetAPITokenID(c *gin.Context) string { return c.GetString(ContextKeyTokenID) }
+
+// GetRoles returns the roles carried by the access token.
+func GetRoles(c *gin.Context) []string { return c.GetStringSlice(ContextKeyRoles) }
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, bearerScheme) {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func tokenErrorCode(err error) string {
+	switch {
+	case errors.Is(err, enum.ErrWrongType):
+		return enum.CodeWrongTokenType
+	case errors.Is(err, enum.ErrWrongAlgorithm):
+		return enum.CodeUnexpectedAlg
+	default:
+		return enum.CodeInvalidToken
+	}
+}
+
+func abortAuth(c *gin.Context, status int, code, message, traceID string, reqTime time.Time, err error) {
+	c.AbortWithStatusJSON(status, dto.NewError(status, code, message, traceID, reqTime, err))
+}
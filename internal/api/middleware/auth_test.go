@@ -0,0 +1,388 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"seno-blackdragon/internal/db/apitoken"
+	"seno-blackdragon/internal/db/dbtest"
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/dto"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/pass"
+	"seno-blackdragon/pkg/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	testIssuer = "seno-blackdragon"
+	testUserID = "6a3c1d2e-8f4b-4e2a-b1c0-9d8e7f6a5b4c"
+	testSID    = "SID_TEST"
+	testDID    = "DEV_TEST"
+)
+
+var testAccessSecret = []byte("access-secret-for-tests")
+
+// authEnv is an AuthService over miniredis with a live session for
+// testUserID on testDID. Its repositories read from db.
+type authEnv struct {
+	as    *service.AuthService
+	redis *redis.Client
+	db    *dbtest.DB
+}
+
+func newAuthEnv(t *testing.T, opts ...func(*service.JWTConfig)) *authEnv {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	cfg := service.JWTConfig{
+		AccessSecret:  testAccessSecret,
+		RefreshSecret: []byte("refresh-secret-for-tests"),
+		AccessTTL:     15 * time.Minute,
+		RefreshTTL:    time.Hour,
+		Issuer:        testIssuer,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	db := dbtest.New()
+	as := service.NewAuthService(repository.NewUserRepo(db), nil, repository.NewRBACRepo(db), nil, nil, nil, nil, repository.NewAPITokenRepo(db),
+		pass.Config{Algo: pass.AlgoBcrypt, BcryptCost: 4}, rdb, nil, cfg, service.Policy{}, zap.NewNop())
+	env := &authEnv{as: as, redis: rdb, db: db}
+
+	ctx := context.Background()
+	if err := as.SaveSession(ctx, testSID, &service.Session{UserID: testUserID, DeviceID: testDID, Status: model.Active}, 3600); err != nil {
+		t.Fatal(err)
+	}
+	if err := as.SaveDevice(ctx, &service.Device{UserID: testUserID, DeviceID: testDID, Status: model.Active}); err != nil {
+		t.Fatal(err)
+	}
+	rdb.Set(ctx, keys.UserVer(testUserID), 1, 0)
+	rdb.Set(ctx, keys.UserActive(testUserID), "1", 0)
+	return env
+}
+
+func accessClaims() *service.AccessClaims {
+	now := time.Now()
+	return &service.AccessClaims{
+		TokenType: "access",
+		SessionID: testSID,
+		DeviceID:  testDID,
+		Uv:        1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Subject:   testUserID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+}
+
+func signHS(t *testing.T, method jwt.SigningMethod, claims jwt.Claims) string {
+	t.Helper()
+	s, err := jwt.NewWithClaims(method, claims).SignedString(testAccessSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// serveAuth runs AuthMiddleware with the given bearer token and returns the
+// status, the error code and the user id seen by the handler.
+func serveAuth(as *service.AuthService, token string, scopes ...string) (int, string, string) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	var userID string
+	r.GET("/", AuthMiddleware(as, scopes...), func(c *gin.Context) {
+		userID = GetUserID(c)
+		c.Status(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		req.Header.Set(HeaderAuthorization, "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var body dto.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	code := ""
+	if body.Error != nil {
+		code = body.Error.Code
+	}
+	return w.Code, code, userID
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name  string
+		token func(t *testing.T) string
+		setup func(env *authEnv)
+		want  int
+		code  string
+	}{
+		{
+			name:  "valid",
+			token: func(t *testing.T) string { return signHS(t, jwt.SigningMethodHS256, accessClaims()) },
+			want:  http.StatusOK,
+		},
+		{
+			name:  "missing token",
+			token: func(t *testing.T) string { return "" },
+			want:  http.StatusUnauthorized,
+			code:  enum.CodeMissingToken,
+		},
+		{
+			name:  "garbage token",
+			token: func(t *testing.T) string { return "not-a-jwt" },
+			want:  http.StatusUnauthorized,
+			code:  enum.CodeInvalidToken,
+		},
+		{
+			name: "refresh token presented as access",
+			token: func(t *testing.T) string {
+				c := accessClaims()
+				c.TokenType = "refresh"
+				return signHS(t, jwt.SigningMethodHS256, c)
+			},
+			want: http.StatusUnauthorized,
+			code: enum.CodeWrongTokenType,
+		},
+		{
+			name:  "unexpected alg",
+			token: func(t *testing.T) string { return signHS(t, jwt.SigningMethodHS512, accessClaims()) },
+			want:  http.StatusUnauthorized,
+			code:  enum.CodeUnexpectedAlg,
+		},
+		{
+			name: "alg none",
+			token: func(t *testing.T) string {
+				s, err := jwt.NewWithClaims(jwt.SigningMethodNone, accessClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s
+			},
+			want: http.StatusUnauthorized,
+			code: enum.CodeUnexpectedAlg,
+		},
+		{
+			name: "wrong issuer",
+			token: func(t *testing.T) string {
+				c := accessClaims()
+				c.Issuer = "someone-else"
+				return signHS(t, jwt.SigningMethodHS256, c)
+			},
+			want: http.StatusUnauthorized,
+			code: enum.CodeInvalidToken,
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T) string {
+				c := accessClaims()
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return signHS(t, jwt.SigningMethodHS256, c)
+			},
+			want: http.StatusUnauthorized,
+			code: enum.CodeInvalidToken,
+		},
+		{
+			name:  "revoked session",
+			token: func(t *testing.T) string { return signHS(t, jwt.SigningMethodHS256, accessClaims()) },
+			setup: func(env *authEnv) {
+				_ = env.as.SaveSession(ctx, testSID, &service.Session{UserID: testUserID, DeviceID: testDID, Status: model.Revoked}, 3600)
+			},
+			want: http.StatusUnauthorized,
+			code: enum.CodeSessionRevoked,
+		},
+		{
+			name:  "deleted session",
+			token: func(t *testing.T) string { return signHS(t, jwt.SigningMethodHS256, accessClaims()) },
+			setup: func(env *authEnv) { _ = env.as.DelSession(ctx, testUserID, testSID) },
+			want:  http.StatusUnauthorized,
+			code:  enum.CodeSessionRevoked,
+		},
+		{
+			name: "session of another device",
+			token: func(t *testing.T) string {
+				c := accessClaims()
+				c.DeviceID = "DEV_OTHER"
+				return signHS(t, jwt.SigningMethodHS256, c)
+			},
+			want: http.StatusUnauthorized,
+			code: enum.CodeSessionRevoked,
+		},
+		{
+			name:  "blocked device",
+			token: func(t *testing.T) string { return signHS(t, jwt.SigningMethodHS256, accessClaims()) },
+			setup: func(env *authEnv) {
+				_ = env.as.SaveDevice(ctx, &service.Device{UserID: testUserID, DeviceID: testDID, Status: model.Block})
+			},
+			want: http.StatusUnauthorized,
+			code: enum.CodeDeviceBlocked,
+		},
+		{
+			name:  "stale user version",
+			token: func(t *testing.T) string { return signHS(t, jwt.SigningMethodHS256, accessClaims()) },
+			setup: func(env *authEnv) { env.redis.Incr(ctx, keys.UserVer(testUserID)) },
+			want:  http.StatusUnauthorized,
+			code:  enum.CodeInvalidToken,
+		},
+		{
+			name:  "deactivated user",
+			token: func(t *testing.T) string { return signHS(t, jwt.SigningMethodHS256, accessClaims()) },
+			setup: func(env *authEnv) { env.redis.Set(ctx, keys.UserActive(testUserID), "0", 0) },
+			want:  http.StatusUnauthorized,
+			code:  enum.CodeAccountDisabled,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newAuthEnv(t)
+			if tc.setup != nil {
+				tc.setup(env)
+			}
+			status, code, userID := serveAuth(env.as, tc.token(t))
+			if status != tc.want || code != tc.code {
+				t.Fatalf("expected %d %q, got %d %q", tc.want, tc.code, status, code)
+			}
+			if status == http.StatusOK && userID != testUserID {
+				t.Errorf("expected user %s on the context, got %q", testUserID, userID)
+			}
+		})
+	}
+}
+
+func TestRequireSession(t *testing.T) {
+	cases := []struct {
+		name string
+		sid  string
+		want int
+	}{
+		{"session", "sid_1", http.StatusOK},
+		{"api token", "", http.StatusForbidden},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tc := range cases {
+		r := gin.New()
+		r.GET("/", func(c *gin.Context) {
+			if tc.sid != "" {
+				c.Set(ContextKeySessionID, tc.sid)
+			}
+			c.Next()
+		}, RequireSession(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, w.Code)
+		}
+	}
+}
+
+const testPAT = "sbd_pat_Abc123xyZ-0123456789abcdefghijklmnopqrstu"
+
+// patRow is the stored form of testPAT with the given scopes.
+func patRow(scopes ...string) apitoken.ApiToken {
+	sum := sha256.Sum256([]byte(testPAT))
+	return apitoken.ApiToken{
+		ID:        utils.PgUUIDFromUUID(uuid.New()),
+		UserID:    utils.PgUUIDFromUUID(uuid.MustParse(testUserID)),
+		Kind:      service.APITokenPersonal,
+		Name:      "ci",
+		Prefix:    testPAT[:len("sbd_pat_")+8],
+		TokenHash: hex.EncodeToString(sum[:]),
+		Scopes:    scopes,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+}
+
+func TestAuthMiddlewareAPIToken(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name   string
+		row    *apitoken.ApiToken
+		token  string
+		scopes []string
+		setup  func(env *authEnv)
+		want   int
+		code   string
+	}{
+		{name: "valid", row: ptr(patRow("booking:read")), want: http.StatusOK},
+		{name: "valid for a scoped route", row: ptr(patRow(service.PermProfileRead)), scopes: []string{service.ScopeProfile}, want: http.StatusOK},
+		{
+			name: "expired",
+			row: func() *apitoken.ApiToken {
+				r := patRow("booking:read")
+				r.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+				return &r
+			}(),
+			want: http.StatusUnauthorized,
+			code: enum.CodeAPITokenInvalid,
+		},
+		{name: "revoked", want: http.StatusUnauthorized, code: enum.CodeAPITokenInvalid},
+		{name: "wrong secret", row: ptr(patRow("booking:read")), token: testPAT[:len(testPAT)-1] + "v", want: http.StatusUnauthorized, code: enum.CodeAPITokenInvalid},
+		{name: "wrong scope", row: ptr(patRow("booking:read")), scopes: []string{service.ScopeProfile}, want: http.StatusForbidden, code: enum.CodeInsufficientScope},
+		{
+			name:   "scope the user lost",
+			row:    ptr(patRow(service.PermProfileRead)),
+			scopes: []string{service.ScopeOpenID},
+			setup: func(env *authEnv) {
+				env.redis.Set(ctx, keys.RolePerms("user"), `["booking:read"]`, 0)
+			},
+			want: http.StatusForbidden,
+			code: enum.CodeInsufficientScope,
+		},
+		{
+			name:  "deactivated user",
+			row:   ptr(patRow("booking:read")),
+			setup: func(env *authEnv) { env.redis.Set(ctx, keys.UserActive(testUserID), "0", 0) },
+			want:  http.StatusUnauthorized,
+			code:  enum.CodeAccountDisabled,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newAuthEnv(t)
+			if tc.row != nil {
+				env.db.On("GetAPITokenByPrefix", dbtest.Row(*tc.row))
+			}
+			env.db.On("ListUserRoles", dbtest.Value("user"))
+			env.redis.Set(ctx, keys.RolePerms("user"), `["booking:read","profile:read"]`, 0)
+			if tc.setup != nil {
+				tc.setup(env)
+			}
+			token := tc.token
+			if token == "" {
+				token = testPAT
+			}
+			status, code, userID := serveAuth(env.as, token, tc.scopes...)
+			if status != tc.want || code != tc.code {
+				t.Fatalf("expected %d %q, got %d %q", tc.want, tc.code, status, code)
+			}
+			if status == http.StatusOK && userID != testUserID {
+				t.Errorf("expected user %s on the context, got %q", testUserID, userID)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -170,6 +170,15 @@ func TraceAndLogFullMiddleware(logger *zap.Logger, opts *TraceLogOptions) gin.Ha
 	}
 }
 
+// TraceID returns the trace id set by TraceAndLogFullMiddleware, falling back
+// to the incoming header when the middleware did not run.
+func TraceID(c *gin.Context) string {
+	if traceID := c.GetString(ContextKeyTraceID); traceID != "" {
+		return traceID
+	}
+	return c.GetHeader(HeaderKeyTraceID)
+}
+
 // ===== Helpers =====
 
 func toSet(csv string) map[string]struct{} {
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oklog/ulid/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset" // seconds until a slot frees up
+	HeaderRetryAfter         = "Retry-After"
+)
+
+// RateLimitKey derives the subject a request is counted against.
+type RateLimitKey func(c *gin.Context) string
+
+// ByIP counts requests per client IP.
+func ByIP(c *gin.Context) string { return "ip:" + c.ClientIP() }
+
+// ByUser counts requests per authenticated user, falling back to the IP
+// outside AuthMiddleware.
+func ByUser(c *gin.Context) string {
+	if uid := GetUserID(c); uid != "" {
+		return "user:" + uid
+	}
+	return ByIP(c)
+}
+
+// ByDevice counts requests per device of the access token, falling back to
+// the IP outside AuthMiddleware.
+func ByDevice(c *gin.Context) string {
+	if did := GetDeviceID(c); did != "" {
+		return "device:" + did
+	}
+	return ByIP(c)
+}
+
+// RateLimitPolicy allows Limit requests per sliding Window for each subject.
+// Name namespaces the counters so route groups do not share budgets.
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+	Key    RateLimitKey
+}
+
+// ParseRate parses "N/duration", e.g. "10/1m". An empty string or "0" means
+// no limit and returns limit 0.
+func ParseRate(s string) (int, time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return 0, 0, nil
+	}
+	n, d, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid rate %q, want N/duration", s)
+	}
+	limit, err := strconv.Atoi(n)
+	if err != nil || limit < 0 {
+		return 0, 0, fmt.Errorf("invalid rate limit %q", n)
+	}
+	window, err := time.ParseDuration(d)
+	if err != nil || window <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate window %q", d)
+	}
+	return limit, window, nil
+}
+
+// slidingWindow keeps one sorted-set member per accepted request, scored by
+// its time in ms. Returns {allowed, remaining, ms until the oldest expires}.
+var slidingWindow = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], 0, now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+  redis.call('ZADD', KEYS[1], now, ARGV[4])
+  redis.call('PEXPIRE', KEYS[1], window)
+  count = count + 1
+  allowed = 1
+end
+local reset = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+  reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, limit - count, reset}
+`)
+
+// RateLimit enforces p with a sliding window log in rdb. Redis failures let
+// the request through: the limiter must not take the API down with it.
+func RateLimit(rdb *redis.Client, p RateLimitPolicy) gin.HandlerFunc {
+	if p.Limit <= 0 || p.Window <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	if p.Key == nil {
+		p.Key = ByIP
+	}
+	return func(c *gin.Context) {
+		now := time.Now()
+		res, err := slidingWindow.Run(c.Request.Context(), rdb,
+			[]string{keys.RateLimit(p.Name, p.Key(c))},
+			now.UnixMilli(), p.Window.Milliseconds(), p.Limit, ulid.Make().String(),
+		).Int64Slice()
+		if err != nil || len(res) != 3 {
+			c.Error(fmt.Errorf("rate limit %s: %w", p.Name, err))
+			c.Next()
+			return
+		}
+		resetSec := int64(math.Ceil(float64(res[2]) / 1000))
+		c.Header(HeaderRateLimitLimit, strconv.Itoa(p.Limit))
+		c.Header(HeaderRateLimitRemaining, strconv.FormatInt(max(res[1], 0), 10))
+		c.Header(HeaderRateLimitReset, strconv.FormatInt(resetSec, 10))
+		if res[0] == 0 {
+			c.Header(HeaderRetryAfter, strconv.FormatInt(max(resetSec, 1), 10))
+			abortAuth(c, http.StatusTooManyRequests, enum.CodeRateLimited, "Too many requests", TraceID(c), now.UTC(), enum.ErrRateLimited)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseRate(t *testing.T) {
+	cases := []struct {
+		in     string
+		limit  int
+		window time.Duration
+		ok     bool
+	}{
+		{"10/1m", 10, time.Minute, true},
+		{" 300/30s ", 300, 30 * time.Second, true},
+		{"", 0, 0, true},
+		{"0", 0, 0, true},
+		{"10", 0, 0, false},
+		{"ten/1m", 0, 0, false},
+		{"10/0s", 0, 0, false},
+	}
+	for _, tc := range cases {
+		limit, window, err := ParseRate(tc.in)
+		if (err == nil) != tc.ok || limit != tc.limit || window != tc.window {
+			t.Errorf("ParseRate(%q) = %d, %s, %v", tc.in, limit, window, err)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	const window = 300 * time.Millisecond
+	r.GET("/", RateLimit(rdb, RateLimitPolicy{Name: "test", Limit: 2, Window: window, Key: ByIP}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	serve := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		w := serve("192.0.2.1")
+		if w.Code != http.StatusOK || w.Header().Get(HeaderRateLimitRemaining) != wantRemaining {
+			t.Fatalf("request %d: got %d with %s remaining", i+1, w.Code, w.Header().Get(HeaderRateLimitRemaining))
+		}
+		if w.Header().Get(HeaderRetryAfter) != "" {
+			t.Errorf("request %d: Retry-After on an allowed request", i+1)
+		}
+	}
+	w := serve("192.0.2.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("over the limit: got %d, want 429", w.Code)
+	}
+	if ra, err := strconv.Atoi(w.Header().Get(HeaderRetryAfter)); err != nil || ra < 1 || ra > int(window.Seconds())+1 {
+		t.Errorf("Retry-After %q, want whole seconds within the window", w.Header().Get(HeaderRetryAfter))
+	}
+	if w := serve("192.0.2.2"); w.Code != http.StatusOK {
+		t.Errorf("another IP: got %d, want its own budget", w.Code)
+	}
+
+	// the window slides: once the first requests age out there is room again
+	time.Sleep(window + 50*time.Millisecond)
+	if w := serve("192.0.2.1"); w.Code != http.StatusOK {
+		t.Errorf("after the window: got %d, want 200", w.Code)
+	}
+
+	// a Redis outage must not take the API down
+	mr.Close()
+	if w := serve("192.0.2.1"); w.Code != http.StatusOK {
+		t.Errorf("without Redis: got %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContextKeyPermissions = "permissions"
+
+// GetPermissions returns the permissions resolved from the token's roles.
+func GetPermissions(c *gin.Context) []string { return c.GetStringSlice(ContextKeyPermissions) }
+
+// RequireRole lets the request through when the caller has any of roles.
+// Must run after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		have := GetRoles(c)
+		for _, r := range roles {
+			if slices.Contains(have, r) {
+				c.Next()
+				return
+			}
+		}
+		abortAuth(c, http.StatusForbidden, enum.CodeForbidden, "Insufficient role", TraceID(c), time.Now().UTC(), enum.ErrForbidden)
+	}
+}
+
+// RequirePermission lets the request through only when the caller holds every
+// listed permission, e.g. RequirePermission("booking:write").
+// Must run after AuthMiddleware.
+func RequirePermission(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		have := GetPermissions(c)
+		for _, p := range perms {
+			if !slices.Contains(have, p) {
+				abortAuth(c, http.StatusForbidden, enum.CodeForbidden, "Missing permission "+p, TraceID(c), time.Now().UTC(), enum.ErrForbidden)
+				return
+			}
+		}
+		c.Next()
+	}
+}
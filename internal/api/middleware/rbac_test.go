@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newAuthzRouter(roles, perms []string, guard gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		c.Set(ContextKeyRoles, roles)
+		c.Set(ContextKeyPermissions, perms)
+		c.Next()
+	}, guard, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestRequireRole(t *testing.T) {
+	cases := []struct {
+		name  string
+		roles []string
+		want  int
+	}{
+		{"has role", []string{"user", "admin"}, http.StatusOK},
+		{"missing role", []string{"user"}, http.StatusForbidden},
+		{"no roles", nil, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		newAuthzRouter(tc.roles, nil, RequireRole("admin")).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, w.Code)
+		}
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	cases := []struct {
+		name  string
+		perms []string
+		want  int
+	}{
+		{"has all", []string{"booking:read", "booking:write"}, http.StatusOK},
+		{"has one", []string{"booking:read"}, http.StatusForbidden},
+		{"none", nil, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		guard := RequirePermission("booking:read", "booking:write")
+		newAuthzRouter(nil, tc.perms, guard).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"seno-blackdragon/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestInfo attaches the client address and user agent to the request
+// context for the audit log. AuthMiddleware adds the identity later on.
+func RequestInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := service.WithRequestInfo(c.Request.Context(), service.RequestInfo{
+			IP: c.ClientIP(),
+			UA: c.Request.UserAgent(),
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"seno-blackdragon/internal/service"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContextKeyScopes = "scopes"
+
+// GetScopes returns the scopes carried by the access token.
+func GetScopes(c *gin.Context) []string { return c.GetStringSlice(ContextKeyScopes) }
+
+// RequireVerifiedEmail rejects tokens restricted by the email verification
+// policy. Must run after AuthMiddleware.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(GetScopes(c), service.ScopeUnverified) {
+			abortAuth(c, http.StatusForbidden, enum.CodeEmailNotVerified, "Email not verified", TraceID(c), time.Now().UTC(), enum.ErrEmailNotVerified)
+			return
+		}
+		c.Next()
+	}
+}
+
+func splitScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+func hasAnyScope(have, want []string) bool {
+	for _, s := range want {
+		if slices.Contains(have, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"seno-blackdragon/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	cases := []struct {
+		name  string
+		scope string
+		want  int
+	}{
+		{"verified", "", http.StatusOK},
+		{"unverified", service.ScopeUnverified, http.StatusForbidden},
+		{"unverified among others", "openid " + service.ScopeUnverified, http.StatusForbidden},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tc := range cases {
+		r := gin.New()
+		r.GET("/", func(c *gin.Context) {
+			c.Set(ContextKeyScopes, splitScope(tc.scope))
+			c.Next()
+		}, RequireVerifiedEmail(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, w.Code)
+		}
+	}
+}
@@ -1,31 +1,48 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"seno-blackdragon/internal/api/handler"
+	"seno-blackdragon/internal/api/middleware"
 	"seno-blackdragon/internal/config"
 	"seno-blackdragon/internal/repository"
 	"seno-blackdragon/internal/service"
 	"seno-blackdragon/internal/store"
 	"seno-blackdragon/internal/version"
-	"seno-blackdragon/pkg/middleware"
+	"seno-blackdragon/pkg/keyring"
+	"seno-blackdragon/pkg/notify"
+	"seno-blackdragon/pkg/oidcclient"
 	"seno-blackdragon/pkg/pass"
+	"seno-blackdragon/pkg/webauthn"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/zap"
 )
 
+// InitRouter also returns a shutdown func that flushes background work, such
+// as queued audit events; call it after the HTTP server has shut down.
+//
 // @title           Seno-BlackDragon API
 // @version         1.0
 // @description     This is a black dragon server.
 // @host            localhost:8080
 // @BasePath        /api/v1
-func InitRouter(db *pgx.Conn, logger *zap.Logger, redis *store.ClientSet, cfg *config.Config) *gin.Engine {
+// @securityDefinitions.apikey BearerAuth
+// @in                         header
+// @name                       Authorization
+// @description                Type "Bearer" followed by a space and the access token.
+func InitRouter(db *pgxpool.Pool, logger *zap.Logger, redis *store.ClientSet, cfg *config.Config) (*gin.Engine, func(context.Context) error) {
 	router := gin.Default()
+	// c.ClientIP() honours X-Forwarded-For only from these (nginx in front)
+	if err := router.SetTrustedProxies(splitList(cfg.TrustedProxies)); err != nil {
+		logger.Fatal("Invalid trusted proxies", zap.Error(err))
+	}
 	router.Use(middleware.TraceAndLogFullMiddleware(logger, nil))
 	router.GET("/docs", func(c *gin.Context) {
 		c.Redirect(302, "/docs/index.html")
@@ -47,9 +64,9 @@ func InitRouter(db *pgx.Conn, logger *zap.Logger, redis *store.ClientSet, cfg *c
 		})
 	})
 
+	var shutdown func(context.Context) error
 	v1 := router.Group("/api/v1")
 	{
-		v1.GET("ping", handler.Ping)
 		// auth
 		jwtCfg := service.JWTConfig{
 			AccessSecret:  []byte(cfg.JwtAccessSecret),
@@ -57,17 +74,210 @@ func InitRouter(db *pgx.Conn, logger *zap.Logger, redis *store.ClientSet, cfg *c
 			AccessTTL:     15 * time.Minute,
 			RefreshTTL:    30 * 24 * time.Hour,
 			Issuer:        "seno-blackdragon",
+			OIDCIssuer:    cfg.OIDCIssuer,
+		}
+		if cfg.JwtKeyFiles != "" {
+			kr, err := keyring.LoadPEMFiles(cfg.JwtKeyFiles, cfg.JwtActiveKid)
+			if err != nil {
+				logger.Fatal("Unable to load JWT signing keys", zap.Error(err))
+			}
+			jwtCfg.AccessKeys, jwtCfg.RefreshKeys = kr, kr
 		}
-		hasher := pass.NewBcryptHasher(pass.BcryptOptions{Cost: 12})
+		// new hashes use PasswordHashAlgo; older bcrypt hashes are upgraded on login
+		passCfg := pass.Config{Algo: pass.Algo(cfg.PasswordHashAlgo), BcryptCost: 12}
 
 		authRepo := repository.NewUserRepo(db)
-		authService := service.NewAuthService(authRepo, hasher, redis.MustGet("token"), jwtCfg, logger)
+		mfaRepo := repository.NewMFARepo(db)
+		rbacRepo := repository.NewRBACRepo(db)
+		auditRepo := repository.NewAuditRepo(db)
+		oauthRepo := repository.NewOAuthRepo(db)
+		identityRepo := repository.NewIdentityRepo(db)
+		passkeyRepo := repository.NewPasskeyRepo(db)
+		apiTokenRepo := repository.NewAPITokenRepo(db)
+		notifier := notify.New(cfg.NotifySink, cfg.NotifyFilePath, logger)
+		authService := service.NewAuthService(authRepo, mfaRepo, rbacRepo, auditRepo, oauthRepo, identityRepo, passkeyRepo, apiTokenRepo, passCfg, redis.MustGet("Token"), notifier, jwtCfg, service.Policy{
+			EmailVerification: cfg.EmailVerificationPolicy,
+			Lockout: service.LockoutPolicy{
+				DelayAfter:    cfg.LockoutDelayAfter,
+				MaxAttempts:   cfg.LockoutMaxAttempts,
+				IPMaxAttempts: cfg.LockoutIPMaxAttempts,
+				Window:        cfg.LockoutWindow,
+				Duration:      cfg.LockoutDuration,
+				AutoBanIP:     cfg.LockoutAutoBanIP,
+			},
+			DeletionGrace:   time.Duration(cfg.AccountDeletionGraceDays) * 24 * time.Hour,
+			SocialProviders: socialProviders(cfg),
+			WebAuthn: webauthn.RelyingParty{
+				ID:      cfg.WebAuthnRPID,
+				Name:    cfg.WebAuthnRPName,
+				Origins: splitList(cfg.WebAuthnOrigins),
+			},
+			PasswordlessSignup: cfg.PasswordlessSignup,
+		}, logger)
+		go authService.RunAccountPurge(context.Background())
+		shutdown = authService.Close
+		v1.Use(middleware.IPFilter(authService), middleware.RequestInfo())
+		v1.GET("ping", handler.Ping)
 		authHandler := handler.NewAuthHandler(authService)
+		mfaHandler := handler.NewMFAHandler(authService)
+		passwordHandler := handler.NewPasswordHandler(authService)
+		emailHandler := handler.NewEmailHandler(authService)
+		socialHandler := handler.NewSocialHandler(authService)
+		passkeyHandler := handler.NewPasskeyHandler(authService)
+		passwordlessHandler := handler.NewPasswordlessHandler(authService)
+		authMw := middleware.AuthMiddleware(authService)
+		// API tokens act for a user but have no session; account and
+		// login management stays with real logins
+		session := middleware.RequireSession()
+		limiter := func(name, rate string, key middleware.RateLimitKey) gin.HandlerFunc {
+			limit, window, err := middleware.ParseRate(rate)
+			if err != nil {
+				logger.Fatal("Invalid rate limit", zap.String("policy", name), zap.Error(err))
+			}
+			return middleware.RateLimit(redis.MustGet("RateLimit"), middleware.RateLimitPolicy{
+				Name: name, Limit: limit, Window: window, Key: key,
+			})
+		}
+		apiLimit := limiter("api", cfg.RateLimitAPI, middleware.ByUser)
+		// token endpoints that clients hit routinely get the API budget per IP
+		tokenLimit := func(name string) gin.HandlerFunc {
+			return limiter(name, cfg.RateLimitAPI, middleware.ByIP)
+		}
+		router.GET("/.well-known/jwks.json", handler.NewJWKSHandler(authService).JWKS)
+		oidcHandler := handler.NewOIDCHandler(authService)
+		if !authService.OIDCEnabled() {
+			logger.Warn("OpenID Connect disabled: JWT_KEY_FILES has no asymmetric active key")
+		}
+		router.GET("/.well-known/openid-configuration", oidcHandler.Discovery)
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/login", authHandler.Login)
-			auth.POST("/register", authHandler.Register)
+			auth.POST("/login", limiter("auth:login", cfg.RateLimitAuth, middleware.ByIP), authHandler.Login)
+			auth.POST("/register", limiter("auth:register", cfg.RateLimitAuth, middleware.ByIP), authHandler.Register)
+			auth.POST("/refresh", tokenLimit("auth:refresh"), authHandler.Refresh)
+			auth.POST("/mfa/verify", limiter("auth:mfa", cfg.RateLimitAuth, middleware.ByIP), mfaHandler.Verify)
+			auth.POST("/password/forgot", limiter("auth:forgot", cfg.RateLimitAuth, middleware.ByIP), passwordHandler.Forgot)
+			auth.POST("/password/reset", limiter("auth:reset", cfg.RateLimitAuth, middleware.ByIP), passwordHandler.Reset)
+			auth.GET("/verify-email", emailHandler.Verify)
+			auth.GET("/email-change/confirm", emailHandler.ConfirmChange)
+			auth.POST("/verify-email/resend", limiter("auth:verify_resend", cfg.RateLimitAuth, middleware.ByIP), emailHandler.Resend)
+			auth.GET("/social", socialHandler.Providers)
+			auth.GET("/social/:provider", limiter("auth:social", cfg.RateLimitAuth, middleware.ByIP), socialHandler.Start)
+			auth.GET("/social/:provider/callback", limiter("auth:social_callback", cfg.RateLimitAuth, middleware.ByIP), socialHandler.Callback)
+			auth.POST("/passkey/login/begin", limiter("auth:passkey", cfg.RateLimitAuth, middleware.ByIP), passkeyHandler.BeginLogin)
+			auth.POST("/passkey/login/finish", limiter("auth:passkey_finish", cfg.RateLimitAuth, middleware.ByIP), passkeyHandler.FinishLogin)
+			auth.POST("/passwordless/start", limiter("auth:passwordless", cfg.RateLimitAuth, middleware.ByIP), passwordlessHandler.Start)
+			auth.POST("/passwordless/verify", limiter("auth:passwordless_verify", cfg.RateLimitAuth, middleware.ByIP), passwordlessHandler.Verify)
+			auth.POST("/logout", authMw, session, authHandler.Logout)
+			auth.POST("/logout/device/:device_id", authMw, session, authHandler.LogoutDevice)
+			auth.POST("/logout/all", authMw, session, authHandler.LogoutAll)
+		}
+
+		sessionHandler := handler.NewSessionHandler(authService)
+		deviceHandler := handler.NewDeviceHandler(authService)
+		auditHandler := handler.NewAuditHandler(authService)
+		profileHandler := handler.NewProfileHandler(authService)
+		apiTokenHandler := handler.NewAPITokenHandler(authService)
+		// tokens restricted by the email verification policy can only
+		// look around and log out until the email is verified
+		verified := middleware.RequireVerifiedEmail()
+		// the only /me route open to third-party OAuth clients and API tokens
+		v1.GET("/me", middleware.AuthMiddleware(authService, service.ScopeProfile), apiLimit, profileHandler.Get)
+		me := v1.Group("/me", authMw, apiLimit, session)
+		{
+			me.PATCH("", verified, profileHandler.Update)
+			me.DELETE("", profileHandler.Delete)
+			me.GET("/sessions", sessionHandler.List)
+			me.DELETE("/sessions/:sid", sessionHandler.Revoke)
+			me.GET("/devices", deviceHandler.List)
+			me.PATCH("/devices/:device_id", verified, deviceHandler.Update)
+			me.POST("/devices/:device_id/block", verified, deviceHandler.Block)
+			me.POST("/mfa/totp/enroll", verified, mfaHandler.EnrollTOTP)
+			me.POST("/mfa/totp/confirm", verified, mfaHandler.ConfirmTOTP)
+			me.POST("/password", verified, passwordHandler.Change)
+			me.GET("/security-events", auditHandler.Mine)
+			me.GET("/identities", socialHandler.ListIdentities)
+			me.POST("/identities/:provider", verified, socialHandler.Link)
+			me.DELETE("/identities/:provider", socialHandler.Unlink)
+			me.GET("/passkeys", passkeyHandler.List)
+			me.POST("/passkeys/register/begin", verified, passkeyHandler.BeginRegistration)
+			me.POST("/passkeys/register/finish", verified, passkeyHandler.FinishRegistration)
+			me.PATCH("/passkeys/:id", verified, passkeyHandler.Rename)
+			me.DELETE("/passkeys/:id", passkeyHandler.Delete)
+			me.GET("/tokens", apiTokenHandler.List)
+			me.POST("/tokens", verified, apiTokenHandler.Create)
+			me.GET("/tokens/:id", apiTokenHandler.Get)
+			me.PATCH("/tokens/:id", verified, apiTokenHandler.Rename)
+			me.DELETE("/tokens/:id", apiTokenHandler.Delete)
 		}
+
+		roleHandler := handler.NewRoleHandler(authService)
+		lockoutHandler := handler.NewLockoutHandler(authService)
+		ipRuleHandler := handler.NewIPRuleHandler(authService)
+		adminUserHandler := handler.NewAdminUserHandler(authService)
+		oauthClientHandler := handler.NewOAuthClientHandler(authService)
+		admin := v1.Group("/admin", authMw, apiLimit, verified, middleware.RequireRole("admin"))
+		{
+			admin.GET("/roles", roleHandler.List)
+			admin.GET("/users", middleware.RequirePermission("user:read"), adminUserHandler.Search)
+			admin.GET("/users/:user_id", middleware.RequirePermission("user:read"), adminUserHandler.Get)
+			admin.PATCH("/users/:user_id", middleware.RequirePermission("user:write"), adminUserHandler.Update)
+			admin.POST("/users/:user_id/activate", middleware.RequirePermission("user:write"), adminUserHandler.Activate)
+			admin.POST("/users/:user_id/deactivate", middleware.RequirePermission("user:write"), adminUserHandler.Deactivate)
+			admin.POST("/users/:user_id/password-reset", middleware.RequirePermission("user:write"), adminUserHandler.ForcePasswordReset)
+			admin.POST("/users/:user_id/restore", middleware.RequirePermission("user:write"), adminUserHandler.Restore)
+			admin.POST("/users/:user_id/roles", middleware.RequirePermission("role:write"), roleHandler.Assign)
+			admin.DELETE("/users/:user_id/roles/:role", middleware.RequirePermission("role:write"), roleHandler.Revoke)
+			admin.GET("/lockouts", middleware.RequirePermission("user:read"), lockoutHandler.List)
+			admin.DELETE("/users/:user_id/lockout", middleware.RequirePermission("user:write"), lockoutHandler.Unlock)
+			admin.GET("/ip-rules", middleware.RequirePermission("ip_rule:read"), ipRuleHandler.List)
+			admin.POST("/ip-rules", middleware.RequirePermission("ip_rule:write"), ipRuleHandler.Add)
+			admin.DELETE("/ip-rules", middleware.RequirePermission("ip_rule:write"), ipRuleHandler.Remove)
+			admin.GET("/audit-events", middleware.RequirePermission("user:read"), auditHandler.List)
+			admin.GET("/oauth-clients", middleware.RequirePermission("oauth_client:read"), oauthClientHandler.List)
+			admin.POST("/oauth-clients", middleware.RequirePermission("oauth_client:write"), oauthClientHandler.Create)
+			admin.DELETE("/oauth-clients/:client_id", middleware.RequirePermission("oauth_client:write"), oauthClientHandler.Delete)
+		}
+
+		oauthHandler := handler.NewOAuthHandler(authService, cfg.OAuthConsentURL)
+		oauth := v1.Group("/oauth")
+		{
+			oauth.GET("/authorize", oauthHandler.Consent)
+			oauth.POST("/authorize", authMw, apiLimit, verified, session, oauthHandler.Authorize)
+			oauth.POST("/token", limiter("oauth:token", cfg.RateLimitAuth, middleware.ByIP), oauthHandler.Token)
+			oauth.POST("/revoke", tokenLimit("oauth:revoke"), oauthHandler.Revoke)
+			oauth.POST("/introspect", tokenLimit("oauth:introspect"), oauthHandler.Introspect)
+		}
+		userinfoMw := middleware.AuthMiddleware(authService, service.ScopeOpenID)
+		v1.GET("/userinfo", userinfoMw, apiLimit, oidcHandler.UserInfo)
+		v1.POST("/userinfo", userinfoMw, apiLimit, oidcHandler.UserInfo)
+	}
+	return router, shutdown
+}
+
+// splitList splits a comma separated config value, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// socialProviders builds a client for every provider configured in cfg.
+// Discovery happens on first use, so a provider that is down does not keep
+// the server from starting.
+func socialProviders(cfg *config.Config) map[string]*oidcclient.Provider {
+	providers := map[string]*oidcclient.Provider{}
+	for _, p := range cfg.SocialProviderList() {
+		providers[p.Name] = oidcclient.New(oidcclient.Config{
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, nil)
 	}
-	return router
+	return providers
 }
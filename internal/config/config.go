@@ -2,6 +2,7 @@ package config
 
 import (
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -10,6 +11,27 @@ import (
 type Config struct {
 	JwtAccessSecret  string `mapstructure:"jwt_access_secret"`
 	JwtRefreshSecret string `mapstructure:"jwt_refresh_secret"`
+	JwtKeyFiles      string `mapstructure:"jwt_key_files"`  // kid=path.pem,... enables asymmetric signing
+	JwtActiveKid     string `mapstructure:"jwt_active_kid"` // kid that signs new tokens
+	PasswordHashAlgo string `mapstructure:"password_hash_algo"` // argon2id | bcrypt
+	EmailVerificationPolicy string `mapstructure:"email_verification_policy"` // block | restrict
+	RateLimitAuth string `mapstructure:"rate_limit_auth"` // per IP on credential endpoints, "N/duration"
+	RateLimitAPI  string `mapstructure:"rate_limit_api"`  // per user on authenticated routes
+	LockoutDelayAfter    int           `mapstructure:"lockout_delay_after"`
+	LockoutMaxAttempts   int           `mapstructure:"lockout_max_attempts"`
+	LockoutIPMaxAttempts int           `mapstructure:"lockout_ip_max_attempts"`
+	LockoutWindow        time.Duration `mapstructure:"lockout_window"`
+	LockoutDuration      time.Duration `mapstructure:"lockout_duration"`
+	LockoutAutoBanIP     bool          `mapstructure:"lockout_auto_ban_ip"`
+	AccountDeletionGraceDays int `mapstructure:"account_deletion_grace_days"` // deleted accounts are purged after this many days
+	OIDCIssuer      string `mapstructure:"oidc_issuer"`       // public URL of this server, iss of ID tokens
+	OAuthConsentURL string `mapstructure:"oauth_consent_url"` // frontend page that asks the user to approve a client
+	SocialProviders string `mapstructure:"social_providers"`  // names of external OpenID Connect providers, see SocialProviderList
+	WebAuthnRPID    string `mapstructure:"webauthn_rp_id"`    // domain passkeys are scoped to
+	WebAuthnRPName  string `mapstructure:"webauthn_rp_name"`  // name shown by authenticators
+	WebAuthnOrigins string `mapstructure:"webauthn_origins"`  // comma separated origins allowed to use passkeys
+	PasswordlessSignup bool `mapstructure:"passwordless_signup"` // email login codes create the account on first use
+	TrustedProxies string `mapstructure:"trusted_proxies"` // comma separated IPs/CIDRs allowed to set X-Forwarded-For
 	RedisHost     string `mapstructure:"redis_host"`
 	RedisPassword string `mapstructure:"redis_password"`
 	RedisPort     int    `mapstructure:"redis_port"`
@@ -23,6 +45,8 @@ type Config struct {
 	ServerPort string `mapstructure:"server_port"`
 	ServerHost string `mapstructure:"server_host"`
 	Environment string `mapstructure:"environment"`
+	NotifySink     string `mapstructure:"notify_sink"`      // log | file
+	NotifyFilePath string `mapstructure:"notify_file_path"` // JSON lines, for the file sink
 }
 
 func LoadConfig(logger *zap.Logger) *Config {
@@ -66,6 +90,43 @@ func setDefaults() {
 	// JWT defaults
 	viper.SetDefault("jwt_access_secret", "your-access-secret-key")
 	viper.SetDefault("jwt_refresh_secret", "your-refresh-secret-key")
+	viper.SetDefault("jwt_key_files", "")
+	viper.SetDefault("jwt_active_kid", "")
+	viper.SetDefault("password_hash_algo", "argon2id")
+	viper.SetDefault("email_verification_policy", "restrict")
+
+	// Rate limits ("N/duration", "0" disables)
+	viper.SetDefault("rate_limit_auth", "10/1m")
+	viper.SetDefault("rate_limit_api", "300/1m")
+
+	// Login lockout
+	viper.SetDefault("lockout_delay_after", 3)
+	viper.SetDefault("lockout_max_attempts", 10)
+	viper.SetDefault("lockout_ip_max_attempts", 50)
+	viper.SetDefault("lockout_window", "15m")
+	viper.SetDefault("lockout_duration", "15m")
+	viper.SetDefault("lockout_auto_ban_ip", true)
+
+	// Account deletion
+	viper.SetDefault("account_deletion_grace_days", 30)
+
+	// OAuth2 / OpenID Connect
+	viper.SetDefault("oidc_issuer", "http://localhost:8080")
+	viper.SetDefault("oauth_consent_url", "http://localhost:3000/oauth/consent")
+
+	// Social login (per provider keys are read in SocialProviderList)
+	viper.SetDefault("social_providers", "")
+
+	// Passkeys
+	viper.SetDefault("webauthn_rp_id", "localhost")
+	viper.SetDefault("webauthn_rp_name", "Seno BlackDragon")
+	viper.SetDefault("webauthn_origins", "http://localhost:3000")
+
+	// Email login codes
+	viper.SetDefault("passwordless_signup", true)
+
+	// Proxies (nginx on the docker network)
+	viper.SetDefault("trusted_proxies", "127.0.0.1,::1,172.16.0.0/12")
 
 	// Redis defaults
 	viper.SetDefault("redis_host", "localhost")
@@ -87,6 +148,10 @@ func setDefaults() {
 
 	// Environment
 	viper.SetDefault("environment", "development")
+
+	// Notifications
+	viper.SetDefault("notify_sink", "log")
+	viper.SetDefault("notify_file_path", "./logs/notifications.log")
 }
 
 // GetString returns a string value from config
@@ -94,6 +159,52 @@ func (c *Config) GetString(key string) string {
 	return viper.GetString(key)
 }
 
+// SocialProvider is an external OpenID Connect provider users can log in
+// with.
+type SocialProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string
+}
+
+// SocialProviderList reads the providers named in SocialProviders from
+// social_<name>_issuer, _client_id, _client_secret, _scopes (space
+// separated, default "openid email profile") and _redirect_url (default
+// OIDCIssuer + /api/v1/auth/social/<name>/callback). Providers without an
+// issuer or client id are skipped.
+func (c *Config) SocialProviderList() []SocialProvider {
+	var providers []SocialProvider
+	for _, name := range strings.Split(c.SocialProviders, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		key := "social_" + name + "_"
+		p := SocialProvider{
+			Name:         name,
+			Issuer:       viper.GetString(key + "issuer"),
+			ClientID:     viper.GetString(key + "client_id"),
+			ClientSecret: viper.GetString(key + "client_secret"),
+			Scopes:       strings.Fields(viper.GetString(key + "scopes")),
+			RedirectURL:  viper.GetString(key + "redirect_url"),
+		}
+		if p.Issuer == "" || p.ClientID == "" {
+			continue
+		}
+		if len(p.Scopes) == 0 {
+			p.Scopes = []string{"openid", "email", "profile"}
+		}
+		if p.RedirectURL == "" {
+			p.RedirectURL = strings.TrimSuffix(c.OIDCIssuer, "/") + "/api/v1/auth/social/" + name + "/callback"
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
+
 // GetInt returns an int value from config
 func (c *Config) GetInt(key string) int {
 	return viper.GetInt(key)
@@ -217,3 +217,37 @@ func TestEnvironmentVariablePrecedence(t *testing.T) {
 		t.Error("Expected RedisHost to be set")
 	}
 }
+
+func TestSocialProviderList(t *testing.T) {
+	os.Setenv("SOCIAL_PROVIDERS", "Google, acme,broken")
+	os.Setenv("SOCIAL_GOOGLE_ISSUER", "https://accounts.google.com")
+	os.Setenv("SOCIAL_GOOGLE_CLIENT_ID", "google-client")
+	os.Setenv("SOCIAL_GOOGLE_CLIENT_SECRET", "google-secret")
+	os.Setenv("SOCIAL_ACME_ISSUER", "https://id.acme.test")
+	os.Setenv("SOCIAL_ACME_CLIENT_ID", "acme-client")
+	os.Setenv("SOCIAL_ACME_SCOPES", "openid email")
+	os.Setenv("SOCIAL_ACME_REDIRECT_URL", "https://app.test/cb")
+	os.Setenv("SOCIAL_BROKEN_ISSUER", "https://broken.test")
+	defer func() {
+		for _, k := range []string{"SOCIAL_PROVIDERS", "SOCIAL_GOOGLE_ISSUER", "SOCIAL_GOOGLE_CLIENT_ID", "SOCIAL_GOOGLE_CLIENT_SECRET",
+			"SOCIAL_ACME_ISSUER", "SOCIAL_ACME_CLIENT_ID", "SOCIAL_ACME_SCOPES", "SOCIAL_ACME_REDIRECT_URL", "SOCIAL_BROKEN_ISSUER"} {
+			os.Unsetenv(k)
+		}
+	}()
+
+	cfg := LoadConfig(zap.NewNop())
+	providers := cfg.SocialProviderList()
+	if len(providers) != 2 {
+		t.Fatalf("Expected 2 providers (broken has no client id), got %d", len(providers))
+	}
+	g, a := providers[0], providers[1]
+	if g.Name != "google" || g.ClientSecret != "google-secret" || len(g.Scopes) != 3 {
+		t.Errorf("Unexpected google provider %+v", g)
+	}
+	if g.RedirectURL != "http://localhost:8080/api/v1/auth/social/google/callback" {
+		t.Errorf("Expected default redirect url, got '%s'", g.RedirectURL)
+	}
+	if a.Name != "acme" || a.RedirectURL != "https://app.test/cb" || len(a.Scopes) != 2 {
+		t.Errorf("Unexpected acme provider %+v", a)
+	}
+}
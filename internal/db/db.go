@@ -4,24 +4,27 @@ import (
 	"context"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
-func ConnectDatabase(logger *zap.Logger, dsn string, dbName string) *pgx.Conn {
-	cfg, err := pgx.ParseConfig(dsn)
+// ConnectDatabase opens a connection pool. Request handlers, the audit
+// writer and the purge job all query at the same time, which a single
+// pgx.Conn does not allow.
+func ConnectDatabase(logger *zap.Logger, dsn string, dbName string) *pgxpool.Pool {
+	cfg, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
-		logger.Error("Unable to parse DB config", zap.Error(err))
+		logger.Fatal("Unable to parse DB config", zap.Error(err))
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	dbConn, err := pgx.ConnectConfig(ctx, cfg)
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
-		logger.Error("Unable to connect to database", zap.Error(err))
+		logger.Fatal("Unable to create database pool", zap.Error(err))
 	}
-	if err := dbConn.Ping(ctx); err != nil {
+	if err := pool.Ping(ctx); err != nil {
 		logger.Error("Database ping failed", zap.Error(err))
 	}
 	logger.Info("Connected to PostgreSQL database", zap.String("db", dbName))
-	return dbConn
+	return pool
 }
@@ -0,0 +1,179 @@
+// Package dbtest is an in-memory stand-in for the sqlc DBTX interface, so
+// that services can be tested without PostgreSQL. Queries are told apart by
+// the "-- name: X" comment sqlc puts in front of every statement.
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Call is one statement the code under test ran.
+type Call struct {
+	Name string
+	Args []any
+}
+
+// DB answers queries with canned rows and records every call. A query
+// without rows returns pgx.ErrNoRows (QueryRow) or an empty result (Query);
+// Exec reports one affected row unless told otherwise.
+type DB struct {
+	mu       sync.Mutex
+	rows     map[string][][]any
+	affected map[string]int64
+	calls    []Call
+}
+
+func New() *DB {
+	return &DB{rows: map[string][][]any{}, affected: map[string]int64{}}
+}
+
+// On sets the rows returned for the named query. Each row lists the column
+// values in scan order; Row builds one from a sqlc model.
+func (db *DB) On(name string, rows ...[]any) *DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.rows[name] = rows
+	return db
+}
+
+// Affected sets the row count Exec reports for the named statement.
+func (db *DB) Affected(name string, n int64) *DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.affected[name] = n
+	return db
+}
+
+// Calls returns the recorded calls of the named statement.
+func (db *DB) Calls(name string) []Call {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var out []Call
+	for _, c := range db.calls {
+		if c.Name == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Row flattens a sqlc model into its column values, in field order, which
+// is the order sqlc scans them in.
+func Row(model any) []any {
+	v := reflect.ValueOf(model)
+	out := make([]any, v.NumField())
+	for i := range out {
+		out[i] = v.Field(i).Interface()
+	}
+	return out
+}
+
+// Value is a single-column row, for queries that return one value.
+func Value(v any) []any { return []any{v} }
+
+func queryName(sql string) string {
+	const marker = "-- name: "
+	i := strings.Index(sql, marker)
+	if i < 0 {
+		return ""
+	}
+	rest := sql[i+len(marker):]
+	if j := strings.IndexAny(rest, " \n"); j >= 0 {
+		rest = rest[:j]
+	}
+	return rest
+}
+
+func (db *DB) record(sql string, args []any) ([][]any, string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	name := queryName(sql)
+	db.calls = append(db.calls, Call{Name: name, Args: args})
+	return db.rows[name], name
+}
+
+func (db *DB) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	_, name := db.record(sql, args)
+	db.mu.Lock()
+	n, ok := db.affected[name]
+	db.mu.Unlock()
+	if !ok {
+		n = 1
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", n)), nil
+}
+
+func (db *DB) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	rows, _ := db.record(sql, args)
+	if len(rows) == 0 {
+		return row{err: pgx.ErrNoRows}
+	}
+	return row{values: rows[0]}
+}
+
+func (db *DB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rows, _ := db.record(sql, args)
+	return &resultRows{rows: rows, i: -1}, nil
+}
+
+type row struct {
+	values []any
+	err    error
+}
+
+func (r row) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	return scan(r.values, dest)
+}
+
+func scan(values, dest []any) error {
+	if len(values) != len(dest) {
+		return fmt.Errorf("dbtest: row has %d columns, scan wants %d", len(values), len(dest))
+	}
+	for i, d := range dest {
+		if values[i] == nil {
+			continue
+		}
+		dv := reflect.ValueOf(d).Elem()
+		sv := reflect.ValueOf(values[i])
+		if !sv.Type().AssignableTo(dv.Type()) {
+			if !sv.Type().ConvertibleTo(dv.Type()) {
+				return fmt.Errorf("dbtest: column %d: cannot scan %T into %s", i, values[i], dv.Type())
+			}
+			sv = sv.Convert(dv.Type())
+		}
+		dv.Set(sv)
+	}
+	return nil
+}
+
+// resultRows implements the parts of pgx.Rows sqlc uses.
+type resultRows struct {
+	rows [][]any
+	i    int
+}
+
+func (r *resultRows) Close()                                       {}
+func (r *resultRows) Err() error                                   { return nil }
+func (r *resultRows) CommandTag() pgconn.CommandTag                { return pgconn.NewCommandTag("SELECT") }
+func (r *resultRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *resultRows) RawValues() [][]byte                          { return nil }
+func (r *resultRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *resultRows) Next() bool {
+	r.i++
+	return r.i < len(r.rows)
+}
+
+func (r *resultRows) Scan(dest ...any) error { return scan(r.rows[r.i], dest) }
+
+func (r *resultRows) Values() ([]any, error) { return r.rows[r.i], nil }
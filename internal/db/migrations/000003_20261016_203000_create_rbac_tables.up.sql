@@ -0,0 +1,28 @@
+CREATE TABLE role (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  name TEXT NOT NULL UNIQUE,
+  description TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE permission (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  name TEXT NOT NULL UNIQUE,
+  description TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE role_permission (
+  role_id UUID NOT NULL REFERENCES role(id) ON DELETE CASCADE,
+  permission_id UUID NOT NULL REFERENCES permission(id) ON DELETE CASCADE,
+  PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE user_role (
+  user_id UUID NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+  role_id UUID NOT NULL REFERENCES role(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX user_role_role_id_idx ON user_role (role_id);
@@ -0,0 +1,3 @@
+DELETE FROM role_permission;
+DELETE FROM permission WHERE name IN ('user:read', 'user:write', 'role:write', 'booking:read', 'booking:write');
+DELETE FROM role WHERE name IN ('admin', 'user');
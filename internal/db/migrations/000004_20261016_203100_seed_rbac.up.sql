@@ -0,0 +1,22 @@
+INSERT INTO role (name, description) VALUES
+  ('admin', 'Full administrative access'),
+  ('user', 'Default role for registered users')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permission (name, description) VALUES
+  ('user:read', 'Read user accounts'),
+  ('user:write', 'Manage user accounts'),
+  ('role:write', 'Assign and revoke roles'),
+  ('booking:read', 'Read bookings'),
+  ('booking:write', 'Create and modify bookings')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permission (role_id, permission_id)
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permission (role_id, permission_id)
+SELECT r.id, p.id FROM role r JOIN permission p ON p.name IN ('booking:read', 'booking:write')
+WHERE r.name = 'user'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+ALTER TABLE "user" ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- accounts created before verification existed are trusted as-is
+UPDATE "user" SET email_verified_at = created_at;
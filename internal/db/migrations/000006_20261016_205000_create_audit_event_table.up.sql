@@ -0,0 +1,18 @@
+-- audit_event is append-only. subject_id has no foreign key so the trail
+-- outlives the account it describes.
+CREATE TABLE audit_event (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  event_type TEXT NOT NULL,
+  actor_id UUID,
+  subject_id UUID,
+  ip TEXT,
+  user_agent TEXT,
+  device_id TEXT,
+  session_id TEXT,
+  metadata JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_event_subject_created_idx ON audit_event (subject_id, created_at DESC);
+CREATE INDEX audit_event_type_created_idx ON audit_event (event_type, created_at DESC);
+CREATE INDEX audit_event_created_idx ON audit_event (created_at DESC);
@@ -0,0 +1 @@
+ALTER TABLE "user" ADD COLUMN avatar_url TEXT;
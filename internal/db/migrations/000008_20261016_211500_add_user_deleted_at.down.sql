@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS user_deleted_at_idx;
+DROP INDEX IF EXISTS user_email_live_key;
+ALTER TABLE "user" DROP COLUMN IF EXISTS purged_at;
+ALTER TABLE "user" DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,7 @@
+-- Soft deletion: deleted_at starts the grace period, purged_at records when
+-- the personal data was wiped. Deleted rows no longer hold on to their email.
+ALTER TABLE "user" ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE "user" ADD COLUMN purged_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX user_email_live_key ON "user" (email) WHERE deleted_at IS NULL;
+CREATE INDEX user_deleted_at_idx ON "user" (deleted_at) WHERE deleted_at IS NOT NULL AND purged_at IS NULL;
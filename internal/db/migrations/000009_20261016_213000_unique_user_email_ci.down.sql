@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS user_email_lower_key;
+CREATE UNIQUE INDEX user_email_live_key ON "user" (email) WHERE deleted_at IS NULL;
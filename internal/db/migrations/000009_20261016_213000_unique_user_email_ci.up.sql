@@ -0,0 +1,7 @@
+-- Emails are stored trimmed and lower-cased; the index also catches writers
+-- that skip the normalisation. Purged accounts have a NULL email and never
+-- conflict. Fails if the table already holds case-insensitive duplicates.
+UPDATE "user" SET email = lower(btrim(email)) WHERE email IS NOT NULL AND email <> lower(btrim(email));
+
+DROP INDEX IF EXISTS user_email_live_key;
+CREATE UNIQUE INDEX user_email_lower_key ON "user" (lower(email)) WHERE deleted_at IS NULL;
@@ -0,0 +1,14 @@
+-- Applications that obtain tokens through /oauth/token. Public clients (mobile,
+-- SPA) have no secret and must use PKCE; first-party clients skip the scope
+-- restrictions that apply to tokens issued to partners.
+CREATE TABLE oauth_client (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  secret_hash TEXT,
+  redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+  grant_types TEXT[] NOT NULL DEFAULT '{}',
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  first_party BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,13 @@
+-- Accounts at external OpenID Connect providers (social login). A user links
+-- at most one account per provider; subject is the provider's "sub" claim.
+CREATE TABLE user_identity (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  email TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_login_at TIMESTAMPTZ,
+  UNIQUE (provider, subject),
+  UNIQUE (user_id, provider)
+);
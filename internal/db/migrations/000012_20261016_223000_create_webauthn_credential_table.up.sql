@@ -0,0 +1,17 @@
+-- Passkeys (WebAuthn public key credentials). credential_id is the id the
+-- authenticator chose; public_key is the COSE key from the registration.
+-- sign_count is 0 for authenticators without a counter (synced passkeys).
+CREATE TABLE webauthn_credential (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+  credential_id BYTEA NOT NULL UNIQUE,
+  public_key BYTEA NOT NULL,
+  sign_count BIGINT NOT NULL DEFAULT 0,
+  transports TEXT[] NOT NULL DEFAULT '{}',
+  aaguid BYTEA,
+  backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used_at TIMESTAMPTZ
+);
+CREATE INDEX webauthn_credential_user_id_idx ON webauthn_credential (user_id);
@@ -0,0 +1,16 @@
+-- Personal access tokens and service API keys. Only a SHA-256 hash of the
+-- token is kept; prefix is the start of the token, shown to the owner and
+-- used to find the row. Scopes are permission names the token may use.
+CREATE TABLE api_token (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL CHECK (kind IN ('personal', 'service')),
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL UNIQUE,
+  token_hash TEXT NOT NULL,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  expires_at TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX api_token_user_id_idx ON api_token (user_id);
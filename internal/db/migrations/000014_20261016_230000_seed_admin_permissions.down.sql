@@ -0,0 +1 @@
+DELETE FROM permission WHERE name IN ('ip_rule:read', 'ip_rule:write', 'oauth_client:read', 'oauth_client:write');
@@ -0,0 +1,12 @@
+INSERT INTO permission (name, description) VALUES
+  ('ip_rule:read', 'Read the IP allow and deny lists'),
+  ('ip_rule:write', 'Manage the IP allow and deny lists'),
+  ('oauth_client:read', 'Read registered OAuth clients'),
+  ('oauth_client:write', 'Register and delete OAuth clients')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permission (role_id, permission_id)
+SELECT r.id, p.id FROM role r JOIN permission p
+  ON p.name IN ('ip_rule:read', 'ip_rule:write', 'oauth_client:read', 'oauth_client:write')
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
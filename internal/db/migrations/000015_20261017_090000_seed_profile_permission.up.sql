@@ -0,0 +1,8 @@
+INSERT INTO permission (name, description) VALUES
+  ('profile:read', 'Read the own profile with an API token')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permission (role_id, permission_id)
+SELECT r.id, p.id FROM role r JOIN permission p ON p.name = 'profile:read'
+WHERE r.name IN ('admin', 'user')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,48 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_token (
+  user_id,
+  kind,
+  name,
+  prefix,
+  token_hash,
+  scopes,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetAPIToken :one
+SELECT * FROM api_token
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetAPITokenByPrefix :one
+SELECT * FROM api_token
+WHERE prefix = $1;
+
+-- name: ListAPITokens :many
+SELECT * FROM api_token
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: RenameAPIToken :execrows
+UPDATE api_token
+SET name = $3
+WHERE id = $1 AND user_id = $2;
+
+-- name: TouchAPIToken :exec
+UPDATE api_token
+SET last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_token
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteAPITokens :exec
+DELETE FROM api_token
+WHERE user_id = $1;
+
+-- name: DeleteAPITokensByKind :execrows
+DELETE FROM api_token
+WHERE user_id = $1 AND kind = $2;
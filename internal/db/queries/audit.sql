@@ -0,0 +1,31 @@
+-- name: InsertAuditEvent :exec
+INSERT INTO audit_event (event_type, actor_id, subject_id, ip, user_agent, device_id, session_id, metadata)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_event
+WHERE (sqlc.narg(subject_id)::uuid IS NULL OR subject_id = sqlc.narg(subject_id))
+  AND (sqlc.narg(actor_id)::uuid IS NULL OR actor_id = sqlc.narg(actor_id))
+  AND (sqlc.narg(event_type)::text IS NULL OR event_type = sqlc.narg(event_type))
+  AND (sqlc.narg(ip)::text IS NULL OR ip = sqlc.narg(ip))
+  AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until))
+ORDER BY created_at DESC
+LIMIT sqlc.arg(lim) OFFSET sqlc.arg(off);
+
+-- name: CountAuditEvents :one
+SELECT COUNT(*) FROM audit_event
+WHERE (sqlc.narg(subject_id)::uuid IS NULL OR subject_id = sqlc.narg(subject_id))
+  AND (sqlc.narg(actor_id)::uuid IS NULL OR actor_id = sqlc.narg(actor_id))
+  AND (sqlc.narg(event_type)::text IS NULL OR event_type = sqlc.narg(event_type))
+  AND (sqlc.narg(ip)::text IS NULL OR ip = sqlc.narg(ip))
+  AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until));
+
+-- name: ScrubAuditEvents :exec
+-- drops the personal data of a purged account; the events themselves stay
+UPDATE audit_event
+SET ip = NULL,
+    user_agent = NULL,
+    metadata = metadata - 'email' - 'old_email'
+WHERE subject_id = $1;
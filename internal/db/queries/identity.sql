@@ -0,0 +1,32 @@
+-- name: CreateUserIdentity :one
+INSERT INTO user_identity (
+  user_id,
+  provider,
+  subject,
+  email
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identity
+WHERE provider = $1 AND subject = $2;
+
+-- name: ListUserIdentities :many
+SELECT * FROM user_identity
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: TouchUserIdentity :exec
+UPDATE user_identity
+SET last_login_at = NOW(), email = $2
+WHERE id = $1;
+
+-- name: DeleteUserIdentity :execrows
+DELETE FROM user_identity
+WHERE user_id = $1 AND provider = $2;
+
+-- name: DeleteUserIdentities :exec
+DELETE FROM user_identity
+WHERE user_id = $1;
@@ -0,0 +1,48 @@
+-- name: GetUserMFA :one
+SELECT * FROM user_mfa
+WHERE user_id = $1;
+
+-- name: UpsertTOTPSecret :exec
+INSERT INTO user_mfa (
+  user_id,
+  totp_secret,
+  enabled_at,
+  created_at,
+  updated_at
+) VALUES (
+  $1, $2, NULL, NOW(), NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET totp_secret = EXCLUDED.totp_secret,
+    enabled_at = NULL,
+    updated_at = NOW();
+
+-- name: EnableTOTP :exec
+UPDATE user_mfa
+SET enabled_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1;
+
+-- name: ReplaceRecoveryCodes :exec
+WITH deleted AS (
+  DELETE FROM mfa_recovery_code
+  WHERE user_id = sqlc.arg(user_id)
+)
+INSERT INTO mfa_recovery_code (user_id, code_hash)
+SELECT sqlc.arg(user_id), unnest(sqlc.arg(code_hashes)::text[]);
+
+-- name: UseRecoveryCode :one
+UPDATE mfa_recovery_code
+SET used_at = NOW()
+WHERE user_id = $1
+  AND code_hash = $2
+  AND used_at IS NULL
+RETURNING id;
+
+-- name: DeleteUserMFA :exec
+WITH codes AS (
+  DELETE FROM mfa_recovery_code
+  WHERE user_id = $1
+)
+DELETE FROM user_mfa
+WHERE user_id = $1;
@@ -0,0 +1,25 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_client (
+  id,
+  name,
+  secret_hash,
+  redirect_uris,
+  grant_types,
+  scopes,
+  first_party
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_client
+WHERE id = $1;
+
+-- name: ListOAuthClients :many
+SELECT * FROM oauth_client
+ORDER BY created_at;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM oauth_client
+WHERE id = $1;
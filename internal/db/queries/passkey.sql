@@ -0,0 +1,41 @@
+-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credential (
+  user_id,
+  credential_id,
+  public_key,
+  sign_count,
+  transports,
+  aaguid,
+  backup_eligible,
+  name
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: GetWebAuthnCredential :one
+SELECT * FROM webauthn_credential
+WHERE credential_id = $1;
+
+-- name: ListWebAuthnCredentials :many
+SELECT * FROM webauthn_credential
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: UpdateWebAuthnCredentialUsage :exec
+UPDATE webauthn_credential
+SET sign_count = $2, last_used_at = NOW()
+WHERE id = $1;
+
+-- name: RenameWebAuthnCredential :execrows
+UPDATE webauthn_credential
+SET name = $3
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteWebAuthnCredential :execrows
+DELETE FROM webauthn_credential
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteWebAuthnCredentials :exec
+DELETE FROM webauthn_credential
+WHERE user_id = $1;
@@ -0,0 +1,31 @@
+-- name: ListRoles :many
+SELECT * FROM role
+ORDER BY name;
+
+-- name: GetRoleByName :one
+SELECT * FROM role
+WHERE name = $1;
+
+-- name: ListUserRoles :many
+SELECT r.name FROM role r
+JOIN user_role ur ON ur.role_id = r.id
+WHERE ur.user_id = $1
+ORDER BY r.name;
+
+-- name: ListPermissionsByRoles :many
+SELECT DISTINCT p.name FROM permission p
+JOIN role_permission rp ON rp.permission_id = p.id
+JOIN role r ON r.id = rp.role_id
+WHERE r.name = ANY(sqlc.arg(role_names)::text[])
+ORDER BY p.name;
+
+-- name: AssignUserRole :exec
+INSERT INTO user_role (user_id, role_id)
+SELECT sqlc.arg(user_id)::uuid, r.id FROM role r
+WHERE r.name = sqlc.arg(role_name)
+ON CONFLICT DO NOTHING;
+
+-- name: RevokeUserRole :execrows
+DELETE FROM user_role
+WHERE user_id = sqlc.arg(user_id)
+  AND role_id = (SELECT id FROM role WHERE name = sqlc.arg(role_name));
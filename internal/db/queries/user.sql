@@ -1,10 +1,14 @@
--- name: UpdateUser :exec
+-- name: UpdateUser :one
+-- optimistic concurrency: no row comes back when updated_at moved on
 UPDATE "user"
 SET full_name = $2,
     bio = $3,
     email = $4,
+    avatar_url = $5,
+    email_verified_at = $6,
     updated_at = NOW()
-WHERE id = $1;
+WHERE id = $1 AND updated_at = $7
+RETURNING *;
 
 -- name: UpdateUserPassword :exec
 UPDATE "user"
@@ -14,7 +18,7 @@ WHERE id = $1;
 
 -- name: GetUserByEmail :one
 SELECT * FROM "user"
-WHERE email = $1;
+WHERE lower(email) = lower(sqlc.arg(email)::text) AND deleted_at IS NULL;
 
 -- name: DeactivateUser :exec
 UPDATE "user"
@@ -30,8 +34,20 @@ WHERE id = $1;
 
 -- name: SearchUsersByName :many
 SELECT * FROM "user"
-WHERE full_name ILIKE '%' || $1 || '%'
-ORDER BY full_name;
+WHERE full_name ILIKE '%' || sqlc.arg(query)::text || '%' ESCAPE '\'
+ORDER BY
+  CASE WHEN sqlc.arg(sort_by)::text = 'full_name' AND NOT sqlc.arg(sort_desc)::bool THEN full_name END ASC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'full_name' AND sqlc.arg(sort_desc)::bool THEN full_name END DESC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'email' AND NOT sqlc.arg(sort_desc)::bool THEN email END ASC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'email' AND sqlc.arg(sort_desc)::bool THEN email END DESC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND NOT sqlc.arg(sort_desc)::bool THEN created_at END ASC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND sqlc.arg(sort_desc)::bool THEN created_at END DESC,
+  id
+LIMIT sqlc.arg(lim) OFFSET sqlc.arg(off);
+
+-- name: CountUsersByName :one
+SELECT COUNT(*) FROM "user"
+WHERE full_name ILIKE '%' || sqlc.arg(query)::text || '%' ESCAPE '\';
 
 -- name: AddUser :one
 INSERT INTO "user" (
@@ -48,4 +64,41 @@ RETURNING id;
 
 -- name: GetUserByID :one
 SELECT * FROM "user"
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+-- name: MarkEmailVerified :execrows
+UPDATE "user"
+SET email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND email_verified_at IS NULL;
+
+-- name: SoftDeleteUser :execrows
+UPDATE "user"
+SET deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreUser :execrows
+UPDATE "user"
+SET deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NOT NULL AND purged_at IS NULL;
+
+-- name: ListUsersToPurge :many
+SELECT id FROM "user"
+WHERE deleted_at < sqlc.arg(cutoff) AND purged_at IS NULL
+ORDER BY deleted_at
+LIMIT sqlc.arg(lim);
+
+-- name: PurgeUser :exec
+-- the row stays so ids in the audit trail and role tables still resolve
+UPDATE "user"
+SET full_name = '',
+    bio = NULL,
+    email = NULL,
+    avatar_url = NULL,
+    password_hash = NULL,
+    email_verified_at = NULL,
+    is_active = FALSE,
+    purged_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NOT NULL;
@@ -9,4 +9,164 @@ CREATE TABLE "user" (
   is_active BOOLEAN NOT NULL DEFAULT TRUE,
   created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
   updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-);
\ No newline at end of file
+);
+
+CREATE TABLE user_mfa (
+  user_id UUID PRIMARY KEY REFERENCES "user"(id) ON DELETE CASCADE,
+  totp_secret TEXT NOT NULL,
+  enabled_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE mfa_recovery_code (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+  code_hash TEXT NOT NULL,
+  used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX mfa_recovery_code_user_id_idx ON mfa_recovery_code (user_id);
+
+
+CREATE TABLE role (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  name TEXT NOT NULL UNIQUE,
+  description TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE permission (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  name TEXT NOT NULL UNIQUE,
+  description TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE role_permission (
+  role_id UUID NOT NULL REFERENCES role(id) ON DELETE CASCADE,
+  permission_id UUID NOT NULL REFERENCES permission(id) ON DELETE CASCADE,
+  PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE user_role (
+  user_id UUID NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+  role_id UUID NOT NULL REFERENCES role(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX user_role_role_id_idx ON user_role (role_id);
+
+
+ALTER TABLE "user" ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+
+-- audit_event is append-only. subject_id has no foreign key so the trail
+-- outlives the account it describes.
+CREATE TABLE audit_event (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  event_type TEXT NOT NULL,
+  actor_id UUID,
+  subject_id UUID,
+  ip TEXT,
+  user_agent TEXT,
+  device_id TEXT,
+  session_id TEXT,
+  metadata JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_event_subject_created_idx ON audit_event (subject_id, created_at DESC);
+CREATE INDEX audit_event_type_created_idx ON audit_event (event_type, created_at DESC);
+CREATE INDEX audit_event_created_idx ON audit_event (created_at DESC);
+
+
+ALTER TABLE "user" ADD COLUMN avatar_url TEXT;
+
+
+-- Soft deletion: deleted_at starts the grace period, purged_at records when
+-- the personal data was wiped. Deleted rows no longer hold on to their email.
+ALTER TABLE "user" ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE "user" ADD COLUMN purged_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX user_email_live_key ON "user" (email) WHERE deleted_at IS NULL;
+CREATE INDEX user_deleted_at_idx ON "user" (deleted_at) WHERE deleted_at IS NOT NULL AND purged_at IS NULL;
+
+
+-- Emails are stored trimmed and lower-cased; the index also catches writers
+-- that skip the normalisation. Purged accounts have a NULL email and never
+-- conflict. Fails if the table already holds case-insensitive duplicates.
+UPDATE "user" SET email = lower(btrim(email)) WHERE email IS NOT NULL AND email <> lower(btrim(email));
+
+DROP INDEX IF EXISTS user_email_live_key;
+CREATE UNIQUE INDEX user_email_lower_key ON "user" (lower(email)) WHERE deleted_at IS NULL;
+
+
+-- Applications that obtain tokens through /oauth/token. Public clients (mobile,
+-- SPA) have no secret and must use PKCE; first-party clients skip the scope
+-- restrictions that apply to tokens issued to partners.
+CREATE TABLE oauth_client (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  secret_hash TEXT,
+  redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+  grant_types TEXT[] NOT NULL DEFAULT '{}',
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  first_party BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+
+-- Accounts at external OpenID Connect providers (social login). A user links
+-- at most one account per provider; subject is the provider's "sub" claim.
+CREATE TABLE user_identity (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  email TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_login_at TIMESTAMPTZ,
+  UNIQUE (provider, subject),
+  UNIQUE (user_id, provider)
+);
+
+
+-- Passkeys (WebAuthn public key credentials). credential_id is the id the
+-- authenticator chose; public_key is the COSE key from the registration.
+-- sign_count is 0 for authenticators without a counter (synced passkeys).
+CREATE TABLE webauthn_credential (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+  credential_id BYTEA NOT NULL UNIQUE,
+  public_key BYTEA NOT NULL,
+  sign_count BIGINT NOT NULL DEFAULT 0,
+  transports TEXT[] NOT NULL DEFAULT '{}',
+  aaguid BYTEA,
+  backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used_at TIMESTAMPTZ
+);
+CREATE INDEX webauthn_credential_user_id_idx ON webauthn_credential (user_id);
+
+
+-- Personal access tokens and service API keys. Only a SHA-256 hash of the
+-- token is kept; prefix is the start of the token, shown to the owner and
+-- used to find the row. Scopes are permission names the token may use.
+CREATE TABLE api_token (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL CHECK (kind IN ('personal', 'service')),
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL UNIQUE,
+  token_hash TEXT NOT NULL,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  expires_at TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX api_token_user_id_idx ON api_token (user_id);
@@ -9,12 +9,16 @@ import (
 )
 
 type User struct {
-	ID           pgtype.UUID
-	FullName     string
-	Bio          pgtype.Text
-	Email        pgtype.Text
-	PasswordHash pgtype.Text
-	IsActive     bool
-	CreatedAt    pgtype.Timestamptz
-	UpdatedAt    pgtype.Timestamptz
+	ID              pgtype.UUID
+	FullName        string
+	Bio             pgtype.Text
+	Email           pgtype.Text
+	PasswordHash    pgtype.Text
+	IsActive        bool
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+	EmailVerifiedAt pgtype.Timestamptz
+	AvatarUrl       pgtype.Text
+	DeletedAt       pgtype.Timestamptz
+	PurgedAt        pgtype.Timestamptz
 }
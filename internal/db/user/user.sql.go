@@ -56,6 +56,18 @@ func (q *Queries) AddUser(ctx context.Context, arg AddUserParams) (pgtype.UUID,
 	return id, err
 }
 
+const countUsersByName = `-- name: CountUsersByName :one
+SELECT COUNT(*) FROM "user"
+WHERE full_name ILIKE '%' || $1::text || '%' ESCAPE '\'
+`
+
+func (q *Queries) CountUsersByName(ctx context.Context, query string) (int64, error) {
+	row := q.db.QueryRow(ctx, countUsersByName, query)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const deactivateUser = `-- name: DeactivateUser :exec
 UPDATE "user"
 SET is_active = FALSE,
@@ -69,11 +81,11 @@ func (q *Queries) DeactivateUser(ctx context.Context, id pgtype.UUID) error {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, full_name, bio, email, password_hash, is_active, created_at, updated_at FROM "user"
-WHERE email = $1
+SELECT id, full_name, bio, email, password_hash, is_active, created_at, updated_at, email_verified_at, avatar_url, deleted_at, purged_at FROM "user"
+WHERE lower(email) = lower($1::text) AND deleted_at IS NULL
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email pgtype.Text) (User, error) {
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
 	row := q.db.QueryRow(ctx, getUserByEmail, email)
 	var i User
 	err := row.Scan(
@@ -85,12 +97,16 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email pgtype.Text) (User,
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.PurgedAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, full_name, bio, email, password_hash, is_active, created_at, updated_at FROM "user"
+SELECT id, full_name, bio, email, password_hash, is_active, created_at, updated_at, email_verified_at, avatar_url, deleted_at, purged_at FROM "user"
 WHERE id = $1
 `
 
@@ -106,18 +122,126 @@ func (q *Queries) GetUserByID(ctx context.Context, id pgtype.UUID) (User, error)
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.PurgedAt,
 	)
 	return i, err
 }
 
+const listUsersToPurge = `-- name: ListUsersToPurge :many
+SELECT id FROM "user"
+WHERE deleted_at < $1 AND purged_at IS NULL
+ORDER BY deleted_at
+LIMIT $2
+`
+
+type ListUsersToPurgeParams struct {
+	Cutoff pgtype.Timestamptz
+	Lim    int32
+}
+
+func (q *Queries) ListUsersToPurge(ctx context.Context, arg ListUsersToPurgeParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listUsersToPurge, arg.Cutoff, arg.Lim)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :execrows
+UPDATE "user"
+SET email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND email_verified_at IS NULL
+`
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, markEmailVerified, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeUser = `-- name: PurgeUser :exec
+UPDATE "user"
+SET full_name = '',
+    bio = NULL,
+    email = NULL,
+    avatar_url = NULL,
+    password_hash = NULL,
+    email_verified_at = NULL,
+    is_active = FALSE,
+    purged_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+// the row stays so ids in the audit trail and role tables still resolve
+func (q *Queries) PurgeUser(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, purgeUser, id)
+	return err
+}
+
+const restoreUser = `-- name: RestoreUser :execrows
+UPDATE "user"
+SET deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NOT NULL AND purged_at IS NULL
+`
+
+func (q *Queries) RestoreUser(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, restoreUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const searchUsersByName = `-- name: SearchUsersByName :many
-SELECT id, full_name, bio, email, password_hash, is_active, created_at, updated_at FROM "user"
-WHERE full_name ILIKE '%' || $1 || '%'
-ORDER BY full_name
+SELECT id, full_name, bio, email, password_hash, is_active, created_at, updated_at, email_verified_at, avatar_url, deleted_at, purged_at FROM "user"
+WHERE full_name ILIKE '%' || $1::text || '%' ESCAPE '\'
+ORDER BY
+  CASE WHEN $2::text = 'full_name' AND NOT $3::bool THEN full_name END ASC,
+  CASE WHEN $2::text = 'full_name' AND $3::bool THEN full_name END DESC,
+  CASE WHEN $2::text = 'email' AND NOT $3::bool THEN email END ASC,
+  CASE WHEN $2::text = 'email' AND $3::bool THEN email END DESC,
+  CASE WHEN $2::text = 'created_at' AND NOT $3::bool THEN created_at END ASC,
+  CASE WHEN $2::text = 'created_at' AND $3::bool THEN created_at END DESC,
+  id
+LIMIT $4 OFFSET $5
 `
 
-func (q *Queries) SearchUsersByName(ctx context.Context, dollar_1 pgtype.Text) ([]User, error) {
-	rows, err := q.db.Query(ctx, searchUsersByName, dollar_1)
+type SearchUsersByNameParams struct {
+	Query    string
+	SortBy   string
+	SortDesc bool
+	Lim      int32
+	Off      int32
+}
+
+func (q *Queries) SearchUsersByName(ctx context.Context, arg SearchUsersByNameParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, searchUsersByName,
+		arg.Query,
+		arg.SortBy,
+		arg.SortDesc,
+		arg.Lim,
+		arg.Off,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -134,6 +258,10 @@ func (q *Queries) SearchUsersByName(ctx context.Context, dollar_1 pgtype.Text) (
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.EmailVerifiedAt,
+			&i.AvatarUrl,
+			&i.DeletedAt,
+			&i.PurgedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -145,30 +273,70 @@ func (q *Queries) SearchUsersByName(ctx context.Context, dollar_1 pgtype.Text) (
 	return items, nil
 }
 
-const updateUser = `-- name: UpdateUser :exec
+const softDeleteUser = `-- name: SoftDeleteUser :execrows
+UPDATE "user"
+SET deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) SoftDeleteUser(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, softDeleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateUser = `-- name: UpdateUser :one
 UPDATE "user"
 SET full_name = $2,
     bio = $3,
     email = $4,
+    avatar_url = $5,
+    email_verified_at = $6,
     updated_at = NOW()
-WHERE id = $1
+WHERE id = $1 AND updated_at = $7
+RETURNING id, full_name, bio, email, password_hash, is_active, created_at, updated_at, email_verified_at, avatar_url, deleted_at, purged_at
 `
 
 type UpdateUserParams struct {
-	ID       pgtype.UUID
-	FullName string
-	Bio      pgtype.Text
-	Email    pgtype.Text
+	ID              pgtype.UUID
+	FullName        string
+	Bio             pgtype.Text
+	Email           pgtype.Text
+	AvatarUrl       pgtype.Text
+	EmailVerifiedAt pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
-	_, err := q.db.Exec(ctx, updateUser,
+// optimistic concurrency: no row comes back when updated_at moved on
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUser,
 		arg.ID,
 		arg.FullName,
 		arg.Bio,
 		arg.Email,
+		arg.AvatarUrl,
+		arg.EmailVerifiedAt,
+		arg.UpdatedAt,
 	)
-	return err
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.FullName,
+		&i.Bio,
+		&i.Email,
+		&i.PasswordHash,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.PurgedAt,
+	)
+	return i, err
 }
 
 const updateUserPassword = `-- name: UpdateUserPassword :exec
@@ -1,5 +1,7 @@
 package keys
 
+import "strconv"
+
 func Device(deviceID string) string   { return "device:" + deviceID }
 func UserDevice(userID string) string { return "user_devices:" + userID }
 
@@ -9,6 +11,9 @@ func RevokedSess(sid string) string { return "revoked:sess:" + sid }
 
 func UserVer(uid string) string { return "user_ver" + uid }
 
+func UserActive(uid string) string { return "user:active:" + uid }
+func AccountPurgeLock() string     { return "account:purge:lock" }
+
 func UserDeviceFams(uid, did string) string { return "user_device_fams:" + uid + ":" + did }
 func UserFams(uid string) string            { return "user_fams:" + uid }
 func RTActive(jti string) string            { return "rt:active" + jti }
@@ -17,3 +22,44 @@ func RTFamilyBlock(fam string) string       { return "rt:family:black" + fam }
 func RotateLock(fam string) string          { return "rotate:lock" + fam }
 func FamActive(fam string) string           { return "rt:fam:active" + fam }
 func FamBlack(fam string) string            { return "rt:family:back" + fam }
+
+func MFAChallenge(token string) string { return "mfa:challenge:" + token }
+func MFAAttempts(token string) string  { return "mfa:attempts:" + token }
+func TOTPUsed(uid string, step int64) string {
+	return "mfa:totp:used:" + uid + ":" + strconv.FormatInt(step, 10)
+}
+
+func RolePerms(role string) string { return "rbac:role_perms:" + role }
+
+func PasswordReset(tokenHash string) string   { return "pwreset:token:" + tokenHash }
+func PasswordResetUser(uid string) string     { return "pwreset:user:" + uid }
+func PasswordResetThrottle(uid string) string { return "pwreset:throttle:" + uid }
+
+func EmailVerify(tokenHash string) string   { return "emailverify:token:" + tokenHash }
+func EmailVerifyUser(uid string) string     { return "emailverify:user:" + uid }
+func EmailVerifyThrottle(uid string) string { return "emailverify:throttle:" + uid }
+
+func EmailChange(tokenHash string) string { return "emailchange:token:" + tokenHash }
+func EmailChangeUser(uid string) string   { return "emailchange:user:" + uid }
+
+func OAuthCode(codeHash string) string     { return "oauth:code:" + codeHash }
+func OAuthCodeUsed(codeHash string) string { return "oauth:code_used:" + codeHash }
+func OAuthRevoked(jti string) string       { return "oauth:revoked:" + jti }
+
+func SocialState(state string) string { return "social:state:" + state }
+
+func WebAuthnChallenge(challenge string) string { return "webauthn:challenge:" + challenge }
+
+func LoginCode(email string) string         { return "logincode:email:" + email }
+func LoginCodeLink(tokenHash string) string { return "logincode:link:" + tokenHash }
+func LoginCodeAttempts(email string) string { return "logincode:attempts:" + email }
+func LoginCodeThrottle(email string) string { return "logincode:throttle:" + email }
+
+func RateLimit(policy, subject string) string { return "rl:" + policy + ":" + subject }
+
+func LoginFail(kind, subject string) string { return "login:fail:" + kind + ":" + subject }
+func LoginLock(kind, subject string) string { return "login:lock:" + kind + ":" + subject }
+func LockoutEvents() string                 { return "login:lockout_events" }
+
+func IPAllow() string { return "ipacl:allow" }
+func IPDeny() string  { return "ipacl:deny" }
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 var (
 	Active  = "active"
 	Block   = "block"
@@ -19,4 +21,65 @@ type TokenPair struct {
 	AccessToken  string
 	RefreshToken string
 	Expired      int64
+	SessionID    string
+}
+
+// LoginResult carries either a token pair or, for users with a second factor,
+// the challenge that must be completed through VerifyMFA.
+type LoginResult struct {
+	Token        *TokenPair
+	MFAChallenge *MFAChallenge
+}
+
+type MFAChallenge struct {
+	Token   string
+	Expired int64
+	Methods []string
+}
+
+type MFAVerifyCmd struct {
+	ChallengeToken string
+	Code           string
+	RecoveryCode   string
+}
+
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// PasskeyRegistrationCmd is the browser's response to
+// navigator.credentials.create.
+type PasskeyRegistrationCmd struct {
+	Name              string
+	ClientDataJSON    []byte
+	AttestationObject []byte
+	Transports        []string
+}
+
+// PasskeyLoginCmd is the browser's response to navigator.credentials.get.
+// UserHandle is the user id given at registration, when the browser sends it.
+type PasskeyLoginCmd struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+// LoginCodeCmd completes an email login with either the code typed in
+// together with the email, or the token from the emailed link.
+type LoginCodeCmd struct {
+	Email string
+	Code  string
+	Token string
+}
+
+// APITokenCmd creates a personal access token or service API key. A zero
+// ExpiresIn picks the default for the kind.
+type APITokenCmd struct {
+	Kind      string
+	Name      string
+	Scopes    []string
+	ExpiresIn time.Duration
 }
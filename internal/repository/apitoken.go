@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"seno-blackdragon/internal/db/apitoken"
+	"seno-blackdragon/pkg/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type APITokenRepo struct {
+	q *apitoken.Queries
+}
+
+// APITokenModel is a personal access token or service API key. TokenHash is
+// the only trace of the secret; Prefix finds the row for a presented token.
+type APITokenModel struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Kind       string // personal | service
+	Name       string
+	Prefix     string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  *time.Time // nil never expires
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+func NewAPITokenRepo(db apitoken.DBTX) *APITokenRepo {
+	q := apitoken.New(db)
+	return &APITokenRepo{q: q}
+}
+
+func (ar *APITokenRepo) Create(ctx context.Context, m APITokenModel) (*APITokenModel, error) {
+	var expires pgtype.Timestamptz
+	if m.ExpiresAt != nil {
+		expires = nullTime(*m.ExpiresAt)
+	}
+	row, err := ar.q.CreateAPIToken(ctx, apitoken.CreateAPITokenParams{
+		UserID:    utils.PgUUIDFromUUID(m.UserID),
+		Kind:      m.Kind,
+		Name:      m.Name,
+		Prefix:    m.Prefix,
+		TokenHash: m.TokenHash,
+		Scopes:    nonNil(m.Scopes),
+		ExpiresAt: expires,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return apiTokenFromRow(row), nil
+}
+
+// Get returns the user's token, or nil if the user has no such token.
+func (ar *APITokenRepo) Get(ctx context.Context, userID, id uuid.UUID) (*APITokenModel, error) {
+	row, err := ar.q.GetAPIToken(ctx, apitoken.GetAPITokenParams{
+		ID:     utils.PgUUIDFromUUID(id),
+		UserID: utils.PgUUIDFromUUID(userID),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return apiTokenFromRow(row), nil
+}
+
+// GetByPrefix returns the token starting with prefix, or nil if none does.
+func (ar *APITokenRepo) GetByPrefix(ctx context.Context, prefix string) (*APITokenModel, error) {
+	row, err := ar.q.GetAPITokenByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return apiTokenFromRow(row), nil
+}
+
+func (ar *APITokenRepo) ListByUserID(ctx context.Context, userID uuid.UUID) ([]APITokenModel, error) {
+	rows, err := ar.q.ListAPITokens(ctx, utils.PgUUIDFromUUID(userID))
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]APITokenModel, 0, len(rows))
+	for _, row := range rows {
+		tokens = append(tokens, *apiTokenFromRow(row))
+	}
+	return tokens, nil
+}
+
+// Rename reports false when the user has no such token.
+func (ar *APITokenRepo) Rename(ctx context.Context, userID, id uuid.UUID, name string) (bool, error) {
+	n, err := ar.q.RenameAPIToken(ctx, apitoken.RenameAPITokenParams{
+		ID:     utils.PgUUIDFromUUID(id),
+		UserID: utils.PgUUIDFromUUID(userID),
+		Name:   name,
+	})
+	return n > 0, err
+}
+
+// Touch records a use of the token. The row is written at most once a
+// minute, so busy tokens do not cost a write per request.
+func (ar *APITokenRepo) Touch(ctx context.Context, id uuid.UUID) error {
+	return ar.q.TouchAPIToken(ctx, utils.PgUUIDFromUUID(id))
+}
+
+// Delete reports false when the user has no such token.
+func (ar *APITokenRepo) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	n, err := ar.q.DeleteAPIToken(ctx, apitoken.DeleteAPITokenParams{
+		ID:     utils.PgUUIDFromUUID(id),
+		UserID: utils.PgUUIDFromUUID(userID),
+	})
+	return n > 0, err
+}
+
+func (ar *APITokenRepo) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	return ar.q.DeleteAPITokens(ctx, utils.PgUUIDFromUUID(userID))
+}
+
+// DeleteByKind removes the user's tokens of one kind and reports how many
+// there were.
+func (ar *APITokenRepo) DeleteByKind(ctx context.Context, userID uuid.UUID, kind string) (int64, error) {
+	return ar.q.DeleteAPITokensByKind(ctx, apitoken.DeleteAPITokensByKindParams{
+		UserID: utils.PgUUIDFromUUID(userID),
+		Kind:   kind,
+	})
+}
+
+func apiTokenFromRow(row apitoken.ApiToken) *APITokenModel {
+	m := &APITokenModel{
+		ID:        utils.UUIDFromPgUUID(row.ID),
+		UserID:    utils.UUIDFromPgUUID(row.UserID),
+		Kind:      row.Kind,
+		Name:      row.Name,
+		Prefix:    row.Prefix,
+		TokenHash: row.TokenHash,
+		Scopes:    row.Scopes,
+		CreatedAt: row.CreatedAt.Time,
+	}
+	if row.ExpiresAt.Valid {
+		t := row.ExpiresAt.Time
+		m.ExpiresAt = &t
+	}
+	if row.LastUsedAt.Valid {
+		t := row.LastUsedAt.Time
+		m.LastUsedAt = &t
+	}
+	return m
+}
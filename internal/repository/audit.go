@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"seno-blackdragon/internal/db/audit"
+	"seno-blackdragon/pkg/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type AuditRepo struct {
+	q *audit.Queries
+}
+
+// AuditEventModel is one row of the security audit trail. Empty strings and
+// uuid.Nil are stored as NULL.
+type AuditEventModel struct {
+	ID        uuid.UUID
+	Type      string
+	ActorID   uuid.UUID
+	SubjectID uuid.UUID
+	IP        string
+	UserAgent string
+	DeviceID  string
+	SessionID string
+	Metadata  map[string]any
+	CreatedAt time.Time
+}
+
+// AuditFilter narrows ListEvents; zero values match everything.
+type AuditFilter struct {
+	SubjectID uuid.UUID
+	ActorID   uuid.UUID
+	Type      string
+	IP        string
+	Since     time.Time
+	Until     time.Time
+}
+
+func NewAuditRepo(db audit.DBTX) *AuditRepo {
+	q := audit.New(db)
+	return &AuditRepo{q: q}
+}
+
+func (ar *AuditRepo) Insert(ctx context.Context, ev AuditEventModel) error {
+	meta, err := json.Marshal(ev.Metadata)
+	if err != nil {
+		return err
+	}
+	if ev.Metadata == nil {
+		meta = []byte("{}")
+	}
+	return ar.q.InsertAuditEvent(ctx, audit.InsertAuditEventParams{
+		EventType: ev.Type,
+		ActorID:   nullUUID(ev.ActorID),
+		SubjectID: nullUUID(ev.SubjectID),
+		Ip:        nullText(ev.IP),
+		UserAgent: nullText(ev.UserAgent),
+		DeviceID:  nullText(ev.DeviceID),
+		SessionID: nullText(ev.SessionID),
+		Metadata:  meta,
+	})
+}
+
+// ListEvents returns one page of events, newest first, and the total count
+// for the filter.
+func (ar *AuditRepo) ListEvents(ctx context.Context, f AuditFilter, limit, offset int) ([]AuditEventModel, int64, error) {
+	total, err := ar.q.CountAuditEvents(ctx, audit.CountAuditEventsParams{
+		SubjectID: nullUUID(f.SubjectID),
+		ActorID:   nullUUID(f.ActorID),
+		EventType: nullText(f.Type),
+		Ip:        nullText(f.IP),
+		Since:     nullTime(f.Since),
+		Until:     nullTime(f.Until),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := ar.q.ListAuditEvents(ctx, audit.ListAuditEventsParams{
+		SubjectID: nullUUID(f.SubjectID),
+		ActorID:   nullUUID(f.ActorID),
+		EventType: nullText(f.Type),
+		Ip:        nullText(f.IP),
+		Since:     nullTime(f.Since),
+		Until:     nullTime(f.Until),
+		Lim:       int32(limit),
+		Off:       int32(offset),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	events := make([]AuditEventModel, 0, len(rows))
+	for _, row := range rows {
+		ev := AuditEventModel{
+			ID:        utils.UUIDFromPgUUID(row.ID),
+			Type:      row.EventType,
+			ActorID:   utils.UUIDFromPgUUID(row.ActorID),
+			SubjectID: utils.UUIDFromPgUUID(row.SubjectID),
+			IP:        utils.StringFromPgText(row.Ip),
+			UserAgent: utils.StringFromPgText(row.UserAgent),
+			DeviceID:  utils.StringFromPgText(row.DeviceID),
+			SessionID: utils.StringFromPgText(row.SessionID),
+			CreatedAt: row.CreatedAt.Time,
+		}
+		_ = json.Unmarshal(row.Metadata, &ev.Metadata)
+		events = append(events, ev)
+	}
+	return events, total, nil
+}
+
+func nullUUID(id uuid.UUID) pgtype.UUID {
+	if id == uuid.Nil {
+		return pgtype.UUID{}
+	}
+	return utils.PgUUIDFromUUID(id)
+}
+
+func nullText(s string) pgtype.Text {
+	if s == "" {
+		return pgtype.Text{}
+	}
+	return utils.PgTextFromString(s)
+}
+
+func nullTime(t time.Time) pgtype.Timestamptz {
+	if t.IsZero() {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+// ScrubSubject clears IPs, user agents and emails from the events of a user.
+func (ar *AuditRepo) ScrubSubject(ctx context.Context, subjectID uuid.UUID) error {
+	return ar.q.ScrubAuditEvents(ctx, utils.PgUUIDFromUUID(subjectID))
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"seno-blackdragon/internal/db/identity"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type IdentityRepo struct {
+	q *identity.Queries
+}
+
+// IdentityModel links an account at an external OpenID Connect provider to a
+// user. Subject is the provider's "sub" claim.
+type IdentityModel struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Provider    string
+	Subject     string
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt *time.Time
+}
+
+func NewIdentityRepo(db identity.DBTX) *IdentityRepo {
+	q := identity.New(db)
+	return &IdentityRepo{q: q}
+}
+
+// Create links an identity. It fails with enum.ErrIdentityLinked when the
+// external account or the provider is already linked.
+func (ir *IdentityRepo) Create(ctx context.Context, m IdentityModel) (*IdentityModel, error) {
+	row, err := ir.q.CreateUserIdentity(ctx, identity.CreateUserIdentityParams{
+		UserID:   utils.PgUUIDFromUUID(m.UserID),
+		Provider: m.Provider,
+		Subject:  m.Subject,
+		Email:    nullText(m.Email),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return nil, enum.ErrIdentityLinked
+		}
+		return nil, err
+	}
+	return identityFromRow(row), nil
+}
+
+// Get returns the identity for an external account, or nil if it is not
+// linked to anyone.
+func (ir *IdentityRepo) Get(ctx context.Context, provider, subject string) (*IdentityModel, error) {
+	row, err := ir.q.GetUserIdentity(ctx, identity.GetUserIdentityParams{Provider: provider, Subject: subject})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return identityFromRow(row), nil
+}
+
+func (ir *IdentityRepo) ListByUserID(ctx context.Context, userID uuid.UUID) ([]IdentityModel, error) {
+	rows, err := ir.q.ListUserIdentities(ctx, utils.PgUUIDFromUUID(userID))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]IdentityModel, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, *identityFromRow(row))
+	}
+	return ids, nil
+}
+
+// Touch records a login and the email the provider reported for it.
+func (ir *IdentityRepo) Touch(ctx context.Context, id uuid.UUID, email string) error {
+	return ir.q.TouchUserIdentity(ctx, identity.TouchUserIdentityParams{
+		ID:    utils.PgUUIDFromUUID(id),
+		Email: nullText(email),
+	})
+}
+
+// Delete unlinks the user's identity at provider; it reports false when
+// there was none.
+func (ir *IdentityRepo) Delete(ctx context.Context, userID uuid.UUID, provider string) (bool, error) {
+	n, err := ir.q.DeleteUserIdentity(ctx, identity.DeleteUserIdentityParams{
+		UserID:   utils.PgUUIDFromUUID(userID),
+		Provider: provider,
+	})
+	return n > 0, err
+}
+
+func (ir *IdentityRepo) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	return ir.q.DeleteUserIdentities(ctx, utils.PgUUIDFromUUID(userID))
+}
+
+func identityFromRow(row identity.UserIdentity) *IdentityModel {
+	m := &IdentityModel{
+		ID:        utils.UUIDFromPgUUID(row.ID),
+		UserID:    utils.UUIDFromPgUUID(row.UserID),
+		Provider:  row.Provider,
+		Subject:   row.Subject,
+		Email:     utils.StringFromPgText(row.Email),
+		CreatedAt: row.CreatedAt.Time,
+	}
+	if row.LastLoginAt.Valid {
+		t := row.LastLoginAt.Time
+		m.LastLoginAt = &t
+	}
+	return m
+}
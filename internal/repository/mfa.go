@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"seno-blackdragon/internal/db/mfa"
+	"seno-blackdragon/pkg/utils"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type MFARepo struct {
+	q *mfa.Queries
+}
+
+type MFAModel struct {
+	UserID     uuid.UUID
+	TOTPSecret string
+	Enabled    bool
+}
+
+func NewMFARepo(db mfa.DBTX) *MFARepo {
+	q := mfa.New(db)
+	return &MFARepo{q: q}
+}
+
+// GetByUserID returns the MFA settings of a user, or nil if never enrolled.
+func (mr *MFARepo) GetByUserID(ctx context.Context, userID uuid.UUID) (*MFAModel, error) {
+	row, err := mr.q.GetUserMFA(ctx, utils.PgUUIDFromUUID(userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &MFAModel{
+		UserID:     utils.UUIDFromPgUUID(row.UserID),
+		TOTPSecret: row.TotpSecret,
+		Enabled:    row.EnabledAt.Valid,
+	}, nil
+}
+
+// SaveTOTPSecret stores a pending (not yet confirmed) TOTP secret.
+func (mr *MFARepo) SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	return mr.q.UpsertTOTPSecret(ctx, mfa.UpsertTOTPSecretParams{
+		UserID:     utils.PgUUIDFromUUID(userID),
+		TotpSecret: secret,
+	})
+}
+
+func (mr *MFARepo) EnableTOTP(ctx context.Context, userID uuid.UUID) error {
+	return mr.q.EnableTOTP(ctx, utils.PgUUIDFromUUID(userID))
+}
+
+// ReplaceRecoveryCodes drops all previous recovery codes and stores new hashes.
+func (mr *MFARepo) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	return mr.q.ReplaceRecoveryCodes(ctx, mfa.ReplaceRecoveryCodesParams{
+		UserID:     utils.PgUUIDFromUUID(userID),
+		CodeHashes: hashes,
+	})
+}
+
+// UseRecoveryCode burns an unused recovery code; false means no such code.
+func (mr *MFARepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error) {
+	_, err := mr.q.UseRecoveryCode(ctx, mfa.UseRecoveryCodeParams{
+		UserID:   utils.PgUUIDFromUUID(userID),
+		CodeHash: hash,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// DeleteByUserID removes the TOTP secret and all recovery codes of a user.
+func (mr *MFARepo) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	return mr.q.DeleteUserMFA(ctx, utils.PgUUIDFromUUID(userID))
+}
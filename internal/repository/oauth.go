@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"seno-blackdragon/internal/db/oauth"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/utils"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type OAuthRepo struct {
+	q *oauth.Queries
+}
+
+// OAuthClientModel is a registered OAuth2 client. An empty SecretHash marks
+// a public client.
+type OAuthClientModel struct {
+	ID           string
+	Name         string
+	SecretHash   string
+	RedirectURIs []string
+	GrantTypes   []string
+	Scopes       []string
+	FirstParty   bool
+	CreatedAt    time.Time
+}
+
+func NewOAuthRepo(db oauth.DBTX) *OAuthRepo {
+	q := oauth.New(db)
+	return &OAuthRepo{q: q}
+}
+
+func (oa *OAuthRepo) CreateClient(ctx context.Context, c OAuthClientModel) (*OAuthClientModel, error) {
+	row, err := oa.q.CreateOAuthClient(ctx, oauth.CreateOAuthClientParams{
+		ID:           c.ID,
+		Name:         c.Name,
+		SecretHash:   nullText(c.SecretHash),
+		RedirectUris: nonNil(c.RedirectURIs),
+		GrantTypes:   nonNil(c.GrantTypes),
+		Scopes:       nonNil(c.Scopes),
+		FirstParty:   c.FirstParty,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return oauthClientFromRow(row), nil
+}
+
+// GetClient returns enum.ErrOAuthClientNotFound for unknown ids.
+func (oa *OAuthRepo) GetClient(ctx context.Context, id string) (*OAuthClientModel, error) {
+	row, err := oa.q.GetOAuthClient(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, enum.ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+	return oauthClientFromRow(row), nil
+}
+
+func (oa *OAuthRepo) ListClients(ctx context.Context) ([]OAuthClientModel, error) {
+	rows, err := oa.q.ListOAuthClients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clients := make([]OAuthClientModel, 0, len(rows))
+	for _, row := range rows {
+		clients = append(clients, *oauthClientFromRow(row))
+	}
+	return clients, nil
+}
+
+func (oa *OAuthRepo) DeleteClient(ctx context.Context, id string) error {
+	n, err := oa.q.DeleteOAuthClient(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return enum.ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+func oauthClientFromRow(row oauth.OauthClient) *OAuthClientModel {
+	return &OAuthClientModel{
+		ID:           row.ID,
+		Name:         row.Name,
+		SecretHash:   utils.StringFromPgText(row.SecretHash),
+		RedirectURIs: row.RedirectUris,
+		GrantTypes:   row.GrantTypes,
+		Scopes:       row.Scopes,
+		FirstParty:   row.FirstParty,
+		CreatedAt:    row.CreatedAt.Time,
+	}
+}
+
+// nonNil keeps NOT NULL array columns from receiving NULL.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
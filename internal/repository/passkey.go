@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"seno-blackdragon/internal/db/passkey"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type PasskeyRepo struct {
+	q *passkey.Queries
+}
+
+// PasskeyModel is a WebAuthn credential. PublicKey is the COSE key from the
+// registration; SignCount the last signature counter seen.
+type PasskeyModel struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	CredentialID   []byte
+	PublicKey      []byte
+	SignCount      uint32
+	Transports     []string
+	AAGUID         []byte
+	BackupEligible bool
+	Name           string
+	CreatedAt      time.Time
+	LastUsedAt     *time.Time
+}
+
+func NewPasskeyRepo(db passkey.DBTX) *PasskeyRepo {
+	q := passkey.New(db)
+	return &PasskeyRepo{q: q}
+}
+
+// Create stores a credential. It fails with enum.ErrPasskeyExists when the
+// credential id is already registered.
+func (pr *PasskeyRepo) Create(ctx context.Context, m PasskeyModel) (*PasskeyModel, error) {
+	row, err := pr.q.CreateWebAuthnCredential(ctx, passkey.CreateWebAuthnCredentialParams{
+		UserID:         utils.PgUUIDFromUUID(m.UserID),
+		CredentialID:   m.CredentialID,
+		PublicKey:      m.PublicKey,
+		SignCount:      int64(m.SignCount),
+		Transports:     nonNil(m.Transports),
+		Aaguid:         m.AAGUID,
+		BackupEligible: m.BackupEligible,
+		Name:           m.Name,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return nil, enum.ErrPasskeyExists
+		}
+		return nil, err
+	}
+	return passkeyFromRow(row), nil
+}
+
+// GetByCredentialID returns the credential, or nil if it is not registered.
+func (pr *PasskeyRepo) GetByCredentialID(ctx context.Context, credentialID []byte) (*PasskeyModel, error) {
+	row, err := pr.q.GetWebAuthnCredential(ctx, credentialID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return passkeyFromRow(row), nil
+}
+
+func (pr *PasskeyRepo) ListByUserID(ctx context.Context, userID uuid.UUID) ([]PasskeyModel, error) {
+	rows, err := pr.q.ListWebAuthnCredentials(ctx, utils.PgUUIDFromUUID(userID))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]PasskeyModel, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, *passkeyFromRow(row))
+	}
+	return keys, nil
+}
+
+// UpdateUsage records a login and the new signature counter.
+func (pr *PasskeyRepo) UpdateUsage(ctx context.Context, id uuid.UUID, signCount uint32) error {
+	return pr.q.UpdateWebAuthnCredentialUsage(ctx, passkey.UpdateWebAuthnCredentialUsageParams{
+		ID:        utils.PgUUIDFromUUID(id),
+		SignCount: int64(signCount),
+	})
+}
+
+// Rename reports false when the user has no such credential.
+func (pr *PasskeyRepo) Rename(ctx context.Context, userID, id uuid.UUID, name string) (bool, error) {
+	n, err := pr.q.RenameWebAuthnCredential(ctx, passkey.RenameWebAuthnCredentialParams{
+		ID:     utils.PgUUIDFromUUID(id),
+		UserID: utils.PgUUIDFromUUID(userID),
+		Name:   name,
+	})
+	return n > 0, err
+}
+
+// Delete reports false when the user has no such credential.
+func (pr *PasskeyRepo) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	n, err := pr.q.DeleteWebAuthnCredential(ctx, passkey.DeleteWebAuthnCredentialParams{
+		ID:     utils.PgUUIDFromUUID(id),
+		UserID: utils.PgUUIDFromUUID(userID),
+	})
+	return n > 0, err
+}
+
+func (pr *PasskeyRepo) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	return pr.q.DeleteWebAuthnCredentials(ctx, utils.PgUUIDFromUUID(userID))
+}
+
+func passkeyFromRow(row passkey.WebauthnCredential) *PasskeyModel {
+	m := &PasskeyModel{
+		ID:             utils.UUIDFromPgUUID(row.ID),
+		UserID:         utils.UUIDFromPgUUID(row.UserID),
+		CredentialID:   row.CredentialID,
+		PublicKey:      row.PublicKey,
+		SignCount:      uint32(row.SignCount),
+		Transports:     row.Transports,
+		AAGUID:         row.Aaguid,
+		BackupEligible: row.BackupEligible,
+		Name:           row.Name,
+		CreatedAt:      row.CreatedAt.Time,
+	}
+	if row.LastUsedAt.Valid {
+		t := row.LastUsedAt.Time
+		m.LastUsedAt = &t
+	}
+	return m
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"seno-blackdragon/internal/db/rbac"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/utils"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type RBACRepo struct {
+	q *rbac.Queries
+}
+
+type RoleModel struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+}
+
+func NewRBACRepo(db rbac.DBTX) *RBACRepo {
+	q := rbac.New(db)
+	return &RBACRepo{q: q}
+}
+
+func (rr *RBACRepo) ListRoles(ctx context.Context) ([]RoleModel, error) {
+	rows, err := rr.q.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]RoleModel, 0, len(rows))
+	for _, row := range rows {
+		roles = append(roles, RoleModel{
+			ID:          utils.UUIDFromPgUUID(row.ID),
+			Name:        row.Name,
+			Description: utils.StringFromPgText(row.Description),
+		})
+	}
+	return roles, nil
+}
+
+func (rr *RBACRepo) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	roles, err := rr.q.ListUserRoles(ctx, utils.PgUUIDFromUUID(userID))
+	if err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	return roles, nil
+}
+
+func (rr *RBACRepo) GetPermissionsByRoles(ctx context.Context, roles []string) ([]string, error) {
+	perms, err := rr.q.ListPermissionsByRoles(ctx, roles)
+	if err != nil {
+		return nil, err
+	}
+	if perms == nil {
+		perms = []string{}
+	}
+	return perms, nil
+}
+
+// AssignRole grants a role by name; assigning an existing role is a no-op.
+func (rr *RBACRepo) AssignRole(ctx context.Context, userID uuid.UUID, role string) error {
+	if _, err := rr.q.GetRoleByName(ctx, role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return enum.ErrRoleNotFound
+		}
+		return err
+	}
+	return rr.q.AssignUserRole(ctx, rbac.AssignUserRoleParams{
+		UserID:   utils.PgUUIDFromUUID(userID),
+		RoleName: role,
+	})
+}
+
+func (rr *RBACRepo) RevokeRole(ctx context.Context, userID uuid.UUID, role string) error {
+	n, err := rr.q.RevokeUserRole(ctx, rbac.RevokeUserRoleParams{
+		UserID:   utils.PgUUIDFromUUID(userID),
+		RoleName: role,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return enum.ErrRoleNotFound
+	}
+	return nil
+}
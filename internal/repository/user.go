@@ -6,8 +6,13 @@ import (
 	"seno-blackdragon/internal/db/user"
 	"seno-blackdragon/pkg/enum"
 	"seno-blackdragon/pkg/utils"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type UserRepo struct {
@@ -15,60 +20,229 @@ type UserRepo struct {
 }
 
 type UserModel struct {
-	ID           uuid.UUID
-	FullName     string
-	Bio          string
-	Email        string
-	PasswordHash string
+	ID              uuid.UUID
+	FullName        string
+	Bio             string
+	Email           string
+	AvatarURL       string
+	PasswordHash    string
+	EmailVerifiedAt *time.Time // nil until the email is verified
+	IsActive        bool
+	DeletedAt       *time.Time // set while the account waits to be purged
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
+// pgUniqueViolation is the SQLSTATE of a unique index conflict. The only
+// unique index on "user" besides the primary key is the one on lower(email).
+const pgUniqueViolation = "23505"
+
+// UserSortColumns are the columns SearchUsers can order by.
+var UserSortColumns = []string{"full_name", "email", "created_at"}
+
 func NewUserRepo(db user.DBTX) *UserRepo {
 	q := user.New(db)
 	return &UserRepo{q: q}
 }
 
+// NormalizeEmail is the form emails are stored and compared in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// GetUserByEmail finds the live (not deleted) account with email, ignoring case.
 func (ur *UserRepo) GetUserByEmail(ctx context.Context, email string) (*UserModel, error) {
-	row, err := ur.q.GetUserByEmail(ctx, utils.PgTextFromString(email))
+	row, err := ur.q.GetUserByEmail(ctx, NormalizeEmail(email))
 	if err != nil {
-		if errors.Is(err, enum.ErrUserNotFound) {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, enum.ErrUserNotFound
 		}
 		return nil, err
 	}
-	user := &UserModel{
-		ID:           utils.UUIDFromPgUUID(row.ID),
-		FullName:     row.FullName,
-		Bio:          utils.StringFromPgText(row.Bio),
-		PasswordHash: utils.StringFromPgText(row.PasswordHash),
-	}
-	return user, nil
+	return userFromRow(row), nil
 }
 
+// CreateUser inserts a user and returns its id. It fails with
+// enum.ErrEmailAlready when a live account already has the email.
 func (ur UserRepo) CreateUser(ctx context.Context, u *UserModel) (uuid.UUID, error) {
 	params := user.AddUserParams{
 		FullName:     u.FullName,
-		Email:        utils.PgTextFromString(u.Email),
+		Email:        utils.PgTextFromString(NormalizeEmail(u.Email)),
 		Bio:          utils.PgTextFromString(u.Bio),
 		PasswordHash: utils.PgTextFromString(u.PasswordHash),
 	}
 
 	id, err := ur.q.AddUser(ctx, params)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, emailConflict(err)
 	}
 	return utils.UUIDFromPgUUID(id), nil
 }
 
 func (ur UserRepo) GetUserByID(ctx context.Context, id uuid.UUID) (*UserModel, error) {
 	row, err := ur.q.GetUserByID(ctx, utils.PgUUIDFromUUID(id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, enum.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return userFromRow(row), nil
+}
+
+func (ur UserRepo) UpdatePassword(ctx context.Context, id uuid.UUID, hash string) error {
+	return ur.q.UpdateUserPassword(ctx, user.UpdateUserPasswordParams{
+		ID:           utils.PgUUIDFromUUID(id),
+		PasswordHash: utils.PgTextFromString(hash),
+	})
+}
+
+// MarkEmailVerified sets email_verified_at once; it reports false when the
+// email was already verified.
+func (ur UserRepo) MarkEmailVerified(ctx context.Context, id uuid.UUID) (bool, error) {
+	n, err := ur.q.MarkEmailVerified(ctx, utils.PgUUIDFromUUID(id))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SearchUsers returns one page of users whose name contains query and the
+// total number of matches. sortBy must be one of UserSortColumns.
+func (ur UserRepo) SearchUsers(ctx context.Context, query, sortBy string, desc bool, limit, offset int) ([]UserModel, int64, error) {
+	query = escapeLike(query)
+	total, err := ur.q.CountUsersByName(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := ur.q.SearchUsersByName(ctx, user.SearchUsersByNameParams{
+		Query:    query,
+		SortBy:   sortBy,
+		SortDesc: desc,
+		Lim:      int32(limit),
+		Off:      int32(offset),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	users := make([]UserModel, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, *userFromRow(row))
+	}
+	return users, total, nil
+}
+
+func (ur UserRepo) SetActive(ctx context.Context, id uuid.UUID, active bool) error {
+	if active {
+		return ur.q.ActivateUser(ctx, utils.PgUUIDFromUUID(id))
+	}
+	return ur.q.DeactivateUser(ctx, utils.PgUUIDFromUUID(id))
+}
+
+// UpdateProfile writes the profile fields of u. u.UpdatedAt must be the
+// value that was read: if the row changed since, nothing is written and
+// enum.ErrUserModified is returned. A taken email gives enum.ErrEmailAlready.
+// The stored row is returned on success.
+func (ur UserRepo) UpdateProfile(ctx context.Context, u *UserModel) (*UserModel, error) {
+	verifiedAt := pgtype.Timestamptz{}
+	if u.EmailVerifiedAt != nil {
+		verifiedAt = pgtype.Timestamptz{Time: *u.EmailVerifiedAt, Valid: true}
+	}
+	row, err := ur.q.UpdateUser(ctx, user.UpdateUserParams{
+		ID:              utils.PgUUIDFromUUID(u.ID),
+		FullName:        u.FullName,
+		Bio:             utils.PgTextFromString(u.Bio),
+		Email:           utils.PgTextFromString(NormalizeEmail(u.Email)),
+		AvatarUrl:       utils.PgTextFromString(u.AvatarURL),
+		EmailVerifiedAt: verifiedAt,
+		UpdatedAt:       pgtype.Timestamptz{Time: u.UpdatedAt, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, enum.ErrUserModified
+		}
+		return nil, emailConflict(err)
+	}
+	return userFromRow(row), nil
+}
+
+// ClearPassword sets the password hash to NULL; no password matches until
+// the user sets a new one through a reset.
+func (ur UserRepo) ClearPassword(ctx context.Context, id uuid.UUID) error {
+	return ur.q.UpdateUserPassword(ctx, user.UpdateUserPasswordParams{
+		ID:           utils.PgUUIDFromUUID(id),
+		PasswordHash: pgtype.Text{},
+	})
+}
+
+// SoftDelete marks the account deleted; it reports false when it already was.
+// GetUserByEmail no longer finds it and its email can be registered again.
+func (ur UserRepo) SoftDelete(ctx context.Context, id uuid.UUID) (bool, error) {
+	n, err := ur.q.SoftDeleteUser(ctx, utils.PgUUIDFromUUID(id))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Restore undoes SoftDelete as long as the account has not been purged.
+func (ur UserRepo) Restore(ctx context.Context, id uuid.UUID) (bool, error) {
+	n, err := ur.q.RestoreUser(ctx, utils.PgUUIDFromUUID(id))
+	if err != nil {
+		return false, emailConflict(err)
+	}
+	return n > 0, nil
+}
+
+// ListToPurge returns up to limit accounts deleted before cutoff whose
+// personal data is still stored, oldest first.
+func (ur UserRepo) ListToPurge(ctx context.Context, cutoff time.Time, limit int) ([]uuid.UUID, error) {
+	rows, err := ur.q.ListUsersToPurge(ctx, user.ListUsersToPurgeParams{
+		Cutoff: pgtype.Timestamptz{Time: cutoff, Valid: true},
+		Lim:    int32(limit),
+	})
 	if err != nil {
 		return nil, err
 	}
-	u := &UserModel{
-		ID:           utils.UUIDFromPgUUID(row.ID),
-		FullName:     row.FullName,
-		Bio:          utils.StringFromPgText(row.Bio),
-		PasswordHash: utils.StringFromPgText(row.PasswordHash),
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, id := range rows {
+		ids = append(ids, utils.UUIDFromPgUUID(id))
 	}
-	return u, nil
+	return ids, nil
 }
+
+// Purge wipes the personal data of a soft-deleted account but keeps the row.
+func (ur UserRepo) Purge(ctx context.Context, id uuid.UUID) error {
+	return ur.q.PurgeUser(ctx, utils.PgUUIDFromUUID(id))
+}
+
+// emailConflict turns a unique violation into enum.ErrEmailAlready.
+func emailConflict(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return enum.ErrEmailAlready
+	}
+	return err
+}
+
+func userFromRow(row user.User) *UserModel {
+	return &UserModel{
+		ID:              utils.UUIDFromPgUUID(row.ID),
+		FullName:        row.FullName,
+		Bio:             utils.StringFromPgText(row.Bio),
+		Email:           utils.StringFromPgText(row.Email),
+		AvatarURL:       utils.StringFromPgText(row.AvatarUrl),
+		PasswordHash:    utils.StringFromPgText(row.PasswordHash),
+		EmailVerifiedAt: utils.TimePtrFromPgTimestamptz(row.EmailVerifiedAt),
+		IsActive:        row.IsActive,
+		DeletedAt:       utils.TimePtrFromPgTimestamptz(row.DeletedAt),
+		CreatedAt:       row.CreatedAt.Time,
+		UpdatedAt:       row.UpdatedAt.Time,
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes s match itself literally inside a LIKE pattern that
+// declares ESCAPE '\'.
+func escapeLike(s string) string { return likeEscaper.Replace(s) }
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"seno-blackdragon/internal/db/dbtest"
+)
+
+func TestSearchUsersEscapesLike(t *testing.T) {
+	db := dbtest.New().On("CountUsersByName", dbtest.Value(int64(0)))
+	if _, _, err := NewUserRepo(db).SearchUsers(context.Background(), `50%_off\`, "full_name", false, 10, 0); err != nil {
+		t.Fatal(err)
+	}
+	const want = `50\%\_off\\`
+	for _, q := range []string{"CountUsersByName", "SearchUsersByName"} {
+		calls := db.Calls(q)
+		if len(calls) != 1 || calls[0].Args[0] != want {
+			t.Errorf("%s: got %v, want the pattern %q", q, calls, want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/notify"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	userActiveTTL        = 5 * time.Minute // how long CheckUserActive trusts its cache
+	accountPurgeInterval = time.Hour
+	accountPurgeBatch    = 100
+)
+
+// AccountDeletion tells the user until when the account can be restored.
+type AccountDeletion struct {
+	DeletedAt  time.Time `json:"deleted_at"`
+	PurgeAfter time.Time `json:"purge_after"`
+}
+
+func userActive(u *repository.UserModel) bool {
+	return u.IsActive && u.DeletedAt == nil
+}
+
+// CheckUserActive fails with enum.ErrAccountDisabled when the account was
+// deactivated or deleted. The answer is cached in Redis for userActiveTTL;
+// every change of the account state drops the cache.
+func (as *AuthService) CheckUserActive(ctx context.Context, userID string) error {
+	v, err := as.redis.Get(ctx, keys.UserActive(userID)).Result()
+	if err == nil {
+		if v != "1" {
+			return enum.ErrAccountDisabled
+		}
+		return nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return err
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return enum.ErrAccountDisabled
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if errors.Is(err, enum.ErrUserNotFound) {
+		return enum.ErrAccountDisabled
+	}
+	if err != nil {
+		return err
+	}
+	active := userActive(u)
+	v = "0"
+	if active {
+		v = "1"
+	}
+	if err := as.redis.Set(ctx, keys.UserActive(userID), v, userActiveTTL).Err(); err != nil {
+		as.log.Warn("cache_user_active_failed", zap.String("user_id", userID), zap.Error(err))
+	}
+	if !active {
+		return enum.ErrAccountDisabled
+	}
+	return nil
+}
+
+func (as *AuthService) dropUserActive(ctx context.Context, userID string) {
+	if err := as.redis.Del(ctx, keys.UserActive(userID)).Err(); err != nil {
+		as.log.Warn("drop_user_active_failed", zap.String("user_id", userID), zap.Error(err))
+	}
+}
+
+// DeleteAccount soft-deletes the account of session sid once confirmIdentity
+// passes and logs it out everywhere. The personal data is purged once the
+// grace period is over; until then an administrator can restore the account.
+func (as *AuthService) DeleteAccount(ctx context.Context, userID, sid, password string) (*AccountDeletion, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if err := as.confirmIdentity(ctx, u, sid, password); err != nil {
+		return nil, err
+	}
+	ok, err := as.userRepo.SoftDelete(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, enum.ErrAccountDisabled
+	}
+	as.dropUserActive(ctx, userID)
+	if err := as.LogoutAll(ctx, userID); err != nil {
+		// the middleware refuses deleted accounts anyway
+		as.log.Warn("logout_deleted_account_failed", zap.String("user_id", userID), zap.Error(err))
+	}
+	now := time.Now().UTC()
+	del := &AccountDeletion{DeletedAt: now, PurgeAfter: now.Add(as.policy.DeletionGrace)}
+	as.audit(ctx, AuditEvent{Type: AuditAccountDelete, SubjectID: userID, Metadata: map[string]any{"purge_after": del.PurgeAfter}})
+	if u.Email != "" {
+		err := as.notifier.Send(ctx, notify.Message{
+			Kind: notify.KindAccountDeletion,
+			To:   u.Email,
+			Data: map[string]string{"purge_after": del.PurgeAfter.Format(time.RFC3339)},
+		})
+		if err != nil {
+			as.log.Warn("send_account_deletion_failed", zap.String("user_id", userID), zap.Error(err))
+		}
+	}
+	return del, nil
+}
+
+// RestoreUser undoes a self-service deletion during the grace period. It
+// fails with enum.ErrEmailAlready when the address was registered again.
+func (as *AuthService) RestoreUser(ctx context.Context, userID uuid.UUID) error {
+	u, err := as.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.DeletedAt == nil {
+		return nil
+	}
+	if err := as.checkEmailFree(ctx, u.Email, userID); err != nil {
+		return err
+	}
+	ok, err := as.userRepo.Restore(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// purged in the meantime
+		return enum.ErrUserNotFound
+	}
+	as.dropUserActive(ctx, userID.String())
+	as.audit(ctx, AuditEvent{Type: AuditAccountRestore, SubjectID: userID.String()})
+	return nil
+}
+
+// PurgeDeletedAccounts wipes the personal data of every account deleted more
+// than the grace period ago and returns how many were purged.
+func (as *AuthService) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	cutoff := time.Now().UTC().Add(-as.policy.DeletionGrace)
+	n := 0
+	for {
+		ids, err := as.userRepo.ListToPurge(ctx, cutoff, accountPurgeBatch)
+		if err != nil {
+			return n, err
+		}
+		for _, id := range ids {
+			if err := as.purgeAccount(ctx, id); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if len(ids) < accountPurgeBatch {
+			return n, nil
+		}
+	}
+}
+
+// purgeAccount drops MFA secrets, linked identities, passkeys, API tokens,
+// devices and audit trail details before anonymising the user row, so a
+// failure is retried on the next run.
+func (as *AuthService) purgeAccount(ctx context.Context, id uuid.UUID) error {
+	uid := id.String()
+	if err := as.mfaRepo.DeleteByUserID(ctx, id); err != nil {
+		return err
+	}
+	if err := as.auditRepo.ScrubSubject(ctx, id); err != nil {
+		return err
+	}
+	if err := as.identityRepo.DeleteByUserID(ctx, id); err != nil {
+		return err
+	}
+	if err := as.passkeyRepo.DeleteByUserID(ctx, id); err != nil {
+		return err
+	}
+	if err := as.apiTokenRepo.DeleteByUserID(ctx, id); err != nil {
+		return err
+	}
+	dids, err := as.redis.SMembers(ctx, keys.UserDevice(uid)).Result()
+	if err != nil {
+		return err
+	}
+	pipe := as.redis.TxPipeline()
+	for _, did := range dids {
+		pipe.Del(ctx, keys.Device(did), keys.UserDeviceFams(uid, did))
+	}
+	pipe.Del(ctx, keys.UserDevice(uid), keys.EmailChangeUser(uid), keys.EmailVerifyUser(uid), keys.PasswordResetUser(uid))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if err := as.userRepo.Purge(ctx, id); err != nil {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditAccountPurge, SubjectID: uid})
+	return nil
+}
+
+// RunAccountPurge calls PurgeDeletedAccounts right away and then every
+// accountPurgeInterval until ctx is done. The Redis lock is left to expire
+// so that only one instance purges per interval.
+func (as *AuthService) RunAccountPurge(ctx context.Context) {
+	t := time.NewTicker(accountPurgeInterval)
+	defer t.Stop()
+	for {
+		if ok, err := as.redis.SetNX(ctx, keys.AccountPurgeLock(), nowISO(), accountPurgeInterval/2).Result(); err != nil {
+			as.log.Warn("account_purge_lock_failed", zap.Error(err))
+		} else if ok {
+			n, err := as.PurgeDeletedAccounts(ctx)
+			if err != nil {
+				as.log.Warn("account_purge_failed", zap.Int("purged", n), zap.Error(err))
+			} else if n > 0 {
+				as.log.Info("account_purge", zap.Int("purged", n))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
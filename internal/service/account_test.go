@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"seno-blackdragon/internal/db/dbtest"
+	"seno-blackdragon/internal/db/user"
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/utils"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const accountPassword = "Curr3nt-Passw0rd!"
+
+// accountRow is a user row with accountPassword hashed by as.
+func accountRow(t *testing.T, as *AuthService, uid uuid.UUID) user.User {
+	t.Helper()
+	hash, err := as.hasher.Hash(accountPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.User{
+		ID:           utils.PgUUIDFromUUID(uid),
+		Email:        pgtype.Text{String: testEmail, Valid: true},
+		PasswordHash: pgtype.Text{String: hash, Valid: true},
+		IsActive:     true,
+	}
+}
+
+func TestInactiveAccountRejected(t *testing.T) {
+	cases := []struct {
+		name    string
+		disable func(*user.User)
+	}{
+		{"deactivated", func(u *user.User) { u.IsActive = false }},
+		{"deleted", func(u *user.User) { u.DeletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true} }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			uid := uuid.New()
+			db := dbtest.New()
+			as, _, _ := newTestService(t, db, Policy{})
+			row := accountRow(t, as, uid)
+			db.On("GetUserByID", dbtest.Row(row))
+			u, err := as.userRepo.GetUserByID(ctx, uid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pair, err := as.issueTokens(ctx, u, model.LoginCmd{DeviceID: "DEV_1"}, sessionGrant{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tc.disable(&row)
+			db.On("GetUserByID", dbtest.Row(row)).On("GetUserByEmail", dbtest.Row(row))
+			if _, err := as.Login(ctx, model.LoginCmd{Email: testEmail, Password: accountPassword}); !errors.Is(err, enum.ErrAccountDisabled) {
+				t.Errorf("login: got %v, want ErrAccountDisabled", err)
+			}
+			if _, err := as.Refresh(ctx, pair.RefreshToken); !errors.Is(err, enum.ErrAccountDisabled) {
+				t.Errorf("refresh: got %v, want ErrAccountDisabled", err)
+			}
+			if err := as.CheckUserActive(ctx, uid.String()); !errors.Is(err, enum.ErrAccountDisabled) {
+				t.Errorf("middleware check: got %v, want ErrAccountDisabled", err)
+			}
+		})
+	}
+}
+
+func TestDeleteAccountConfirmsIdentity(t *testing.T) {
+	cases := []struct {
+		name         string
+		passwordless bool
+		given        string
+		authTime     time.Duration
+		want         error
+	}{
+		{"right password", false, accountPassword, time.Hour, nil},
+		{"wrong password", false, "wrong", time.Minute, enum.ErrInvalidCredentials},
+		{"no password given", false, "", time.Minute, enum.ErrInvalidCredentials},
+		{"passwordless, fresh login", true, "", time.Minute, nil},
+		{"passwordless, stale login", true, "", time.Hour, enum.ErrReauthRequired},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			uid := uuid.New()
+			db := dbtest.New()
+			as, _, _ := newTestService(t, db, Policy{DeletionGrace: 24 * time.Hour})
+			row := accountRow(t, as, uid)
+			if tc.passwordless {
+				row.PasswordHash = pgtype.Text{}
+			}
+			db.On("GetUserByID", dbtest.Row(row))
+			sess := &Session{UserID: uid.String(), Status: model.Active, AuthTime: time.Now().Add(-tc.authTime).Unix()}
+			if err := as.SaveSession(ctx, "SID_1", sess, 3600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := as.DeleteAccount(ctx, uid.String(), "SID_1", tc.given)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got %v, want %v", err, tc.want)
+			}
+			deleted := len(db.Calls("SoftDeleteUser"))
+			if (tc.want == nil) != (deleted == 1) {
+				t.Errorf("soft-deleted %d times", deleted)
+			}
+		})
+	}
+}
+
+func TestPurgeDeletedAccounts(t *testing.T) {
+	ctx := context.Background()
+	uid := uuid.New()
+	db := dbtest.New().On("ListUsersToPurge", dbtest.Value(utils.PgUUIDFromUUID(uid)))
+	as, mr, _ := newTestService(t, db, Policy{DeletionGrace: 24 * time.Hour})
+	as.auditRepo = repository.NewAuditRepo(db)
+	if err := as.SaveDevice(ctx, &Device{UserID: uid.String(), DeviceID: "DEV_1", Status: model.Active}); err != nil {
+		t.Fatal(err)
+	}
+	mr.Set(keys.EmailChangeUser(uid.String()), "pending")
+
+	n, err := as.PurgeDeletedAccounts(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("got %d, %v, want 1 account purged", n, err)
+	}
+	for _, q := range []string{"DeleteUserMFA", "ScrubAuditEvents", "DeleteUserIdentities", "DeleteWebAuthnCredentials", "DeleteAPITokens", "PurgeUser"} {
+		calls := db.Calls(q)
+		if len(calls) != 1 || calls[0].Args[0] != utils.PgUUIDFromUUID(uid) {
+			t.Errorf("%s: got %v, want one call for the user", q, calls)
+		}
+	}
+	for _, k := range []string{keys.Device("DEV_1"), keys.UserDevice(uid.String()), keys.EmailChangeUser(uid.String())} {
+		if mr.Exists(k) {
+			t.Errorf("%s survived the purge", k)
+		}
+	}
+	if args := db.Calls("ListUsersToPurge")[0].Args; args[0].(pgtype.Timestamptz).Time.After(time.Now().Add(-23 * time.Hour)) {
+		t.Errorf("purge cutoff %v is inside the grace period", args[0])
+	}
+}
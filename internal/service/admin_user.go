@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// UserView is a user account without its credentials.
+type UserView struct {
+	ID              string     `json:"id"`
+	FullName        string     `json:"full_name"`
+	Bio             string     `json:"bio,omitempty"`
+	Email           string     `json:"email"`
+	AvatarURL       string     `json:"avatar_url,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	IsActive        bool       `json:"is_active"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// UserDetail is what administrators see when they open one account.
+type UserDetail struct {
+	UserView
+	Roles    []string      `json:"roles"`
+	Devices  []DeviceItem  `json:"devices"`
+	Sessions []SessionItem `json:"sessions"`
+}
+
+// UserUpdate carries the editable profile fields; nil means unchanged.
+type UserUpdate struct {
+	FullName  *string
+	Bio       *string
+	AvatarURL *string
+	Email     *string
+}
+
+func newUserView(u *repository.UserModel) UserView {
+	return UserView{
+		ID:              u.ID.String(),
+		FullName:        u.FullName,
+		Bio:             u.Bio,
+		Email:           u.Email,
+		AvatarURL:       u.AvatarURL,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		IsActive:        u.IsActive,
+		DeletedAt:       u.DeletedAt,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+	}
+}
+
+// SearchUsers finds users by name. sortBy is one of
+// repository.UserSortColumns (default full_name), order is asc or desc.
+func (as *AuthService) SearchUsers(ctx context.Context, query, sortBy, order string, limit, offset int) ([]UserView, int64, error) {
+	if sortBy == "" {
+		sortBy = "full_name"
+	}
+	order = strings.ToLower(order)
+	if !slices.Contains(repository.UserSortColumns, sortBy) || (order != "" && order != "asc" && order != "desc") {
+		return nil, 0, enum.ErrInvalidSort
+	}
+	users, total, err := as.userRepo.SearchUsers(ctx, query, sortBy, order == "desc", limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	views := make([]UserView, 0, len(users))
+	for i := range users {
+		views = append(views, newUserView(&users[i]))
+	}
+	return views, total, nil
+}
+
+// GetUserDetail returns a user with roles, devices and live sessions.
+func (as *AuthService) GetUserDetail(ctx context.Context, userID uuid.UUID) (*UserDetail, error) {
+	u, err := as.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := as.rbacRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	devices, err := as.ListDevices(ctx, userID.String(), "")
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := as.ListSessions(ctx, userID.String(), "")
+	if err != nil {
+		return nil, err
+	}
+	return &UserDetail{UserView: newUserView(u), Roles: roles, Devices: devices, Sessions: sessions}, nil
+}
+
+// SetUserActive activates or deactivates an account. Deactivation logs the
+// user out everywhere. Administrators cannot deactivate themselves.
+func (as *AuthService) SetUserActive(ctx context.Context, userID uuid.UUID, active bool) error {
+	if !active && RequestInfoFrom(ctx).UserID == userID.String() {
+		return enum.ErrForbidden
+	}
+	if _, err := as.userRepo.GetUserByID(ctx, userID); err != nil {
+		return err
+	}
+	if err := as.userRepo.SetActive(ctx, userID, active); err != nil {
+		return err
+	}
+	as.dropUserActive(ctx, userID.String())
+	event := AuditUserActivate
+	if !active {
+		event = AuditUserDeactivate
+		if err := as.LogoutAll(ctx, userID.String()); err != nil {
+			return err
+		}
+	}
+	as.audit(ctx, AuditEvent{Type: event, SubjectID: userID.String()})
+	return nil
+}
+
+// UpdateUser edits profile fields on behalf of the user.
+func (as *AuthService) UpdateUser(ctx context.Context, userID uuid.UUID, upd UserUpdate) (*UserView, error) {
+	u, err := as.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	changed := []string{}
+	if upd.FullName != nil && *upd.FullName != u.FullName {
+		u.FullName = *upd.FullName
+		changed = append(changed, "full_name")
+	}
+	if upd.Bio != nil && *upd.Bio != u.Bio {
+		u.Bio = *upd.Bio
+		changed = append(changed, "bio")
+	}
+	if upd.AvatarURL != nil && *upd.AvatarURL != u.AvatarURL {
+		u.AvatarURL = *upd.AvatarURL
+		changed = append(changed, "avatar_url")
+	}
+	if upd.Email != nil {
+		email := repository.NormalizeEmail(*upd.Email)
+		upd.Email = &email
+	}
+	emailChanged := upd.Email != nil && *upd.Email != u.Email
+	if emailChanged {
+		if err := as.checkEmailFree(ctx, *upd.Email, userID); err != nil {
+			return nil, err
+		}
+		// the new address has not been proven yet
+		u.Email, u.EmailVerifiedAt = *upd.Email, nil
+		changed = append(changed, "email")
+	}
+	if len(changed) > 0 {
+		if u, err = as.userRepo.UpdateProfile(ctx, u); err != nil {
+			return nil, err
+		}
+		as.audit(ctx, AuditEvent{Type: AuditUserUpdate, SubjectID: userID.String(), Metadata: map[string]any{"fields": changed}})
+	}
+	if emailChanged {
+		if err := as.sendEmailVerification(ctx, userID.String(), u.Email); err != nil {
+			as.log.Warn("send_email_verification_failed", zap.String("user_id", userID.String()), zap.Error(err))
+		}
+	}
+	view := newUserView(u)
+	return &view, nil
+}
+
+// checkEmailFree returns enum.ErrEmailAlready when another account uses email.
+func (as *AuthService) checkEmailFree(ctx context.Context, email string, self uuid.UUID) error {
+	other, err := as.userRepo.GetUserByEmail(ctx, email)
+	if errors.Is(err, enum.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if other.ID != self {
+		return enum.ErrEmailAlready
+	}
+	return nil
+}
+
+// ForcePasswordReset invalidates the current password, logs the user out
+// everywhere and mails a reset link.
+func (as *AuthService) ForcePasswordReset(ctx context.Context, userID uuid.UUID) error {
+	u, err := as.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := as.userRepo.ClearPassword(ctx, userID); err != nil {
+		return err
+	}
+	if err := as.LogoutAll(ctx, userID.String()); err != nil {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditUserForceReset, SubjectID: userID.String()})
+	return as.sendPasswordReset(ctx, u)
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"slices"
+	"strings"
+	"time"
+
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// API token kinds. Personal access tokens stand in for the user's password
+// in scripts. Service API keys are meant for partner integrations; they
+// outlive password changes and logouts, so only administrators who just
+// logged in may create them and they need an explicit expiry.
+const (
+	APITokenPersonal = "personal"
+	APITokenService  = "service"
+)
+
+const (
+	// APITokenPrefix starts every API token, telling them apart from JWTs.
+	APITokenPrefix = "sbd_"
+
+	apiTokenLookupLen  = 8 // characters after the kind prefix stored as the lookup prefix
+	apiTokenDefaultTTL = 90 * 24 * time.Hour
+	apiTokenMaxTTL     = 365 * 24 * time.Hour
+	adminRole          = "admin"
+)
+
+// PermProfileRead is what an API token needs where a delegated token needs
+// the profile or openid scope.
+const PermProfileRead = "profile:read"
+
+// scopePermissions maps the OAuth scopes routes ask for to the permission an
+// API token must hold instead; API token scopes are permission names.
+var scopePermissions = map[string]string{
+	ScopeProfile: PermProfileRead,
+	ScopeOpenID:  PermProfileRead,
+	ScopeEmail:   PermProfileRead,
+}
+
+var apiTokenKindPrefix = map[string]string{
+	APITokenPersonal: APITokenPrefix + "pat_",
+	APITokenService:  APITokenPrefix + "svc_",
+}
+
+// APIToken is a token as shown to its owner; the secret is only returned
+// once, by CreateAPIToken.
+type APIToken struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewAPIToken is a freshly created token with its secret.
+type NewAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+// APITokenIdentity is who a request authenticated with an API token acts
+// as. Permissions are the user's, limited to the token's scopes; tokens
+// carry no roles, so role-gated routes stay closed to them.
+type APITokenIdentity struct {
+	UserID      string
+	TokenID     string
+	Scopes      []string
+	Permissions []string
+}
+
+// AllowsScopes reports whether the token may use a route that delegated
+// tokens need one of scopes for. Routes listing no scopes are left to their
+// permission checks.
+func (id *APITokenIdentity) AllowsScopes(scopes []string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, s := range scopes {
+		if p, ok := scopePermissions[s]; ok && slices.Contains(id.Permissions, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func newAPITokenView(m *repository.APITokenModel) APIToken {
+	return APIToken{
+		ID:         m.ID.String(),
+		Kind:       m.Kind,
+		Name:       m.Name,
+		Prefix:     m.Prefix,
+		Scopes:     m.Scopes,
+		ExpiresAt:  m.ExpiresAt,
+		LastUsedAt: m.LastUsedAt,
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+// IsAPIToken tells an API token from a JWT by its prefix.
+func IsAPIToken(raw string) bool { return strings.HasPrefix(raw, APITokenPrefix) }
+
+// apiTokenLookup returns the stored prefix of raw, or "" if raw is not
+// shaped like an API token.
+func apiTokenLookup(raw string) string {
+	for _, p := range apiTokenKindPrefix {
+		if strings.HasPrefix(raw, p) && len(raw) > len(p)+apiTokenLookupLen {
+			return raw[:len(p)+apiTokenLookupLen]
+		}
+	}
+	return ""
+}
+
+// userPermissions resolves the permissions of the user's current roles.
+func (as *AuthService) userPermissions(ctx context.Context, uid uuid.UUID) (roles, perms []string, err error) {
+	roles, err = as.rbacRepo.GetUserRoles(ctx, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	perms, err = as.PermissionsForRoles(ctx, roles)
+	return roles, perms, err
+}
+
+// CreateAPIToken issues a token for the user of session sid. Scopes are
+// permission names the user holds now; the token never gets more than the
+// user has when it is used either.
+func (as *AuthService) CreateAPIToken(ctx context.Context, userID, sid string, cmd model.APITokenCmd) (*NewAPIToken, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	if cmd.Kind == "" {
+		cmd.Kind = APITokenPersonal
+	}
+	prefix, ok := apiTokenKindPrefix[cmd.Kind]
+	if !ok {
+		return nil, enum.ErrAPITokenKind
+	}
+	roles, perms, err := as.userPermissions(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Kind == APITokenService {
+		if !slices.Contains(roles, adminRole) {
+			return nil, enum.ErrForbidden
+		}
+		if err := as.requireFreshAuth(ctx, userID, sid); err != nil {
+			return nil, err
+		}
+	}
+	scopes := slices.Compact(slices.Sorted(slices.Values(cmd.Scopes)))
+	if len(scopes) == 0 {
+		return nil, enum.ErrAPITokenScope
+	}
+	for _, s := range scopes {
+		if !slices.Contains(perms, s) {
+			return nil, enum.ErrAPITokenScope
+		}
+	}
+
+	switch {
+	case cmd.ExpiresIn < 0, cmd.ExpiresIn > apiTokenMaxTTL:
+		return nil, enum.ErrAPITokenExpiry
+	case cmd.ExpiresIn == 0 && cmd.Kind == APITokenService:
+		return nil, enum.ErrAPITokenExpiry
+	case cmd.ExpiresIn == 0:
+		cmd.ExpiresIn = apiTokenDefaultTTL
+	}
+	expiresAt := time.Now().UTC().Add(cmd.ExpiresIn)
+
+	token, err := randomToken(prefix, 32)
+	if err != nil {
+		return nil, err
+	}
+	m, err := as.apiTokenRepo.Create(ctx, repository.APITokenModel{
+		UserID:    uid,
+		Kind:      cmd.Kind,
+		Name:      strings.TrimSpace(cmd.Name),
+		Prefix:    apiTokenLookup(token),
+		TokenHash: hashToken(token),
+		Scopes:    scopes,
+		ExpiresAt: &expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	as.audit(ctx, AuditEvent{
+		Type:     AuditAPITokenCreate,
+		ActorID:  userID,
+		Metadata: map[string]any{"token_id": m.ID.String(), "kind": m.Kind, "scopes": scopes},
+	})
+	return &NewAPIToken{APIToken: newAPITokenView(m), Token: token}, nil
+}
+
+// AuthenticateAPIToken checks a presented token and returns the identity it
+// acts as. Unknown, mismatching and expired tokens all fail the same way.
+func (as *AuthService) AuthenticateAPIToken(ctx context.Context, raw string) (*APITokenIdentity, error) {
+	lookup := apiTokenLookup(raw)
+	if lookup == "" {
+		return nil, enum.ErrAPITokenInvalid
+	}
+	m, err := as.apiTokenRepo.GetByPrefix(ctx, lookup)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil || subtle.ConstantTimeCompare([]byte(hashToken(raw)), []byte(m.TokenHash)) != 1 {
+		return nil, enum.ErrAPITokenInvalid
+	}
+	if m.ExpiresAt != nil && time.Now().After(*m.ExpiresAt) {
+		return nil, enum.ErrAPITokenInvalid
+	}
+	userID := m.UserID.String()
+	if err := as.CheckUserActive(ctx, userID); err != nil {
+		return nil, err
+	}
+	_, perms, err := as.userPermissions(ctx, m.UserID)
+	if err != nil {
+		return nil, err
+	}
+	granted := make([]string, 0, len(m.Scopes))
+	for _, s := range m.Scopes {
+		if slices.Contains(perms, s) {
+			granted = append(granted, s)
+		}
+	}
+	if err := as.apiTokenRepo.Touch(ctx, m.ID); err != nil {
+		as.log.Warn("api_token_touch_failed", zap.String("token_id", m.ID.String()), zap.Error(err))
+	}
+	return &APITokenIdentity{
+		UserID:      userID,
+		TokenID:     m.ID.String(),
+		Scopes:      m.Scopes,
+		Permissions: granted,
+	}, nil
+}
+
+func (as *AuthService) ListAPITokens(ctx context.Context, userID string) ([]APIToken, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	tokens, err := as.apiTokenRepo.ListByUserID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]APIToken, 0, len(tokens))
+	for i := range tokens {
+		views = append(views, newAPITokenView(&tokens[i]))
+	}
+	return views, nil
+}
+
+func (as *AuthService) GetAPIToken(ctx context.Context, userID, tokenID string) (*APIToken, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return nil, enum.ErrAPITokenNotFound
+	}
+	m, err := as.apiTokenRepo.Get(ctx, uid, id)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, enum.ErrAPITokenNotFound
+	}
+	view := newAPITokenView(m)
+	return &view, nil
+}
+
+func (as *AuthService) RenameAPIToken(ctx context.Context, userID, tokenID, name string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return enum.ErrUserNotFound
+	}
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return enum.ErrAPITokenNotFound
+	}
+	ok, err := as.apiTokenRepo.Rename(ctx, uid, id, strings.TrimSpace(name))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return enum.ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// DeleteAPIToken revokes a token; requests using it fail from then on.
+func (as *AuthService) DeleteAPIToken(ctx context.Context, userID, tokenID string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return enum.ErrUserNotFound
+	}
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return enum.ErrAPITokenNotFound
+	}
+	ok, err := as.apiTokenRepo.Delete(ctx, uid, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return enum.ErrAPITokenNotFound
+	}
+	as.audit(ctx, AuditEvent{
+		Type:     AuditAPITokenDelete,
+		ActorID:  userID,
+		Metadata: map[string]any{"token_id": tokenID},
+	})
+	return nil
+}
+
+// revokePersonalTokens deletes the user's personal access tokens. They stand
+// in for the password, so they go wherever the password or every session
+// goes; service API keys belong to integrations and are left alone, which
+// is why creating one takes a fresh login.
+func (as *AuthService) revokePersonalTokens(ctx context.Context, userID string) (int64, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, enum.ErrUserNotFound
+	}
+	return as.apiTokenRepo.DeleteByKind(ctx, uid, APITokenPersonal)
+}
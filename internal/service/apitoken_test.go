@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"seno-blackdragon/internal/db/apitoken"
+	"seno-blackdragon/internal/db/dbtest"
+	"seno-blackdragon/internal/db/user"
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestAPITokenLookup(t *testing.T) {
+	tok, err := randomToken(apiTokenKindPrefix[APITokenPersonal], 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsAPIToken(tok) {
+		t.Fatalf("Expected %q to be an API token", tok)
+	}
+	if got := apiTokenLookup(tok); got != tok[:len("sbd_pat_")+apiTokenLookupLen] {
+		t.Errorf("Unexpected lookup prefix %q", got)
+	}
+	for _, raw := range []string{"sbd_pat_short", "sbd_xyz_0123456789", "eyJhbGciOiJSUzI1NiJ9.e30.sig"} {
+		if got := apiTokenLookup(raw); got != "" {
+			t.Errorf("Expected no lookup prefix for %q, got %q", raw, got)
+		}
+	}
+}
+
+func TestPersonalTokensRevoked(t *testing.T) {
+	const current, next = "Curr3nt-Passw0rd!", "N3w-Passw0rd!long"
+	cases := []struct {
+		name string
+		run  func(ctx context.Context, as *AuthService, uid uuid.UUID) error
+	}{
+		{"logout everywhere", func(ctx context.Context, as *AuthService, uid uuid.UUID) error {
+			return as.LogoutAll(ctx, uid.String())
+		}},
+		{"password change", func(ctx context.Context, as *AuthService, uid uuid.UUID) error {
+			return as.ChangePassword(ctx, uid.String(), "SID_CURRENT", current, next)
+		}},
+		{"password reset", func(ctx context.Context, as *AuthService, uid uuid.UUID) error {
+			as.redis.Set(ctx, keys.PasswordReset(hashToken("rst_token")), uid.String(), 0)
+			return as.ResetPassword(ctx, "rst_token", next)
+		}},
+		{"forced reset", func(ctx context.Context, as *AuthService, uid uuid.UUID) error {
+			return as.ForcePasswordReset(ctx, uid)
+		}},
+		{"refresh token reuse", func(ctx context.Context, as *AuthService, uid uuid.UUID) error {
+			as.handleRefreshReuse(ctx, &RefreshClaims{DeviceID: "DEV_1", Fam: "FAM_1", RegisteredClaims: jwt.RegisteredClaims{Subject: uid.String()}})
+			return nil
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			uid := uuid.New()
+			db := dbtest.New()
+			as, _, _ := newTestService(t, db, Policy{})
+			hash, err := as.hasher.Hash(current)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db.On("GetUserByID", dbtest.Row(user.User{
+				ID:           utils.PgUUIDFromUUID(uid),
+				Email:        pgtype.Text{String: testEmail, Valid: true},
+				PasswordHash: pgtype.Text{String: hash, Valid: true},
+				IsActive:     true,
+			}))
+
+			if err := tc.run(context.Background(), as, uid); err != nil {
+				t.Fatal(err)
+			}
+			calls := db.Calls("DeleteAPITokensByKind")
+			if len(calls) != 1 {
+				t.Fatalf("personal tokens deleted %d times, want once", len(calls))
+			}
+			if got := calls[0].Args; got[0] != utils.PgUUIDFromUUID(uid) || got[1] != APITokenPersonal {
+				t.Errorf("deleted tokens %v, want the user's personal ones", got)
+			}
+			if n := len(db.Calls("DeleteAPITokens")); n != 0 {
+				t.Errorf("service API keys deleted too")
+			}
+		})
+	}
+}
+
+func TestCreateServiceKey(t *testing.T) {
+	uid := uuid.New()
+	cases := []struct {
+		name      string
+		roles     []any
+		loggedIn  time.Duration // how long ago the session authenticated
+		expiresIn time.Duration
+		want      error
+	}{
+		{"fresh admin", []any{adminRole}, time.Minute, 30 * 24 * time.Hour, nil},
+		{"not an admin", []any{"user"}, time.Minute, 30 * 24 * time.Hour, enum.ErrForbidden},
+		{"stale login", []any{adminRole}, time.Hour, 30 * 24 * time.Hour, enum.ErrReauthRequired},
+		{"no expiry", []any{adminRole}, time.Minute, 0, enum.ErrAPITokenExpiry},
+		{"expiry over the limit", []any{adminRole}, time.Minute, apiTokenMaxTTL + time.Hour, enum.ErrAPITokenExpiry},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rows := make([][]any, len(tc.roles))
+			for i, r := range tc.roles {
+				rows[i] = dbtest.Value(r)
+			}
+			db := dbtest.New().
+				On("ListUserRoles", rows...).
+				On("ListPermissionsByRoles", dbtest.Value("user:read")).
+				On("CreateAPIToken", dbtest.Row(apitoken.ApiToken{
+					ID:     utils.PgUUIDFromUUID(uuid.New()),
+					UserID: utils.PgUUIDFromUUID(uid),
+					Kind:   APITokenService,
+					Scopes: []string{"user:read"},
+				}))
+			as, _, _ := newTestService(t, db, Policy{})
+			ctx := context.Background()
+			sess := &Session{UserID: uid.String(), Status: model.Active, AuthTime: time.Now().Add(-tc.loggedIn).Unix()}
+			if err := as.SaveSession(ctx, "SID_1", sess, 3600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := as.CreateAPIToken(ctx, uid.String(), "SID_1", model.APITokenCmd{
+				Kind:      APITokenService,
+				Name:      "partner",
+				Scopes:    []string{"user:read"},
+				ExpiresIn: tc.expiresIn,
+			})
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got %v, want %v", err, tc.want)
+			}
+			created := len(db.Calls("CreateAPIToken"))
+			if (tc.want == nil) != (created == 1) {
+				t.Errorf("created %d tokens", created)
+			}
+		})
+	}
+}
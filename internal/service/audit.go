@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"seno-blackdragon/internal/repository"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Audit event types, stored as-is and used by the "type" filter.
+const (
+	AuditLoginSuccess       = "login.success"
+	AuditLoginFailure       = "login.failure"
+	AuditLoginLockout       = "login.lockout"
+	AuditRefreshReuse       = "refresh.reuse"
+	AuditLogout             = "logout"
+	AuditLogoutDevice       = "logout.device"
+	AuditLogoutAll          = "logout.all"
+	AuditSessionRevoke      = "session.revoke"
+	AuditPasswordChange     = "password.change"
+	AuditPasswordReset      = "password.reset"
+	AuditDeviceBlock        = "device.block"
+	AuditEmailVerified      = "email.verified"
+	AuditEmailChangeRequest = "email.change_request"
+	AuditEmailChange        = "email.change"
+	AuditMFAEnabled         = "mfa.enabled"
+	AuditRoleAssign         = "role.assign"
+	AuditRoleRevoke         = "role.revoke"
+	AuditIPBan              = "ip.ban"
+	AuditUserActivate       = "user.activate"
+	AuditUserDeactivate     = "user.deactivate"
+	AuditUserUpdate         = "user.update"
+	AuditUserForceReset     = "user.force_password_reset"
+	AuditAccountDelete      = "account.delete"
+	AuditAccountRestore     = "account.restore"
+	AuditAccountPurge       = "account.purge"
+	AuditOAuthClientCreate  = "oauth_client.create"
+	AuditOAuthClientDelete  = "oauth_client.delete"
+	AuditOAuthAuthorize     = "oauth.authorize"
+	AuditOAuthToken         = "oauth.token"
+	AuditOAuthCodeReuse     = "oauth.code_reuse"
+	AuditOAuthRevoke        = "oauth.revoke"
+	AuditIdentityLink       = "identity.link"
+	AuditIdentityUnlink     = "identity.unlink"
+	AuditPasskeyRegister    = "passkey.register"
+	AuditPasskeyDelete      = "passkey.delete"
+	AuditAPITokenCreate     = "api_token.create"
+	AuditAPITokenDelete     = "api_token.delete"
+
+	auditQueueSize    = 1024
+	auditWriteTimeout = 5 * time.Second
+)
+
+// RequestInfo describes the caller of a service method. The HTTP layer puts
+// it on the request context so audit events pick up IP, user agent and the
+// authenticated identity without threading them through every signature.
+type RequestInfo struct {
+	IP        string
+	UA        string
+	UserID    string
+	DeviceID  string
+	SessionID string
+}
+
+type requestInfoKey struct{}
+
+func WithRequestInfo(ctx context.Context, ri RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, ri)
+}
+
+func RequestInfoFrom(ctx context.Context) RequestInfo {
+	ri, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return ri
+}
+
+// AuditEvent is what the auth flows record. Empty fields are filled from the
+// RequestInfo on the context; SubjectID defaults to the actor.
+type AuditEvent struct {
+	Type      string
+	ActorID   string
+	SubjectID string
+	IP        string
+	UA        string
+	DeviceID  string
+	SessionID string
+	Metadata  map[string]any
+}
+
+// auditWriter persists events on a single background goroutine so the auth
+// path never waits for Postgres. When the queue is full events are dropped
+// with a warning rather than blocking a login. close drains the queue.
+type auditWriter struct {
+	repo  *repository.AuditRepo
+	log   *zap.Logger
+	queue chan repository.AuditEventModel
+	done  chan struct{} // closed when run returns
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newAuditWriter(repo *repository.AuditRepo, log *zap.Logger) *auditWriter {
+	if repo == nil {
+		return nil
+	}
+	w := &auditWriter{
+		repo:  repo,
+		log:   log,
+		queue: make(chan repository.AuditEventModel, auditQueueSize),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *auditWriter) run() {
+	defer close(w.done)
+	for ev := range w.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+		if err := w.repo.Insert(ctx, ev); err != nil {
+			w.log.Warn("audit_event_write_failed", zap.String("type", ev.Type), zap.Error(err))
+		}
+		cancel()
+	}
+}
+
+func (w *auditWriter) enqueue(ev repository.AuditEventModel) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		w.log.Warn("audit_event_after_close", zap.String("type", ev.Type), zap.String("subject_id", ev.SubjectID.String()))
+		return
+	}
+	select {
+	case w.queue <- ev:
+	default:
+		w.log.Warn("audit_event_dropped", zap.String("type", ev.Type), zap.String("subject_id", ev.SubjectID.String()))
+	}
+}
+
+// close stops taking events and waits until the queued ones are written or
+// ctx is done.
+func (w *auditWriter) close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.log.Warn("audit_queue_not_drained", zap.Int("pending", len(w.queue)))
+		return ctx.Err()
+	}
+}
+
+// Close flushes the audit events still queued. Call it once the server has
+// stopped taking requests; events recorded afterwards are dropped.
+func (as *AuthService) Close(ctx context.Context) error {
+	if as.auditLog == nil {
+		return nil
+	}
+	return as.auditLog.close(ctx)
+}
+
+// audit records a security event asynchronously.
+func (as *AuthService) audit(ctx context.Context, ev AuditEvent) {
+	if as.auditLog == nil {
+		return
+	}
+	ri := RequestInfoFrom(ctx)
+	if ev.ActorID == "" {
+		ev.ActorID = ri.UserID
+	}
+	if ev.SubjectID == "" {
+		ev.SubjectID = ev.ActorID
+	}
+	if ev.IP == "" {
+		ev.IP = ri.IP
+	}
+	if ev.UA == "" {
+		ev.UA = ri.UA
+	}
+	if ev.DeviceID == "" && ev.SubjectID == ri.UserID {
+		ev.DeviceID = ri.DeviceID
+	}
+	if ev.SessionID == "" && ev.SubjectID == ri.UserID {
+		ev.SessionID = ri.SessionID
+	}
+	actor, _ := uuid.Parse(ev.ActorID)
+	subject, _ := uuid.Parse(ev.SubjectID)
+	as.auditLog.enqueue(repository.AuditEventModel{
+		Type:      ev.Type,
+		ActorID:   actor,
+		SubjectID: subject,
+		IP:        ev.IP,
+		UserAgent: ev.UA,
+		DeviceID:  ev.DeviceID,
+		SessionID: ev.SessionID,
+		Metadata:  ev.Metadata,
+	})
+}
+
+// AuditFilter narrows ListAuditEvents; zero values match everything.
+type AuditFilter = repository.AuditFilter
+
+// AuditRecord is a stored audit event as returned by the API.
+type AuditRecord struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	ActorID   string         `json:"actor_id,omitempty"`
+	SubjectID string         `json:"subject_id,omitempty"`
+	IP        string         `json:"ip,omitempty"`
+	UserAgent string         `json:"user_agent,omitempty"`
+	DeviceID  string         `json:"device_id,omitempty"`
+	SessionID string         `json:"session_id,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// ListAuditEvents returns one page of the audit trail, newest first, and the
+// total number of events matching the filter.
+func (as *AuthService) ListAuditEvents(ctx context.Context, f AuditFilter, limit, offset int) ([]AuditRecord, int64, error) {
+	events, total, err := as.auditRepo.ListEvents(ctx, f, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	records := make([]AuditRecord, 0, len(events))
+	for _, ev := range events {
+		rec := AuditRecord{
+			ID:        ev.ID.String(),
+			Type:      ev.Type,
+			IP:        ev.IP,
+			UserAgent: ev.UserAgent,
+			DeviceID:  ev.DeviceID,
+			SessionID: ev.SessionID,
+			Metadata:  ev.Metadata,
+			CreatedAt: ev.CreatedAt,
+		}
+		if ev.ActorID != uuid.Nil {
+			rec.ActorID = ev.ActorID.String()
+		}
+		if ev.SubjectID != uuid.Nil {
+			rec.SubjectID = ev.SubjectID.String()
+		}
+		records = append(records, rec)
+	}
+	return records, total, nil
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"seno-blackdragon/internal/db/dbtest"
+	"seno-blackdragon/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+func TestAuditFillsFromRequestInfo(t *testing.T) {
+	w := &auditWriter{log: zap.NewNop(), queue: make(chan repository.AuditEventModel, 1)}
+	as := &AuthService{auditLog: w}
+	admin := "0b7e2f9c-4d1a-4c55-9a51-1f7f5b0e6a01"
+	target := "6a3c1d2e-8f4b-4e2a-b1c0-9d8e7f6a5b4c"
+	ctx := WithRequestInfo(context.Background(), RequestInfo{
+		IP: "10.0.0.1", UA: "curl/8", UserID: admin, DeviceID: "DEV_A", SessionID: "SID_A",
+	})
+
+	as.audit(ctx, AuditEvent{Type: AuditRoleAssign, SubjectID: target})
+	ev := <-w.queue
+	if ev.ActorID.String() != admin || ev.SubjectID.String() != target || ev.IP != "10.0.0.1" || ev.UserAgent != "curl/8" {
+		t.Errorf("Unexpected event %+v", ev)
+	}
+	if ev.DeviceID != "" || ev.SessionID != "" {
+		t.Errorf("Expected the admin's device and session to stay off another user's event, got %q %q", ev.DeviceID, ev.SessionID)
+	}
+
+	as.audit(ctx, AuditEvent{Type: AuditLogout})
+	ev = <-w.queue
+	if ev.SubjectID.String() != admin || ev.SessionID != "SID_A" {
+		t.Errorf("Expected subject and session to default to the caller, got %+v", ev)
+	}
+
+	// a full queue drops instead of blocking the auth path
+	as.audit(ctx, AuditEvent{Type: AuditLogout})
+	as.audit(ctx, AuditEvent{Type: AuditLogout})
+	if len(w.queue) != 1 {
+		t.Errorf("Expected one queued event, got %d", len(w.queue))
+	}
+}
+
+func TestAuditWriterCloseFlushes(t *testing.T) {
+	db := dbtest.New()
+	w := newAuditWriter(repository.NewAuditRepo(db), zap.NewNop())
+	as := &AuthService{auditLog: w}
+	ctx := context.Background()
+	const n = 50
+	for range n {
+		as.audit(ctx, AuditEvent{Type: AuditLogout, ActorID: "6a3c1d2e-8f4b-4e2a-b1c0-9d8e7f6a5b4c"})
+	}
+
+	closeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := as.Close(closeCtx); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(db.Calls("InsertAuditEvent")); got != n {
+		t.Fatalf("Expected %d events written by Close, got %d", n, got)
+	}
+
+	// late events are dropped, not sent on the closed queue
+	as.audit(ctx, AuditEvent{Type: AuditLogout})
+	if err := as.Close(closeCtx); err != nil {
+		t.Errorf("Expected a second Close to succeed, got %v", err)
+	}
+	if got := len(db.Calls("InsertAuditEvent")); got != n {
+		t.Errorf("Expected no writes after Close, got %d", got-n)
+	}
+}
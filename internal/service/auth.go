@@ -2,16 +2,25 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"seno-blackdragon/internal/keys"
 	"seno-blackdragon/internal/model"
 	"seno-blackdragon/internal/repository"
 	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/keyring"
+	"seno-blackdragon/pkg/notify"
+	"seno-blackdragon/pkg/oidcclient"
 	"seno-blackdragon/pkg/pass"
+	"seno-blackdragon/pkg/webauthn"
 
 	cryptoRand "crypto/rand"
 
@@ -25,9 +34,31 @@ import (
 type JWTConfig struct {
 	AccessSecret  []byte
 	RefreshSecret []byte
-	AccessTTL     time.Duration // e.g. 15 * time.Minute
-	RefreshTTL    time.Duration // e.g. 30 * 24 * time.Hour
-	Issuer        string        // e.g. "seno-blackdragon"
+	// AccessKeys/RefreshKeys sign and verify tokens by kid. When nil they
+	// fall back to HS256 keyrings built from the secrets above.
+	AccessKeys  *keyring.Keyring
+	RefreshKeys *keyring.Keyring
+	AccessTTL   time.Duration // e.g. 15 * time.Minute
+	RefreshTTL  time.Duration // e.g. 30 * 24 * time.Hour
+	Issuer      string        // e.g. "seno-blackdragon"
+	// OIDCIssuer is the public URL of the server, the iss of ID tokens and
+	// the base of the OpenID Connect discovery document.
+	OIDCIssuer string
+}
+
+// Policy holds the account rules that vary per deployment.
+type Policy struct {
+	EmailVerification string // EmailPolicyBlock | EmailPolicyRestrict
+	Lockout           LockoutPolicy
+	DeletionGrace     time.Duration // deleted accounts are purged after this
+	// SocialProviders are the external OpenID Connect providers users can
+	// log in with, by name.
+	SocialProviders map[string]*oidcclient.Provider
+	// WebAuthn is the relying party passkeys are registered with.
+	WebAuthn webauthn.RelyingParty
+	// PasswordlessSignup lets an email login code create the account on
+	// first use.
+	PasswordlessSignup bool
 }
 
 type AccessClaims struct {
@@ -35,12 +66,16 @@ type AccessClaims struct {
 	TokenType string   `json:"typ"` // "access" | "refresh"
 	DeviceID  string   `json:"did"`
 	SessionID string   `json:"sid"`
+	Uv        int      `json:"uv"` // user version; a bump invalidates the token
 	Roles     []string `json:"roles,omitempty"`
+	Scope     string   `json:"scope,omitempty"`     // space separated, e.g. ScopeUnverified
+	ClientID  string   `json:"client_id,omitempty"` // OAuth client the token was issued to
 	jwt.RegisteredClaims
 }
 
 type RefreshClaims struct {
 	DeviceID  string `json:"did"`
+	SessionID string `json:"sid"`
 	Uv        int    `json:"uv"`
 	Fam       string `json:"fam"`
 	TokenType string `json:"typ"` // "access" | "refresh"
@@ -56,8 +91,28 @@ type Session struct {
 	LastSeen  string   `json:"last_seen"`
 	Exp       string   `json:"exp"`
 	Scopes    []string `json:"scopes,omitempty"`
+	Fam       string   `json:"fam,omitempty"`
 	MFA       bool     `json:"mfa"`
-	Status    string   `json:"status"` // active | revoked
+	Status    string   `json:"status"`              // active | revoked
+	ClientID  string   `json:"client_id,omitempty"` // set for sessions opened through OAuth
+	Grant     []string `json:"grant,omitempty"`     // OAuth scopes granted to ClientID
+	Delegated bool     `json:"delegated,omitempty"` // third-party client, see sessionGrant
+	AuthTime  int64    `json:"auth_time,omitempty"` // unix time the user authenticated
+	AMR       []string `json:"amr,omitempty"`       // authentication methods (RFC 8176)
+}
+
+// sessionGrant describes how a session was obtained. Sessions of third-party
+// OAuth clients are delegated: their access tokens carry no roles and are
+// only accepted on routes that take one of the granted scopes.
+type sessionGrant struct {
+	MFA       bool // a second factor was verified
+	ClientID  string
+	Scopes    []string
+	Delegated bool
+	// AMR and AuthTime describe how and when the user authenticated; OAuth
+	// sessions inherit them from the session that approved the client.
+	AMR      []string
+	AuthTime int64 // defaults to now
 }
 
 type Device struct {
@@ -74,26 +129,68 @@ type Device struct {
 }
 
 type AuthService struct {
-	userRepo *repository.UserRepo // interface
-	hasher   pass.Hasher          // Argon2id/Bcrypt impl
-	jwtCfg   JWTConfig
-	redis    *redis.Client
-	log      *zap.Logger
+	userRepo     *repository.UserRepo // interface
+	mfaRepo      *repository.MFARepo
+	rbacRepo     *repository.RBACRepo
+	auditRepo    *repository.AuditRepo
+	oauthRepo    *repository.OAuthRepo
+	identityRepo *repository.IdentityRepo
+	passkeyRepo  *repository.PasskeyRepo
+	apiTokenRepo *repository.APITokenRepo
+	auditLog     *auditWriter
+	hasher       pass.Hasher // target for new hashes
+	argon2id     pass.Hasher // verifiers picked by pass.Detect
+	bcrypt       pass.Hasher
+	jwtCfg       JWTConfig
+	policy       Policy
+	redis        *redis.Client
+	notifier     notify.Notifier
+	ipACL        ipACLCache
+	log          *zap.Logger
 }
 
 func NewAuthService(
 	userRepo *repository.UserRepo,
-	hasher pass.Hasher,
+	mfaRepo *repository.MFARepo,
+	rbacRepo *repository.RBACRepo,
+	auditRepo *repository.AuditRepo,
+	oauthRepo *repository.OAuthRepo,
+	identityRepo *repository.IdentityRepo,
+	passkeyRepo *repository.PasskeyRepo,
+	apiTokenRepo *repository.APITokenRepo,
+	passCfg pass.Config,
 	redis *redis.Client,
+	notifier notify.Notifier,
 	jwtCfg JWTConfig,
+	policy Policy,
 	log *zap.Logger,
 ) *AuthService {
+	if jwtCfg.AccessKeys == nil {
+		jwtCfg.AccessKeys = keyring.NewHMAC("hs-access", jwtCfg.AccessSecret)
+	}
+	if jwtCfg.RefreshKeys == nil {
+		jwtCfg.RefreshKeys = keyring.NewHMAC("hs-refresh", jwtCfg.RefreshSecret)
+	}
+	argonCfg, bcryptCfg := passCfg, passCfg
+	argonCfg.Algo, bcryptCfg.Algo = pass.AlgoArgon2id, pass.AlgoBcrypt
 	return &AuthService{
-		userRepo: userRepo,
-		hasher:   hasher,
-		jwtCfg:   jwtCfg,
-		log:      log,
-		redis:    redis,
+		userRepo:     userRepo,
+		mfaRepo:      mfaRepo,
+		rbacRepo:     rbacRepo,
+		auditRepo:    auditRepo,
+		oauthRepo:    oauthRepo,
+		identityRepo: identityRepo,
+		passkeyRepo:  passkeyRepo,
+		apiTokenRepo: apiTokenRepo,
+		auditLog:     newAuditWriter(auditRepo, log),
+		hasher:       pass.New(passCfg),
+		argon2id:     pass.New(argonCfg),
+		bcrypt:       pass.New(bcryptCfg),
+		jwtCfg:       jwtCfg,
+		policy:       policy,
+		log:          log,
+		redis:        redis,
+		notifier:     notifier,
 	}
 }
 
@@ -105,6 +202,22 @@ func newID(prefix string) string {
 	entropy := ulid.Monotonic(cryptoRand.Reader, 0)
 	return prefix + ulid.MustNew(ulid.Timestamp(time.Now()), entropy).String()
 }
+
+// randomToken returns n random bytes, base64url encoded, prefixed.
+func randomToken(prefix string, n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := cryptoRand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is used for high-entropy secrets (reset tokens, recovery codes)
+// where a fast hash is enough and lookups must be deterministic.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
 func (as *AuthService) EnsureUserVersion(ctx context.Context, userID string) (int, error) {
 	key := keys.UserVer(userID)
 	v, err := as.redis.Get(ctx, key).Int()
@@ -117,7 +230,7 @@ func (as *AuthService) EnsureUserVersion(ctx context.Context, userID string) (in
 		}
 		return 1, nil
 	}
-	return 1, nil
+	return v, nil
 }
 
 func (as *AuthService) GetUserVersion(ctx context.Context, userID string) (int, error) {
@@ -129,6 +242,19 @@ func (as *AuthService) GetUserVersion(ctx context.Context, userID string) (int,
 	return v, err
 }
 
+// CheckUserVersion rejects tokens minted before the user version was bumped
+// by a logout everywhere or a refresh token reuse.
+func (as *AuthService) CheckUserVersion(ctx context.Context, userID string, uv int) error {
+	cur, err := as.GetUserVersion(ctx, userID)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	if cur != uv {
+		return enum.ErrInvalidToken
+	}
+	return nil
+}
+
 func (as *AuthService) SaveDevice(ctx context.Context, d *Device) error {
 	b, _ := json.Marshal(d)
 	pipe := as.redis.TxPipeline()
@@ -155,7 +281,7 @@ func (as *AuthService) GetDevice(ctx context.Context, deviceID string) (*Device,
 
 // ===== token helpers =====
 
-func (as *AuthService) makeAccessToken(u *repository.UserModel, jti, sessionID, deviceID string, roles []string) (string, time.Time, error) {
+func (as *AuthService) makeAccessToken(u *repository.UserModel, jti, sessionID, deviceID string, uv int, clientID string, roles, scopes []string) (string, time.Time, error) {
 	now := time.Now().UTC()
 	exp := now.Add(as.jwtCfg.AccessTTL)
 	claims := &AccessClaims{
@@ -163,6 +289,10 @@ func (as *AuthService) makeAccessToken(u *repository.UserModel, jti, sessionID,
 		TokenType: "access",
 		SessionID: sessionID,
 		DeviceID:  deviceID,
+		Uv:        uv,
+		Roles:     roles,
+		Scope:     strings.Join(scopes, " "),
+		ClientID:  clientID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    as.jwtCfg.Issuer,
 			Subject:   u.ID.String(),
@@ -171,18 +301,19 @@ func (as *AuthService) makeAccessToken(u *repository.UserModel, jti, sessionID,
 			ID:        jti,
 		},
 	}
-	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := tok.SignedString(as.jwtCfg.AccessSecret)
+	ss, err := as.jwtCfg.AccessKeys.Sign(claims)
 	return ss, exp, err
 }
 
-func (as *AuthService) makeRefreshToken(u *repository.UserModel, jti, deviceID, fam string, uv int) (string, time.Time, error) {
+func (as *AuthService) makeRefreshToken(u *repository.UserModel, jti, deviceID, fam, sessionID string, uv int) (string, time.Time, error) {
 	now := time.Now().UTC()
-	exp := now.Add(time.Duration(as.jwtCfg.RefreshTTL) * time.Second)
+	exp := now.Add(as.jwtCfg.RefreshTTL)
 	claims := &RefreshClaims{
-		DeviceID: deviceID,
-		Fam:      fam,
-		Uv:       uv,
+		DeviceID:  deviceID,
+		SessionID: sessionID,
+		Fam:       fam,
+		Uv:        uv,
+		TokenType: "refresh",
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    as.jwtCfg.Issuer,
 			Subject:   u.ID.String(),
@@ -191,11 +322,54 @@ func (as *AuthService) makeRefreshToken(u *repository.UserModel, jti, deviceID,
 			ID:        jti,
 		},
 	}
-	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := tok.SignedString(as.jwtCfg.RefreshSecret)
+	ss, err := as.jwtCfg.RefreshKeys.Sign(claims)
 	return ss, exp, err
 }
 
+// JWKS returns the public keys that verify access tokens. It is empty while
+// the service signs with HS256 secrets.
+func (as *AuthService) JWKS() keyring.JWKSet {
+	return as.jwtCfg.AccessKeys.JWKS()
+}
+
+// ParseAccessToken verifies signature, algorithm, issuer and expiry of an access
+// token and makes sure it was not a refresh token presented as an access token.
+func (as *AuthService) ParseAccessToken(accessToken string) (*AccessClaims, error) {
+	claims, err := as.parseAccessClaims(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" || claims.SessionID == "" {
+		return nil, enum.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// parseAccessClaims is ParseAccessToken without the session requirement, so
+// it also accepts client credentials tokens.
+func (as *AuthService) parseAccessClaims(accessToken string) (*AccessClaims, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(as.jwtCfg.AccessKeys.Algs()),
+		jwt.WithIssuer(as.jwtCfg.Issuer),
+		jwt.WithExpirationRequired(),
+	)
+	claims := &AccessClaims{}
+	tok, err := parser.ParseWithClaims(accessToken, claims, as.jwtCfg.AccessKeys.Keyfunc)
+	if err != nil {
+		if errors.Is(err, keyring.ErrAlgMismatch) || (tok != nil && tok.Method != nil && !slices.Contains(as.jwtCfg.AccessKeys.Algs(), tok.Method.Alg())) {
+			return nil, enum.ErrWrongAlgorithm
+		}
+		return nil, enum.ErrInvalidToken
+	}
+	if !tok.Valid {
+		return nil, enum.ErrInvalidToken
+	}
+	if claims.TokenType != "access" {
+		return nil, enum.ErrWrongType
+	}
+	return claims, nil
+}
+
 func (as *AuthService) SaveSession(ctx context.Context, sid string, s *Session, ttlSec int) error {
 	b, _ := json.Marshal(s)
 	pipe := as.redis.TxPipeline()
@@ -223,38 +397,50 @@ func (as *AuthService) GetSession(ctx context.Context, sid string) (*Session, er
 func (as *AuthService) DelSession(ctx context.Context, userID, sid string) error {
 	pipe := as.redis.TxPipeline()
 	pipe.Del(ctx, keys.Session(sid))
-	pipe.SRem(ctx, keys.UserSession(userID))
+	pipe.SRem(ctx, keys.UserSession(userID), sid)
 	_, err := pipe.Exec(ctx)
 	return err
 }
 
+// LogoutAll bumps the user version, which invalidates every refresh token
+// issued so far, drops all sessions so access tokens die immediately, and
+// deletes the user's personal access tokens.
 func (as *AuthService) LogoutAll(ctx context.Context, userID string) error {
-	if err := as.redis.Incr(ctx, keys.UserSession(userID)).Err(); err != nil {
+	if err := as.redis.Incr(ctx, keys.UserVer(userID)).Err(); err != nil {
 		return err
 	}
-	sids, _ := as.redis.SMembers(ctx, keys.UserSession(userID)).Result()
-	if len(sids) > 0 {
-		pipe := as.redis.TxPipeline()
-		for _, sid := range sids {
-			pipe.Del(ctx, keys.Session(sid))
-			pipe.Del(ctx, keys.UserSession(userID))
-		}
-		_, _ = pipe.Exec(ctx)
+	sids, err := as.redis.SMembers(ctx, keys.UserSession(userID)).Result()
+	if err != nil {
+		return err
+	}
+	pipe := as.redis.TxPipeline()
+	for _, sid := range sids {
+		pipe.Del(ctx, keys.Session(sid))
+	}
+	pipe.Del(ctx, keys.UserSession(userID))
+	if _, err = pipe.Exec(ctx); err != nil {
+		return err
 	}
+	tokens, err := as.revokePersonalTokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditLogoutAll, SubjectID: userID, Metadata: map[string]any{"sessions": len(sids), "api_tokens": tokens}})
 	return nil
 }
 
 // ===== auth flows =====
 
 func (as *AuthService) Register(ctx context.Context, fullName string, bio string, email string, password string) (uuid.UUID, error) {
-	u, err := as.userRepo.GetUserByEmail(ctx, email)
-	if err == nil && !errors.Is(err, enum.ErrUserNotFound) {
-		return uuid.Nil, fmt.Errorf("failed to check existing email: %w", err)
-	}
-
-	if u != nil {
+	email = repository.NormalizeEmail(email)
+	// fast path only; the unique index on lower(email) settles races
+	_, err := as.userRepo.GetUserByEmail(ctx, email)
+	if err == nil {
 		return uuid.Nil, enum.ErrEmailAlready
 	}
+	if !errors.Is(err, enum.ErrUserNotFound) {
+		return uuid.Nil, fmt.Errorf("failed to check existing email: %w", err)
+	}
 	hashed, err := as.hasher.Hash(password)
 	if err != nil {
 		return uuid.Nil, err
@@ -269,23 +455,111 @@ func (as *AuthService) Register(ctx context.Context, fullName string, bio string
 	if err != nil {
 		return uuid.Nil, err
 	}
+	if err := as.rbacRepo.AssignRole(ctx, id, DefaultRole); err != nil {
+		as.log.Warn("assign_default_role_failed", zap.String("user_id", id.String()), zap.Error(err))
+	}
+	if err := as.sendEmailVerification(ctx, id.String(), email); err != nil {
+		as.log.Warn("send_email_verification_failed", zap.String("user_id", id.String()), zap.Error(err))
+	}
 	return id, nil
 }
 
-func (as *AuthService) Login(ctx context.Context, cmd model.LoginCmd) (*model.TokenPair, error) {
+func (as *AuthService) Login(ctx context.Context, cmd model.LoginCmd) (*model.LoginResult, error) {
+	if err := as.checkLockout(ctx, cmd.Email, cmd.IP); err != nil {
+		return nil, err
+	}
 	u, err := as.userRepo.GetUserByEmail(ctx, cmd.Email)
 	if err != nil || u == nil {
+		as.recordLoginFailure(ctx, cmd.Email, cmd.IP)
+		as.auditLoginFailure(ctx, cmd, "", "unknown_email")
 		return nil, enum.ErrInvalidCredentials
 	}
-	ok, _ := as.hasher.Verify(cmd.Password, u.PasswordHash)
-	if !ok {
+	if !as.checkPassword(ctx, u, cmd.Password) {
+		as.recordLoginFailure(ctx, cmd.Email, cmd.IP)
+		as.auditLoginFailure(ctx, cmd, u.ID.String(), "bad_password")
 		return nil, enum.ErrInvalidCredentials
 	}
+	as.clearLoginFailures(ctx, cmd.Email)
+	return as.completeLogin(ctx, u, cmd, sessionGrant{AMR: []string{AMRPassword}})
+}
+
+// completeLogin runs the checks shared by every first factor (password,
+// social login, ...) and either issues tokens or, for users with a second
+// factor, opens an MFA challenge. grant.AMR lists the first factor's
+// methods; grant.MFA is set when that factor is multi-factor on its own (a
+// user-verifying passkey), which skips the challenge.
+func (as *AuthService) completeLogin(ctx context.Context, u *repository.UserModel, cmd model.LoginCmd, grant sessionGrant) (*model.LoginResult, error) {
+	if !userActive(u) {
+		as.auditLoginFailure(ctx, cmd, u.ID.String(), "inactive")
+		return nil, enum.ErrAccountDisabled
+	}
+	if u.EmailVerifiedAt == nil && as.policy.EmailVerification == EmailPolicyBlock {
+		return nil, enum.ErrEmailNotVerified
+	}
+	if err := as.checkDevice(ctx, u, cmd.DeviceID); err != nil {
+		as.auditLoginFailure(ctx, cmd, u.ID.String(), "device_blocked")
+		return nil, err
+	}
+	mfaState, err := as.mfaRepo.GetByUserID(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfaState != nil && mfaState.Enabled && !grant.MFA {
+		challenge, err := as.newMFAChallenge(ctx, u, cmd, grant.AMR)
+		if err != nil {
+			return nil, err
+		}
+		return &model.LoginResult{MFAChallenge: challenge}, nil
+	}
+	token, err := as.issueTokens(ctx, u, cmd, grant)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResult{Token: token}, nil
+}
+
+// checkDevice refuses logins from a blocked device before any factor is asked.
+func (as *AuthService) checkDevice(ctx context.Context, u *repository.UserModel, deviceID string) error {
+	if deviceID == "" {
+		return nil
+	}
+	dev, err := as.GetDevice(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if dev != nil && dev.UserID == u.ID.String() && dev.Status == model.Block {
+		return enum.ErrDeviceBlocked
+	}
+	return nil
+}
+
+func (as *AuthService) auditLoginFailure(ctx context.Context, cmd model.LoginCmd, userID, reason string) {
+	as.audit(ctx, AuditEvent{
+		Type:      AuditLoginFailure,
+		SubjectID: userID,
+		IP:        cmd.IP,
+		UA:        cmd.UA,
+		DeviceID:  cmd.DeviceID,
+		Metadata:  map[string]any{"email": cmd.Email, "reason": reason},
+	})
+}
+
+// issueTokens ensures the device, opens a session and starts a new refresh
+// family for an already authenticated user.
+func (as *AuthService) issueTokens(ctx context.Context, u *repository.UserModel, cmd model.LoginCmd, grant sessionGrant) (*model.TokenPair, error) {
 	did := cmd.DeviceID
 	if did == "" {
 		did = newID("DEV_")
 	}
 	dev, _ := as.GetDevice(ctx, did)
+	if dev != nil && dev.UserID != u.ID.String() {
+		// device ids are client supplied; never attach to another user's device
+		did = newID("DEV_")
+		dev = nil
+	}
+	if dev != nil && dev.Status == model.Block {
+		return nil, enum.ErrDeviceBlocked
+	}
 	if dev == nil {
 		dev = &Device{
 			UserID:    u.ID.String(),
@@ -305,7 +579,9 @@ func (as *AuthService) Login(ctx context.Context, cmd model.LoginCmd) (*model.To
 		dev.LastSeen = nowISO()
 		_ = as.SaveDevice(ctx, dev)
 	}
+	// A session lives as long as its refresh family; Refresh keeps the sid.
 	sid := newID("SID_")
+	fam := newID("FAM_")
 	session := &Session{
 		UserID:    u.ID.String(),
 		DeviceID:  did,
@@ -313,90 +589,161 @@ func (as *AuthService) Login(ctx context.Context, cmd model.LoginCmd) (*model.To
 		UA:        cmd.UA,
 		CreatedAt: nowISO(),
 		LastSeen:  nowISO(),
-		Exp:       addSecISO(int(as.jwtCfg.AccessTTL)),
-		MFA:       true,
+		Exp:       addSecISO(int(as.jwtCfg.RefreshTTL.Seconds())),
+		Scopes:    append(as.userScopes(u), grant.Scopes...),
+		Fam:       fam,
+		MFA:       grant.MFA,
 		Status:    model.Active,
+		ClientID:  grant.ClientID,
+		Grant:     grant.Scopes,
+		Delegated: grant.Delegated,
+		AuthTime:  grant.AuthTime,
+		AMR:       grant.AMR,
+	}
+	if session.AuthTime == 0 {
+		session.AuthTime = time.Now().Unix()
 	}
-	if err := as.SaveSession(ctx, sid, session, int(as.jwtCfg.AccessTTL)); err != nil {
+	if err := as.SaveSession(ctx, sid, session, int(as.jwtCfg.RefreshTTL.Seconds())); err != nil {
 		return nil, err
 	}
 
+	roles, err := as.tokenRoles(ctx, u, session)
+	if err != nil {
+		return nil, err
+	}
+	uv, err := as.EnsureUserVersion(ctx, u.ID.String())
+	if err != nil {
+		return nil, err
+	}
 	atJTI := fmt.Sprintf("at-%s-%d", u.ID, time.Now().UnixNano())
-	at, atExp, err := as.makeAccessToken(u, atJTI, sid, did, []string{})
+	at, atExp, err := as.makeAccessToken(u, atJTI, sid, did, uv, grant.ClientID, roles, session.Scopes)
 	if err != nil {
 		return nil, enum.ErrInvalidToken
 	}
-	fam := newID("FAM_")
 	rtJTI := fmt.Sprintf("rt-%s-%d", u.ID, time.Now().UnixNano())
-	uv, _ := as.EnsureUserVersion(ctx, u.ID.String())
 
-	rt, _, err := as.makeRefreshToken(u, rtJTI, did, fam, uv)
+	rt, _, err := as.makeRefreshToken(u, rtJTI, did, fam, sid, uv)
 	if err != nil {
 		return nil, enum.ErrInvalidToken
 	}
 
 	pipe := as.redis.TxPipeline()
-	pipe.Set(ctx, keys.RTActive(rtJTI), fmt.Sprintf(`{"user_id:"%s","device_id": "%s", "fam":"%s"}`, u.ID.String(), did, fam), as.jwtCfg.RefreshTTL)
+	pipe.Set(ctx, keys.RTActive(rtJTI), rtRecord(u.ID.String(), did, fam), as.jwtCfg.RefreshTTL)
 	pipe.SAdd(ctx, keys.FamActive(fam), rtJTI)
 	pipe.SAdd(ctx, keys.UserDeviceFams(u.ID.String(), did), fam)
 	if _, err := pipe.Exec(ctx); err != nil {
 		return nil, err
 	}
+	as.audit(ctx, AuditEvent{
+		Type:      AuditLoginSuccess,
+		ActorID:   u.ID.String(),
+		IP:        cmd.IP,
+		UA:        cmd.UA,
+		DeviceID:  did,
+		SessionID: sid,
+		Metadata:  map[string]any{"mfa": grant.MFA, "amr": grant.AMR, "client_id": grant.ClientID},
+	})
 	return &model.TokenPair{
 		AccessToken:  at,
 		RefreshToken: rt,
 		Expired:      int64(atExp.Unix()),
+		SessionID:    sid,
 	}, nil
 }
 
-func (as *AuthService) Refresh(ctx context.Context, refreshToken string) (*model.TokenPair, error) {
-	// Parse & validate refresh token
+// tokenRoles returns the roles to embed in an access token; delegated
+// sessions get none so that partners never inherit admin rights.
+func (as *AuthService) tokenRoles(ctx context.Context, u *repository.UserModel, sess *Session) ([]string, error) {
+	if sess.Delegated {
+		return nil, nil
+	}
+	return as.rbacRepo.GetUserRoles(ctx, u.ID)
+}
+
+// parseRefreshToken verifies signature, issuer, expiry and type of a refresh
+// token; whether it is still active is up to the caller.
+func (as *AuthService) parseRefreshToken(refreshToken string) (*RefreshClaims, error) {
 	parser := jwt.NewParser(
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithValidMethods(as.jwtCfg.RefreshKeys.Algs()),
 		jwt.WithIssuer(as.jwtCfg.Issuer),
+		jwt.WithExpirationRequired(),
 	)
 	claims := &RefreshClaims{}
-	tok, err := parser.ParseWithClaims(refreshToken, claims, func(t *jwt.Token) (any, error) {
-		if t.Method != jwt.SigningMethodHS256 {
-			return nil, enum.ErrWrongAlgorithm
-		}
-		return as.jwtCfg.RefreshSecret, nil
-	})
+	tok, err := parser.ParseWithClaims(refreshToken, claims, as.jwtCfg.RefreshKeys.Keyfunc)
 	if err != nil || !tok.Valid {
 		return nil, enum.ErrInvalidToken
 	}
 	if claims.TokenType != "refresh" {
 		return nil, enum.ErrWrongType
 	}
-	jti := claims.Subject
-	if jti == "" {
-		jti = claims.Subject
+	return claims, nil
+}
+
+// Refresh rotates a refresh token of a login session. Tokens issued to OAuth
+// clients are refreshed through the token endpoint instead.
+func (as *AuthService) Refresh(ctx context.Context, refreshToken string) (*model.TokenPair, error) {
+	return as.refresh(ctx, refreshToken, "")
+}
+
+// refresh rotates a refresh token whose session belongs to clientID ("" for
+// sessions opened by Login).
+func (as *AuthService) refresh(ctx context.Context, refreshToken, clientID string) (*model.TokenPair, error) {
+	claims, err := as.parseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
 	}
+	jti := claims.ID
 	fam := claims.Fam
-	if fam == "" {
+	sid := claims.SessionID
+	did := claims.DeviceID
+	if jti == "" || fam == "" || sid == "" {
 		return nil, enum.ErrInvalidToken
 	}
-	did := claims.DeviceID
 	if n, _ := as.redis.Exists(ctx, keys.FamBlack(fam)).Result(); n == 1 {
 		return nil, enum.ErrFamilyBlocked
 	}
 	if n, _ := as.redis.Exists(ctx, keys.RTRevoked(jti)).Result(); n == 1 {
-		_ = as.redis.Set(ctx, keys.FamBlack(fam), "1", as.jwtCfg.RefreshTTL).Err()
+		as.handleRefreshReuse(ctx, claims)
 		return nil, enum.ErrRefreshRevoked
 	}
 	if n, _ := as.redis.Exists(ctx, keys.RTActive(jti)).Result(); n != 1 {
 		return nil, enum.ErrRefreshNotActive
 	}
-	if claims.Uv > 0 {
-		curUv, err := as.GetUserVersion(ctx, jti)
-		if err != nil && curUv != claims.Uv {
-			return nil, enum.ErrInvalidToken
-		}
+	curUv, err := as.GetUserVersion(ctx, claims.Subject)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	if curUv != claims.Uv {
+		return nil, enum.ErrInvalidToken
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, enum.ErrInvalidToken
 	}
-	u, err := as.userRepo.GetUserByID(ctx, uuid.MustParse(claims.Subject))
+	u, err := as.userRepo.GetUserByID(ctx, userID)
 	if err != nil || u == nil {
 		return nil, enum.ErrUserNotFound
 	}
+	if !userActive(u) {
+		return nil, enum.ErrAccountDisabled
+	}
+	sess, err := as.GetSession(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil || sess.Status != model.Active || sess.UserID != claims.Subject {
+		return nil, enum.ErrSessionRevoked
+	}
+	if sess.ClientID != clientID {
+		return nil, enum.ErrInvalidToken
+	}
+	dev, err := as.GetDevice(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	if dev != nil && dev.Status == model.Block {
+		return nil, enum.ErrDeviceBlocked
+	}
 	gotLock, _ := as.redis.SetNX(ctx, keys.RotateLock(fam), "1", 10*time.Second).Result()
 	if !gotLock {
 		return nil, enum.ErrRotationRace
@@ -406,37 +753,44 @@ func (as *AuthService) Refresh(ctx context.Context, refreshToken string) (*model
 	if ttlLeft < time.Second {
 		ttlLeft = time.Second
 	}
-	pipe := as.redis.TxPipeline()
-	pipe.Del(ctx, keys.RTActive(jti))
-	pipe.SRem(ctx, keys.FamActive(fam), jti)
-	pipe.Set(ctx, keys.RTRevoked(jti), "1", ttlLeft)
 
-	newRTJTI := fmt.Sprintf("rt-%d-%d", u.ID, time.Now().UnixNano())
-	newRT, _, err := as.makeRefreshToken(u, newRTJTI, claims.DeviceID, fam, claims.Uv)
+	newRTJTI := fmt.Sprintf("rt-%s-%d", u.ID, time.Now().UnixNano())
+	newRT, newRtExp, err := as.makeRefreshToken(u, newRTJTI, did, fam, sid, claims.Uv)
 	if err != nil {
 		return nil, err
 	}
-	pipe.SAdd(ctx, keys.FamActive(fam), newRTJTI)
-	newSID := newID("SID_")
-	newSess := &Session{
-		UserID:    u.ID.String(),
-		DeviceID:  did,
-		IP:        "",
-		UA:        "",
-		CreatedAt: nowISO(),
-		LastSeen:  nowISO(),
-		Exp:       addSecISO(int(as.jwtCfg.AccessTTL)),
-		MFA:       true,
-		Status:    model.Active,
+	roles, err := as.tokenRoles(ctx, u, sess)
+	if err != nil {
+		return nil, err
 	}
-	if err := as.SaveSession(ctx, newSID, newSess, int(as.jwtCfg.AccessTTL)); err != nil {
+	newATJTI := fmt.Sprintf("at-%s-%d", u.ID, time.Now().UnixNano())
+	// scopes are recomputed so a freshly verified email lifts the restriction
+	sess.Scopes = append(as.userScopes(u), sess.Grant...)
+	newAT, newAtExp, err := as.makeAccessToken(u, newATJTI, sid, did, claims.Uv, sess.ClientID, roles, sess.Scopes)
+	if err != nil {
 		return nil, err
 	}
-	newATJTI := fmt.Sprintf("at-%s-%d", u.ID, time.Now().Unix())
-	newAT, newAtExp, err := as.makeAccessToken(u, newATJTI, newID("SID_"), claims.DeviceID, []string{})
+	sess.LastSeen = nowISO()
+	sess.Exp = newRtExp.Format(time.RFC3339)
+	sessRaw, _ := json.Marshal(sess)
+
+	// The checks above ran before the lock; claiming the parent is what makes
+	// the rotation single-use. Losing the claim means another request already
+	// rotated this token, so it is a replay like any other.
+	claimed, err := as.redis.Del(ctx, keys.RTActive(jti)).Result()
 	if err != nil {
 		return nil, err
 	}
+	if claimed != 1 {
+		as.handleRefreshReuse(ctx, claims)
+		return nil, enum.ErrRefreshRevoked
+	}
+	pipe := as.redis.TxPipeline()
+	pipe.SRem(ctx, keys.FamActive(fam), jti)
+	pipe.Set(ctx, keys.RTRevoked(jti), "1", ttlLeft)
+	pipe.Set(ctx, keys.RTActive(newRTJTI), rtRecord(u.ID.String(), did, fam), as.jwtCfg.RefreshTTL)
+	pipe.SAdd(ctx, keys.FamActive(fam), newRTJTI)
+	pipe.Set(ctx, keys.Session(sid), sessRaw, as.jwtCfg.RefreshTTL)
 	if _, err := pipe.Exec(ctx); err != nil {
 		return nil, err
 	}
@@ -444,11 +798,56 @@ func (as *AuthService) Refresh(ctx context.Context, refreshToken string) (*model
 		AccessToken:  newAT,
 		RefreshToken: newRT,
 		Expired:      int64(newAtExp.Unix()),
+		SessionID:    sid,
 	}
 	return token, nil
 }
+
+// Logout ends the caller's current session.
+func (as *AuthService) Logout(ctx context.Context, userID, sid string) error {
+	if err := as.revokeSession(ctx, userID, sid); err != nil {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditLogout, SubjectID: userID, SessionID: sid})
+	return nil
+}
+
 func (as *AuthService) LogoutDevice(ctx context.Context, userID, deviceID string) error {
+	if _, err := as.GetUserDevice(ctx, userID, deviceID); err != nil {
+		return err
+	}
+	if _, err := as.revokeDevice(ctx, userID, deviceID); err != nil {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditLogoutDevice, SubjectID: userID, DeviceID: deviceID})
+	return nil
+}
+
+// revokeDevice kills every refresh family and session of one device and
+// reports how many sessions were dropped.
+func (as *AuthService) revokeDevice(ctx context.Context, userID, deviceID string) (int, error) {
 	fams, _ := as.redis.SMembers(ctx, keys.UserDeviceFams(userID, deviceID)).Result()
+	if err := as.revokeFamilies(ctx, fams...); err != nil {
+		return 0, err
+	}
+	n := 0
+	sids, _ := as.redis.SMembers(ctx, keys.UserSession(userID)).Result()
+	for _, sid := range sids {
+		if sess, _ := as.GetSession(ctx, sid); sess != nil && sess.DeviceID == deviceID {
+			if err := as.DelSession(ctx, userID, sid); err == nil {
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+// revokeFamilies blacklists refresh families and moves their live JTIs to the
+// revoked set, so a replay is reported as reuse instead of "not active".
+func (as *AuthService) revokeFamilies(ctx context.Context, fams ...string) error {
+	if len(fams) == 0 {
+		return nil
+	}
 	pipe := as.redis.TxPipeline()
 	for _, fam := range fams {
 		pipe.Set(ctx, keys.FamBlack(fam), "1", as.jwtCfg.RefreshTTL)
@@ -459,17 +858,11 @@ func (as *AuthService) LogoutDevice(ctx context.Context, userID, deviceID string
 		}
 		pipe.Del(ctx, keys.FamActive(fam))
 	}
-	_, _ = pipe.Exec(ctx)
-
-	sids, _ := as.redis.SMembers(ctx, keys.UserSession(userID)).Result()
-	for _, sid := range sids {
-		if sess, _ := as.GetSession(ctx, sid); sess != nil && sess.DeviceID == deviceID {
-			_ = as.DelSession(ctx, userID, sid)
-		}
-	}
-	return nil
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
-// func (as *AuthService) LogoutAll(ctx context.Context, userID string) error {
-// 	fams, _ := as.redis.SMembers(ctx, keys.UserFam)
-// }
+func rtRecord(userID, deviceID, fam string) string {
+	b, _ := json.Marshal(map[string]string{"user_id": userID, "device_id": deviceID, "fam": fam})
+	return string(b)
+}
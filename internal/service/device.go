@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/pkg/enum"
+)
+
+// DeviceItem is a device as shown to its owner.
+type DeviceItem struct {
+	Device
+	Current bool `json:"current"`
+}
+
+// DeviceUpdate carries the owner-editable fields of a device; nil means unchanged.
+type DeviceUpdate struct {
+	Name    *string
+	Trusted *bool
+}
+
+// ListDevices returns every device a user has logged in from, most recent first.
+func (as *AuthService) ListDevices(ctx context.Context, userID, currentDID string) ([]DeviceItem, error) {
+	dids, err := as.redis.SMembers(ctx, keys.UserDevice(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(dids) == 0 {
+		return []DeviceItem{}, nil
+	}
+	devKeys := make([]string, len(dids))
+	for i, did := range dids {
+		devKeys[i] = keys.Device(did)
+	}
+	raws, err := as.redis.MGet(ctx, devKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]DeviceItem, 0, len(dids))
+	for _, raw := range raws {
+		str, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		var d Device
+		if err := json.Unmarshal([]byte(str), &d); err != nil || d.UserID != userID {
+			continue
+		}
+		items = append(items, DeviceItem{Device: d, Current: d.DeviceID == currentDID})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].LastSeen > items[j].LastSeen })
+	return items, nil
+}
+
+// GetUserDevice loads a device and checks it belongs to the user.
+func (as *AuthService) GetUserDevice(ctx context.Context, userID, deviceID string) (*Device, error) {
+	dev, err := as.GetDevice(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if dev == nil || dev.UserID != userID {
+		return nil, enum.ErrDeviceNotFound
+	}
+	return dev, nil
+}
+
+// UpdateDevice renames a device and/or changes its trusted flag.
+func (as *AuthService) UpdateDevice(ctx context.Context, userID, deviceID string, upd DeviceUpdate) (*Device, error) {
+	dev, err := as.GetUserDevice(ctx, userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if upd.Name != nil {
+		dev.Name = *upd.Name
+	}
+	if upd.Trusted != nil {
+		dev.Trusted = *upd.Trusted
+	}
+	if err := as.SaveDevice(ctx, dev); err != nil {
+		return nil, err
+	}
+	return dev, nil
+}
+
+// BlockDevice marks a device as blocked and kills all of its sessions and
+// refresh families. Login and Refresh refuse blocked devices afterwards.
+func (as *AuthService) BlockDevice(ctx context.Context, userID, deviceID string) error {
+	dev, err := as.GetUserDevice(ctx, userID, deviceID)
+	if err != nil {
+		return err
+	}
+	dev.Status = model.Block
+	dev.Trusted = false
+	if err := as.SaveDevice(ctx, dev); err != nil {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditDeviceBlock, SubjectID: userID, DeviceID: deviceID})
+	return as.LogoutDevice(ctx, userID, deviceID)
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/pkg/enum"
+
+	"go.uber.org/zap"
+)
+
+const (
+	IPListAllow = "allow"
+	IPListDeny  = "deny"
+
+	IPRuleSourceAdmin = "admin"
+	IPRuleSourceAuto  = "auto" // banned by the login lockout
+
+	// ipACLRefresh bounds how stale an instance's copy of the lists may be.
+	ipACLRefresh = 5 * time.Second
+)
+
+// IPRule is one CIDR entry of the allow or deny list. While the allow list is
+// empty every address outside the deny list gets in; as soon as it has an
+// entry only allow-listed addresses do. Allow entries are never blocked, not
+// even by an automatic ban; deny entries block the range until ExpiresAt, or
+// forever when it is empty.
+type IPRule struct {
+	CIDR      string `json:"cidr"`
+	Note      string `json:"note,omitempty"`
+	Source    string `json:"source"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+type IPRules struct {
+	Allow []IPRule `json:"allow"`
+	Deny  []IPRule `json:"deny"`
+}
+
+type ipRuleSet struct {
+	allow []netip.Prefix
+	deny  []ipDeny
+}
+
+type ipDeny struct {
+	prefix netip.Prefix
+	until  time.Time // zero = permanent
+}
+
+// ipACLCache is a per-instance snapshot of the Redis lists, reloaded every
+// ipACLRefresh so that updates from any instance apply everywhere.
+type ipACLCache struct {
+	mu       sync.Mutex
+	rules    *ipRuleSet
+	loadedAt time.Time
+}
+
+// ParseCIDR accepts a CIDR or a single address and returns the canonical
+// prefix, e.g. "10.1.2.3" -> "10.1.2.3/32".
+func ParseCIDR(s string) (netip.Prefix, error) {
+	if p, err := netip.ParsePrefix(s); err == nil {
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, enum.ErrInvalidCIDR
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func ipListKey(list string) (string, error) {
+	switch list {
+	case IPListAllow:
+		return keys.IPAllow(), nil
+	case IPListDeny:
+		return keys.IPDeny(), nil
+	default:
+		return "", enum.ErrInvalidIPList
+	}
+}
+
+// AddIPRule stores a rule in list. ttl > 0 makes a deny rule temporary.
+func (as *AuthService) AddIPRule(ctx context.Context, list, cidr, note string, ttl time.Duration) (*IPRule, error) {
+	return as.addIPRule(ctx, list, cidr, note, IPRuleSourceAdmin, ttl)
+}
+
+func (as *AuthService) addIPRule(ctx context.Context, list, cidr, note, source string, ttl time.Duration) (*IPRule, error) {
+	key, err := ipListKey(list)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	rule := &IPRule{CIDR: prefix.String(), Note: note, Source: source, CreatedAt: nowISO()}
+	if ttl > 0 {
+		rule.ExpiresAt = time.Now().UTC().Add(ttl).Format(time.RFC3339)
+	}
+	b, _ := json.Marshal(rule)
+	if err := as.redis.HSet(ctx, key, rule.CIDR, b).Err(); err != nil {
+		return nil, err
+	}
+	as.ipACL.invalidate()
+	return rule, nil
+}
+
+// RemoveIPRule deletes a rule from list.
+func (as *AuthService) RemoveIPRule(ctx context.Context, list, cidr string) error {
+	key, err := ipListKey(list)
+	if err != nil {
+		return err
+	}
+	prefix, err := ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	n, err := as.redis.HDel(ctx, key, prefix.String()).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return enum.ErrIPRuleNotFound
+	}
+	as.ipACL.invalidate()
+	return nil
+}
+
+// ListIPRules returns both lists, dropping expired bans on the way.
+func (as *AuthService) ListIPRules(ctx context.Context) (*IPRules, error) {
+	allow, err := as.loadIPRules(ctx, keys.IPAllow())
+	if err != nil {
+		return nil, err
+	}
+	deny, err := as.loadIPRules(ctx, keys.IPDeny())
+	if err != nil {
+		return nil, err
+	}
+	return &IPRules{Allow: allow, Deny: deny}, nil
+}
+
+func (as *AuthService) loadIPRules(ctx context.Context, key string) ([]IPRule, error) {
+	raw, err := as.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]IPRule, 0, len(raw))
+	var expired []string
+	now := time.Now()
+	for field, v := range raw {
+		var r IPRule
+		if err := json.Unmarshal([]byte(v), &r); err != nil {
+			continue
+		}
+		if r.ExpiresAt != "" {
+			if until, err := time.Parse(time.RFC3339, r.ExpiresAt); err == nil && !until.After(now) {
+				expired = append(expired, field)
+				continue
+			}
+		}
+		rules = append(rules, r)
+	}
+	if len(expired) > 0 {
+		_ = as.redis.HDel(ctx, key, expired...).Err()
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].CIDR < rules[j].CIDR })
+	return rules, nil
+}
+
+// IPBlocked reports whether ip is kept out by the lists: it is outside a
+// non-empty allow list, or on the deny list and not allow-listed.
+func (as *AuthService) IPBlocked(ctx context.Context, ip string) (bool, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false, nil
+	}
+	set, err := as.ipACL.get(func() (*ipRuleSet, error) { return as.buildIPRuleSet(ctx) })
+	if err != nil {
+		return false, err
+	}
+	return set.blocks(addr.Unmap(), time.Now()), nil
+}
+
+func (s *ipRuleSet) blocks(addr netip.Addr, now time.Time) bool {
+	for _, p := range s.allow {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	if len(s.allow) > 0 {
+		return true // allow-only mode
+	}
+	for _, d := range s.deny {
+		if d.prefix.Contains(addr) && (d.until.IsZero() || d.until.After(now)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (as *AuthService) buildIPRuleSet(ctx context.Context) (*ipRuleSet, error) {
+	rules, err := as.ListIPRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	set := &ipRuleSet{}
+	for _, r := range rules.Allow {
+		if p, err := netip.ParsePrefix(r.CIDR); err == nil {
+			set.allow = append(set.allow, p)
+		}
+	}
+	for _, r := range rules.Deny {
+		p, err := netip.ParsePrefix(r.CIDR)
+		if err != nil {
+			continue
+		}
+		d := ipDeny{prefix: p}
+		if r.ExpiresAt != "" {
+			d.until, _ = time.Parse(time.RFC3339, r.ExpiresAt)
+		}
+		set.deny = append(set.deny, d)
+	}
+	return set, nil
+}
+
+// autoBanIP puts an address that hit the IP lockout on the deny list for the
+// lockout duration, if the policy asks for it.
+func (as *AuthService) autoBanIP(ctx context.Context, ip string, d time.Duration) {
+	if !as.policy.Lockout.AutoBanIP {
+		return
+	}
+	if _, err := as.addIPRule(ctx, IPListDeny, ip, "repeated failed logins", IPRuleSourceAuto, d); err != nil {
+		as.log.Warn("ip_autoban_failed", zap.String("ip", ip), zap.Error(err))
+		return
+	}
+	as.audit(ctx, AuditEvent{Type: AuditIPBan, IP: ip, Metadata: map[string]any{"duration": d.String(), "source": IPRuleSourceAuto}})
+}
+
+func (c *ipACLCache) get(load func() (*ipRuleSet, error)) (*ipRuleSet, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rules != nil && time.Since(c.loadedAt) < ipACLRefresh {
+		return c.rules, nil
+	}
+	set, err := load()
+	if err != nil {
+		if c.rules != nil {
+			return c.rules, nil // serve the last known lists while Redis is down
+		}
+		return nil, err
+	}
+	c.rules, c.loadedAt = set, time.Now()
+	return set, nil
+}
+
+func (c *ipACLCache) invalidate() {
+	c.mu.Lock()
+	c.rules = nil
+	c.mu.Unlock()
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestParseCIDR(t *testing.T) {
+	cases := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"10.1.2.3", "10.1.2.3/32", true},
+		{"10.1.2.3/8", "10.0.0.0/8", true},
+		{"::ffff:10.1.2.3", "10.1.2.3/32", true},
+		{"2001:db8::1", "2001:db8::1/128", true},
+		{"not-an-ip", "", false},
+		{"10.0.0.0/33", "", false},
+	}
+	for _, tc := range cases {
+		p, err := ParseCIDR(tc.in)
+		if (err == nil) != tc.ok || (tc.ok && p.String() != tc.want) {
+			t.Errorf("ParseCIDR(%q) = %v, %v; want %s", tc.in, p, err, tc.want)
+		}
+	}
+}
+
+func TestIPRuleSetBlocks(t *testing.T) {
+	now := time.Now()
+	deny := []ipDeny{
+		{prefix: netip.MustParsePrefix("203.0.113.0/24")},
+		{prefix: netip.MustParsePrefix("198.51.100.7/32"), until: now.Add(-time.Minute)}, // expired ban
+	}
+	cases := []struct {
+		name  string
+		allow []string
+		ip    string
+		want  bool
+	}{
+		{"open mode, unlisted", nil, "192.0.2.1", false},
+		{"open mode, denied", nil, "203.0.113.9", true},
+		{"open mode, expired ban", nil, "198.51.100.7", false},
+		{"allow-only mode, allowed", []string{"10.0.0.0/8"}, "10.1.2.3", false},
+		{"allow-only mode, unlisted", []string{"10.0.0.0/8"}, "192.0.2.1", true},
+		{"allow beats deny", []string{"203.0.113.0/28"}, "203.0.113.9", false},
+		{"allow-only mode, denied outside allow", []string{"203.0.113.0/28"}, "203.0.113.200", true},
+	}
+	for _, tc := range cases {
+		set := &ipRuleSet{deny: deny}
+		for _, a := range tc.allow {
+			set.allow = append(set.allow, netip.MustParsePrefix(a))
+		}
+		if got := set.blocks(netip.MustParseAddr(tc.ip), now); got != tc.want {
+			t.Errorf("%s: blocks(%s) = %v, want %v", tc.name, tc.ip, got, tc.want)
+		}
+	}
+}
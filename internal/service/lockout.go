@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	LockoutKindEmail = "email"
+	LockoutKindIP    = "ip"
+
+	lockoutMaxDelay  = time.Minute
+	lockoutEventsCap = 1000
+)
+
+// LockoutPolicy configures brute-force protection on login. Failures are
+// counted per email and per IP within Window. From DelayAfter failures on, each
+// further failure blocks the subject for an exponentially growing delay; at
+// MaxAttempts (IPMaxAttempts for addresses) it is locked for Duration.
+type LockoutPolicy struct {
+	DelayAfter    int
+	MaxAttempts   int
+	IPMaxAttempts int
+	Window        time.Duration
+	Duration      time.Duration
+	AutoBanIP     bool // also put locked-out IPs on the deny list
+}
+
+// LockoutError is returned while a login subject is locked.
+type LockoutError struct {
+	Until time.Time
+}
+
+func (e *LockoutError) Error() string { return enum.ErrAccountLocked.Error() }
+func (e *LockoutError) Unwrap() error { return enum.ErrAccountLocked }
+
+// RetryAfter tells the handler how long the client should wait.
+func (e *LockoutError) RetryAfter() time.Duration { return time.Until(e.Until) }
+
+// LockoutEvent records a temporary lockout for the security team.
+type LockoutEvent struct {
+	Kind     string `json:"kind"` // email | ip
+	Subject  string `json:"subject"`
+	Failures int64  `json:"failures"`
+	Until    string `json:"until"`
+	At       string `json:"at"`
+}
+
+// lockoutDelay returns how long a subject with failures recent failures must
+// wait, and whether that is a full lockout. Zero means no wait.
+func lockoutDelay(p LockoutPolicy, failures int64, maxAttempts int) (time.Duration, bool) {
+	if maxAttempts > 0 && failures >= int64(maxAttempts) {
+		return p.Duration, true
+	}
+	if p.DelayAfter <= 0 || failures < int64(p.DelayAfter) {
+		return 0, false
+	}
+	shift := failures - int64(p.DelayAfter)
+	if shift > 6 {
+		return lockoutMaxDelay, false
+	}
+	return min(time.Second<<shift, lockoutMaxDelay), false
+}
+
+func lockoutEmail(email string) string { return strings.ToLower(strings.TrimSpace(email)) }
+
+// checkLockout refuses a login attempt while the email or the IP is locked.
+// Either may be empty when the attempt does not name it.
+func (as *AuthService) checkLockout(ctx context.Context, email, ip string) error {
+	var subjects []string
+	if email != "" {
+		subjects = append(subjects, keys.LoginLock(LockoutKindEmail, lockoutEmail(email)))
+	}
+	if ip != "" {
+		subjects = append(subjects, keys.LoginLock(LockoutKindIP, ip))
+	}
+	var until time.Time
+	for _, k := range subjects {
+		ttl, err := as.redis.PTTL(ctx, k).Result()
+		if err != nil {
+			return err
+		}
+		if ttl > 0 && time.Now().Add(ttl).After(until) {
+			until = time.Now().Add(ttl)
+		}
+	}
+	if !until.IsZero() {
+		return &LockoutError{Until: until}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login for the email and the IP and
+// applies the resulting delay or lockout.
+func (as *AuthService) recordLoginFailure(ctx context.Context, email, ip string) {
+	p := as.policy.Lockout
+	if email != "" {
+		as.countFailure(ctx, p, LockoutKindEmail, lockoutEmail(email), p.MaxAttempts)
+	}
+	if ip != "" {
+		as.countFailure(ctx, p, LockoutKindIP, ip, p.IPMaxAttempts)
+	}
+}
+
+func (as *AuthService) countFailure(ctx context.Context, p LockoutPolicy, kind, subject string, maxAttempts int) {
+	failKey := keys.LoginFail(kind, subject)
+	n, err := as.redis.Incr(ctx, failKey).Result()
+	if err != nil {
+		as.log.Warn("login_failure_count_failed", zap.String("kind", kind), zap.Error(err))
+		return
+	}
+	if n == 1 {
+		_ = as.redis.Expire(ctx, failKey, p.Window).Err()
+	}
+	delay, locked := lockoutDelay(p, n, maxAttempts)
+	if delay <= 0 {
+		return
+	}
+	_ = as.redis.Set(ctx, keys.LoginLock(kind, subject), "1", delay).Err()
+	if !locked {
+		return
+	}
+	// the lockout consumes the failures; the next round starts fresh
+	_ = as.redis.Del(ctx, failKey).Err()
+	if kind == LockoutKindIP {
+		as.autoBanIP(ctx, subject, delay)
+	}
+	ev := LockoutEvent{
+		Kind:     kind,
+		Subject:  subject,
+		Failures: n,
+		Until:    time.Now().UTC().Add(delay).Format(time.RFC3339),
+		At:       nowISO(),
+	}
+	as.log.Warn("login_lockout", zap.String("kind", kind), zap.String("subject", subject), zap.Int64("failures", n), zap.Duration("duration", delay))
+	as.audit(ctx, AuditEvent{
+		Type:     AuditLoginLockout,
+		Metadata: map[string]any{"kind": kind, "subject": subject, "failures": n, "until": ev.Until},
+	})
+	b, _ := json.Marshal(ev)
+	pipe := as.redis.TxPipeline()
+	pipe.LPush(ctx, keys.LockoutEvents(), b)
+	pipe.LTrim(ctx, keys.LockoutEvents(), 0, lockoutEventsCap-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		as.log.Warn("lockout_event_record_failed", zap.Error(err))
+	}
+}
+
+// clearLoginFailures resets the email counter after a successful login. The
+// IP counter is kept so one valid account does not launder a stuffing run.
+func (as *AuthService) clearLoginFailures(ctx context.Context, email string) {
+	_ = as.redis.Del(ctx, keys.LoginFail(LockoutKindEmail, lockoutEmail(email))).Err()
+}
+
+// UnlockUser lifts a lockout of the user's email before it expires.
+func (as *AuthService) UnlockUser(ctx context.Context, userID uuid.UUID) error {
+	u, err := as.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	subject := lockoutEmail(u.Email)
+	return as.redis.Del(ctx, keys.LoginFail(LockoutKindEmail, subject), keys.LoginLock(LockoutKindEmail, subject)).Err()
+}
+
+// ListLockoutEvents returns the most recent lockouts, newest first.
+func (as *AuthService) ListLockoutEvents(ctx context.Context) ([]LockoutEvent, error) {
+	raws, err := as.redis.LRange(ctx, keys.LockoutEvents(), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]LockoutEvent, 0, len(raws))
+	for _, raw := range raws {
+		var ev LockoutEvent
+		if err := json.Unmarshal([]byte(raw), &ev); err == nil {
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutDelay(t *testing.T) {
+	p := LockoutPolicy{DelayAfter: 3, MaxAttempts: 10, Duration: 15 * time.Minute}
+	cases := []struct {
+		failures int64
+		delay    time.Duration
+		locked   bool
+	}{
+		{1, 0, false},
+		{2, 0, false},
+		{3, time.Second, false},
+		{4, 2 * time.Second, false},
+		{6, 8 * time.Second, false},
+		{9, time.Minute, false},
+		{10, 15 * time.Minute, true},
+	}
+	for _, tc := range cases {
+		delay, locked := lockoutDelay(p, tc.failures, p.MaxAttempts)
+		if delay != tc.delay || locked != tc.locked {
+			t.Errorf("lockoutDelay(%d) = %s, %v; want %s, %v", tc.failures, delay, locked, tc.delay, tc.locked)
+		}
+	}
+	if delay, _ := lockoutDelay(LockoutPolicy{}, 100, 0); delay != 0 {
+		t.Errorf("Expected a zero policy to never delay, got %s", delay)
+	}
+}
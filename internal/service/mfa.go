@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/totp"
+
+	cryptoRand "crypto/rand"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	MFAMethodTOTP     = "totp"
+	MFAMethodRecovery = "recovery_code"
+
+	mfaChallengeTTL    = 5 * time.Minute
+	mfaMaxAttempts     = 5
+	totpSkew           = 1 // accept the previous/next 30s window for clock drift
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	recoveryAlphabet   = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // no 0/O, 1/I
+)
+
+// mfaChallenge is what a password-verified login parks in Redis until the
+// second factor arrives. It never contains the password.
+type mfaChallenge struct {
+	UserID     string            `json:"user_id"`
+	DeviceID   string            `json:"device_id,omitempty"`
+	DeviceMeta map[string]string `json:"device_meta,omitempty"`
+	IP         string            `json:"ip,omitempty"`
+	UA         string            `json:"ua,omitempty"`
+	AMR        []string          `json:"amr,omitempty"` // first factor
+}
+
+func (as *AuthService) newMFAChallenge(ctx context.Context, u *repository.UserModel, cmd model.LoginCmd, amr []string) (*model.MFAChallenge, error) {
+	token, err := randomToken("mfa_", 32)
+	if err != nil {
+		return nil, err
+	}
+	b, _ := json.Marshal(mfaChallenge{
+		UserID:     u.ID.String(),
+		DeviceID:   cmd.DeviceID,
+		DeviceMeta: cmd.DeviceMeta,
+		IP:         cmd.IP,
+		UA:         cmd.UA,
+		AMR:        amr,
+	})
+	if err := as.redis.Set(ctx, keys.MFAChallenge(token), b, mfaChallengeTTL).Err(); err != nil {
+		return nil, err
+	}
+	return &model.MFAChallenge{
+		Token:   token,
+		Expired: time.Now().Add(mfaChallengeTTL).Unix(),
+		Methods: []string{MFAMethodTOTP, MFAMethodRecovery},
+	}, nil
+}
+
+// VerifyMFA completes a two-stage login with a TOTP or a recovery code and
+// issues the token pair with Session.MFA set.
+func (as *AuthService) VerifyMFA(ctx context.Context, cmd model.MFAVerifyCmd) (*model.TokenPair, error) {
+	raw, err := as.redis.Get(ctx, keys.MFAChallenge(cmd.ChallengeToken)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, enum.ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ch mfaChallenge
+	if err := json.Unmarshal(raw, &ch); err != nil {
+		return nil, enum.ErrMFAChallengeInvalid
+	}
+	attempts, err := as.redis.Incr(ctx, keys.MFAAttempts(cmd.ChallengeToken)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if attempts == 1 {
+		_ = as.redis.Expire(ctx, keys.MFAAttempts(cmd.ChallengeToken), mfaChallengeTTL).Err()
+	}
+	if attempts > mfaMaxAttempts {
+		_ = as.redis.Del(ctx, keys.MFAChallenge(cmd.ChallengeToken), keys.MFAAttempts(cmd.ChallengeToken)).Err()
+		return nil, enum.ErrMFAChallengeInvalid
+	}
+
+	userID, err := uuid.Parse(ch.UserID)
+	if err != nil {
+		return nil, enum.ErrMFAChallengeInvalid
+	}
+	switch {
+	case cmd.Code != "":
+		if err := as.verifyTOTP(ctx, userID, cmd.Code); err != nil {
+			return nil, err
+		}
+	case cmd.RecoveryCode != "":
+		ok, err := as.mfaRepo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(cmd.RecoveryCode)))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, enum.ErrMFAInvalidCode
+		}
+	default:
+		return nil, enum.ErrMFAInvalidCode
+	}
+
+	// the challenge is single use
+	if n, err := as.redis.Del(ctx, keys.MFAChallenge(cmd.ChallengeToken)).Result(); err != nil || n == 0 {
+		return nil, enum.ErrMFAChallengeInvalid
+	}
+	_ = as.redis.Del(ctx, keys.MFAAttempts(cmd.ChallengeToken)).Err()
+
+	u, err := as.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// the account may have been disabled while the challenge was open
+	if !userActive(u) {
+		return nil, enum.ErrAccountDisabled
+	}
+	return as.issueTokens(ctx, u, model.LoginCmd{
+		Email:      u.Email,
+		DeviceID:   ch.DeviceID,
+		DeviceMeta: ch.DeviceMeta,
+		IP:         ch.IP,
+		UA:         ch.UA,
+	}, sessionGrant{MFA: true, AMR: mfaAMR(ch.AMR)})
+}
+
+// mfaAMR adds the second factor to the methods of the first one. Challenges
+// opened before the first factor was recorded came from a password login.
+func mfaAMR(first []string) []string {
+	if len(first) == 0 {
+		first = []string{AMRPassword}
+	}
+	return append(slices.Clone(first), AMROTP, AMRMFA)
+}
+
+// EnrollTOTP generates a new secret for the user. It stays inactive until
+// ConfirmTOTP proves the authenticator app was set up.
+func (as *AuthService) EnrollTOTP(ctx context.Context, userID string) (*model.TOTPEnrollment, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	current, err := as.mfaRepo.GetByUserID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && current.Enabled {
+		return nil, enum.ErrMFAAlreadyEnabled
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := as.mfaRepo.SaveTOTPSecret(ctx, uid, secret); err != nil {
+		return nil, err
+	}
+	return &model.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(as.jwtCfg.Issuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP activates a pending TOTP secret and returns fresh recovery codes.
+// The plain codes are shown once; only their hashes are stored.
+func (as *AuthService) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	current, err := as.mfaRepo.GetByUserID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, enum.ErrMFANotEnrolled
+	}
+	if current.Enabled {
+		return nil, enum.ErrMFAAlreadyEnabled
+	}
+	if err := as.verifyTOTP(ctx, uid, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := as.mfaRepo.ReplaceRecoveryCodes(ctx, uid, hashes); err != nil {
+		return nil, err
+	}
+	if err := as.mfaRepo.EnableTOTP(ctx, uid); err != nil {
+		return nil, err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditMFAEnabled, SubjectID: userID, Metadata: map[string]any{"method": MFAMethodTOTP}})
+	return codes, nil
+}
+
+// verifyTOTP checks a code against the stored secret and refuses to accept
+// the same time step twice.
+func (as *AuthService) verifyTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	m, err := as.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return enum.ErrMFANotEnrolled
+	}
+	step, ok := totp.Validate(m.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return enum.ErrMFAInvalidCode
+	}
+	ttl := time.Duration((2*totpSkew+1)*totp.Period) * time.Second
+	fresh, err := as.redis.SetNX(ctx, keys.TOTPUsed(userID.String(), step), "1", ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return enum.ErrMFAInvalidCode
+	}
+	return nil
+}
+
+func newRecoveryCodes() (codes []string, hashes []string, err error) {
+	buf := make([]byte, recoveryCodeLength)
+	for range recoveryCodeCount {
+		if _, err := cryptoRand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		var sb strings.Builder
+		for i, b := range buf {
+			if i == recoveryCodeLength/2 {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte(recoveryAlphabet[int(b)%len(recoveryAlphabet)])
+		}
+		code := sb.String()
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,485 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// ScopeProfile lets delegated tokens read the profile through GET /me.
+	ScopeProfile = "profile"
+
+	oauthCodeTTL = 5 * time.Minute
+	pkceS256     = "S256"
+)
+
+// AuthorizeRequest holds the authorization endpoint parameters (RFC 6749
+// section 4.1.1 with the PKCE extension of RFC 7636).
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string // OpenID Connect, echoed in the ID token
+}
+
+// oauthCode is parked in Redis between authorize and token.
+type oauthCode struct {
+	ClientID      string   `json:"client_id"`
+	UserID        string   `json:"user_id"`
+	RedirectURI   string   `json:"redirect_uri"` // as sent, "" if omitted
+	Scopes        []string `json:"scopes"`
+	CodeChallenge string   `json:"code_challenge"`
+	MFA           bool     `json:"mfa"`
+	AMR           []string `json:"amr,omitempty"`
+	AuthTime      int64    `json:"auth_time"`
+	Nonce         string   `json:"nonce,omitempty"`
+}
+
+// TokenRequest holds the token endpoint parameters for every supported grant.
+type TokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+}
+
+// OAuthToken is the RFC 6749 section 5.1 token response.
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"` // when openid was granted
+}
+
+// Introspection is the RFC 7662 response. Inactive tokens only report
+// active=false.
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"` // access_token | refresh_token
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+// Authorize issues an authorization code to the client on behalf of the
+// logged-in user and returns the redirect URI that carries it. It is called
+// by the consent page once the user approved; sid is the caller's session,
+// whose second factor carries over to the client's session.
+func (as *AuthService) Authorize(ctx context.Context, userID, sid string, req AuthorizeRequest) (string, error) {
+	client, err := as.oauthRepo.GetClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, enum.ErrOAuthClientNotFound) {
+			return "", enum.ErrOAuthInvalidClient
+		}
+		return "", err
+	}
+	redirect, err := resolveRedirectURI(client, req.RedirectURI)
+	if err != nil {
+		return "", err
+	}
+	if req.ResponseType != "code" {
+		return "", enum.ErrOAuthUnsupportedResponseType
+	}
+	if !allowsGrant(client, GrantAuthorizationCode) {
+		return "", enum.ErrOAuthUnauthorizedClient
+	}
+	// PKCE is mandatory for every client, confidential or not
+	if req.CodeChallengeMethod != pkceS256 || len(req.CodeChallenge) != 43 {
+		return "", fmt.Errorf("%w: code_challenge with method S256 is required", enum.ErrOAuthInvalidRequest)
+	}
+	scopes, err := grantScopes(req.Scope, client.Scopes)
+	if err != nil {
+		return "", err
+	}
+	if err := as.checkOIDCScope(scopes); err != nil {
+		return "", err
+	}
+	sess, err := as.GetSession(ctx, sid)
+	if err != nil {
+		return "", err
+	}
+	if sess == nil {
+		return "", enum.ErrSessionRevoked
+	}
+	code, err := randomToken("oac_", 32)
+	if err != nil {
+		return "", err
+	}
+	b, _ := json.Marshal(oauthCode{
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		MFA:           sess.MFA,
+		AMR:           sess.AMR,
+		AuthTime:      authTime(sess),
+		Nonce:         req.Nonce,
+	})
+	if err := as.redis.Set(ctx, keys.OAuthCode(hashToken(code)), b, oauthCodeTTL).Err(); err != nil {
+		return "", err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditOAuthAuthorize, SubjectID: userID, Metadata: map[string]any{"client_id": client.ID, "scope": strings.Join(scopes, " ")}})
+	q := redirect.Query()
+	q.Set("code", code)
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+	redirect.RawQuery = q.Encode()
+	return redirect.String(), nil
+}
+
+// Token implements the token endpoint for the authorization code, refresh
+// token and client credentials grants. ip and ua describe the client and
+// end up on the session and device it opens.
+func (as *AuthService) Token(ctx context.Context, req TokenRequest, ip, ua string) (*OAuthToken, error) {
+	client, err := as.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	switch req.GrantType {
+	case GrantAuthorizationCode, GrantRefreshToken, GrantClientCredentials:
+	default:
+		return nil, enum.ErrOAuthUnsupportedGrant
+	}
+	if !allowsGrant(client, req.GrantType) {
+		return nil, enum.ErrOAuthUnauthorizedClient
+	}
+	switch req.GrantType {
+	case GrantAuthorizationCode:
+		return as.exchangeCode(ctx, client, req, ip, ua)
+	case GrantRefreshToken:
+		pair, err := as.refresh(ctx, req.RefreshToken, client.ID)
+		if err != nil {
+			return nil, err
+		}
+		return as.oauthToken(ctx, pair, "")
+	default:
+		return as.clientCredentials(ctx, client, req)
+	}
+}
+
+func (as *AuthService) exchangeCode(ctx context.Context, client *repository.OAuthClientModel, req TokenRequest, ip, ua string) (*OAuthToken, error) {
+	h := hashToken(req.Code)
+	raw, err := as.redis.GetDel(ctx, keys.OAuthCode(h)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		as.handleCodeReuse(ctx, h)
+		return nil, enum.ErrOAuthInvalidGrant
+	}
+	if err != nil {
+		return nil, err
+	}
+	var code oauthCode
+	if err := json.Unmarshal(raw, &code); err != nil {
+		return nil, enum.ErrOAuthInvalidGrant
+	}
+	if code.ClientID != client.ID || code.RedirectURI != req.RedirectURI || !verifyPKCE(req.CodeVerifier, code.CodeChallenge) {
+		return nil, enum.ErrOAuthInvalidGrant
+	}
+	uid, err := uuid.Parse(code.UserID)
+	if err != nil {
+		return nil, enum.ErrOAuthInvalidGrant
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if !userActive(u) {
+		return nil, enum.ErrAccountDisabled
+	}
+	// every grant gets its own device, named after the client
+	pair, err := as.issueTokens(ctx, u, model.LoginCmd{
+		Email:      u.Email,
+		DeviceMeta: map[string]string{"name": client.Name},
+		IP:         ip,
+		UA:         ua,
+	}, sessionGrant{
+		MFA:       code.MFA,
+		ClientID:  client.ID,
+		Scopes:    code.Scopes,
+		Delegated: !client.FirstParty,
+		AMR:       code.AMR,
+		AuthTime:  code.AuthTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// remembered so that a replayed code can take the tokens down with it
+	_ = as.redis.Set(ctx, keys.OAuthCodeUsed(h), pair.SessionID, oauthCodeTTL).Err()
+	as.audit(ctx, AuditEvent{Type: AuditOAuthToken, ActorID: code.UserID, SessionID: pair.SessionID, IP: ip, UA: ua, Metadata: map[string]any{"client_id": client.ID, "grant_type": GrantAuthorizationCode}})
+	return as.oauthToken(ctx, pair, code.Nonce)
+}
+
+// handleCodeReuse revokes the session opened with a code that is presented a
+// second time (RFC 6749 section 4.1.2).
+func (as *AuthService) handleCodeReuse(ctx context.Context, codeHash string) {
+	sid, err := as.redis.GetDel(ctx, keys.OAuthCodeUsed(codeHash)).Result()
+	if err != nil {
+		return
+	}
+	sess, _ := as.GetSession(ctx, sid)
+	if sess == nil {
+		return
+	}
+	_ = as.revokeSession(ctx, sess.UserID, sid)
+	as.audit(ctx, AuditEvent{Type: AuditOAuthCodeReuse, SubjectID: sess.UserID, SessionID: sid, Metadata: map[string]any{"client_id": sess.ClientID}})
+}
+
+// clientCredentials issues an access token that stands for the client
+// itself. It has no session, so the user-facing API does not accept it;
+// resource servers check it through introspection.
+func (as *AuthService) clientCredentials(ctx context.Context, client *repository.OAuthClientModel, req TokenRequest) (*OAuthToken, error) {
+	if client.SecretHash == "" {
+		return nil, enum.ErrOAuthUnauthorizedClient
+	}
+	scopes, err := grantScopes(req.Scope, client.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	claims := &AccessClaims{
+		TokenType: "access",
+		Scope:     strings.Join(scopes, " "),
+		ClientID:  client.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    as.jwtCfg.Issuer,
+			Subject:   client.ID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(as.jwtCfg.AccessTTL)),
+			ID:        fmt.Sprintf("cc-%s-%d", client.ID, now.UnixNano()),
+		},
+	}
+	at, err := as.jwtCfg.AccessKeys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditOAuthToken, Metadata: map[string]any{"client_id": client.ID, "grant_type": GrantClientCredentials}})
+	return &OAuthToken{AccessToken: at, TokenType: "Bearer", ExpiresIn: int64(as.jwtCfg.AccessTTL.Seconds()), Scope: claims.Scope}, nil
+}
+
+// RevokeToken implements RFC 7009. Revoking either token of a session ends
+// the session and its refresh family. Unknown tokens and tokens of other
+// clients are ignored, as the RFC asks.
+func (as *AuthService) RevokeToken(ctx context.Context, clientID, secret, token string) error {
+	client, err := as.authenticateClient(ctx, clientID, secret)
+	if err != nil {
+		return err
+	}
+	sid := ""
+	if rc, err := as.parseRefreshToken(token); err == nil {
+		sid = rc.SessionID
+	} else if ac, err := as.parseAccessClaims(token); err == nil {
+		if ac.SessionID == "" {
+			if ac.ClientID == client.ID {
+				ttl := time.Until(ac.ExpiresAt.Time)
+				return as.redis.Set(ctx, keys.OAuthRevoked(ac.ID), "1", max(ttl, time.Second)).Err()
+			}
+			return nil
+		}
+		sid = ac.SessionID
+	} else {
+		return nil
+	}
+	sess, err := as.GetSession(ctx, sid)
+	if err != nil || sess == nil || sess.ClientID != client.ID {
+		return err
+	}
+	if err := as.revokeSession(ctx, sess.UserID, sid); err != nil && !errors.Is(err, enum.ErrSessionRevoked) {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditOAuthRevoke, SubjectID: sess.UserID, SessionID: sid, Metadata: map[string]any{"client_id": client.ID}})
+	return nil
+}
+
+// Introspect implements RFC 7662 for confidential clients. A token is active
+// when the checks of the auth middleware (or of a refresh) would pass.
+func (as *AuthService) Introspect(ctx context.Context, clientID, secret, token string) (*Introspection, error) {
+	client, err := as.authenticateClient(ctx, clientID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if client.SecretHash == "" {
+		return nil, enum.ErrOAuthUnauthorizedClient
+	}
+	inactive := &Introspection{}
+	if ac, err := as.parseAccessClaims(token); err == nil {
+		res := &Introspection{
+			Active:    true,
+			Scope:     ac.Scope,
+			ClientID:  ac.ClientID,
+			TokenType: "access_token",
+			Exp:       ac.ExpiresAt.Unix(),
+			Iat:       ac.IssuedAt.Unix(),
+			Sub:       ac.Subject,
+			Iss:       ac.Issuer,
+			Jti:       ac.ID,
+		}
+		if ac.SessionID == "" {
+			if n, _ := as.redis.Exists(ctx, keys.OAuthRevoked(ac.ID)).Result(); n == 1 {
+				return inactive, nil
+			}
+			return res, nil
+		}
+		sess, err := as.liveSession(ctx, ac.SessionID, ac.Subject, ac.Uv)
+		if err != nil || sess == nil || sess.DeviceID != ac.DeviceID {
+			return inactive, err
+		}
+		res.Username = ac.Email
+		return res, nil
+	}
+	rc, err := as.parseRefreshToken(token)
+	if err != nil {
+		return inactive, nil
+	}
+	if n, _ := as.redis.Exists(ctx, keys.RTActive(rc.ID)).Result(); n != 1 {
+		return inactive, nil
+	}
+	if n, _ := as.redis.Exists(ctx, keys.FamBlack(rc.Fam)).Result(); n != 0 {
+		return inactive, nil
+	}
+	sess, err := as.liveSession(ctx, rc.SessionID, rc.Subject, rc.Uv)
+	if err != nil || sess == nil {
+		return inactive, err
+	}
+	return &Introspection{
+		Active:    true,
+		Scope:     strings.Join(sess.Grant, " "),
+		ClientID:  sess.ClientID,
+		TokenType: "refresh_token",
+		Exp:       rc.ExpiresAt.Unix(),
+		Iat:       rc.IssuedAt.Unix(),
+		Sub:       rc.Subject,
+		Iss:       rc.Issuer,
+		Jti:       rc.ID,
+	}, nil
+}
+
+// liveSession returns the session when it is active, belongs to userID, the
+// user version still matches and neither account nor device are disabled.
+// A nil session without error means the token is no longer valid.
+func (as *AuthService) liveSession(ctx context.Context, sid, userID string, uv int) (*Session, error) {
+	sess, err := as.GetSession(ctx, sid)
+	if err != nil || sess == nil || sess.Status != model.Active || sess.UserID != userID {
+		return nil, err
+	}
+	if err := as.CheckUserVersion(ctx, userID, uv); err != nil {
+		return nil, ignoreErr(err, enum.ErrInvalidToken)
+	}
+	if err := as.CheckUserActive(ctx, userID); err != nil {
+		return nil, ignoreErr(err, enum.ErrAccountDisabled)
+	}
+	dev, err := as.GetDevice(ctx, sess.DeviceID)
+	if err != nil || (dev != nil && dev.Status == model.Block) {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// ignoreErr returns nil when err is target.
+func ignoreErr(err, target error) error {
+	if errors.Is(err, target) {
+		return nil
+	}
+	return err
+}
+
+// oauthToken builds the token response for a session opened or refreshed
+// through OAuth, adding an ID token when the openid scope was granted.
+func (as *AuthService) oauthToken(ctx context.Context, pair *model.TokenPair, nonce string) (*OAuthToken, error) {
+	tok := &OAuthToken{
+		AccessToken:  pair.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    max(pair.Expired-time.Now().Unix(), 0),
+		RefreshToken: pair.RefreshToken,
+	}
+	sess, err := as.GetSession(ctx, pair.SessionID)
+	if err != nil || sess == nil {
+		return tok, err
+	}
+	tok.Scope = strings.Join(sess.Grant, " ")
+	if !slices.Contains(sess.Grant, ScopeOpenID) || !as.OIDCEnabled() {
+		return tok, nil
+	}
+	uid, err := uuid.Parse(sess.UserID)
+	if err != nil {
+		return nil, enum.ErrInvalidToken
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if tok.IDToken, err = as.makeIDToken(u, sess, sess.ClientID, nonce); err != nil {
+		return nil, err
+	}
+	return tok, nil
+}
+
+// resolveRedirectURI picks the registered redirect URI that matches requested
+// exactly; it may be omitted when the client registered only one.
+func resolveRedirectURI(c *repository.OAuthClientModel, requested string) (*url.URL, error) {
+	switch {
+	case requested == "" && len(c.RedirectURIs) == 1:
+		requested = c.RedirectURIs[0]
+	case !slices.Contains(c.RedirectURIs, requested):
+		return nil, fmt.Errorf("%w: redirect_uri is not registered for this client", enum.ErrOAuthInvalidRequest)
+	}
+	return url.Parse(requested)
+}
+
+// grantScopes checks a space separated scope request against the scopes the
+// client may ask for. An empty request grants all of them.
+func grantScopes(requested string, allowed []string) ([]string, error) {
+	scopes := strings.Fields(requested)
+	if len(scopes) == 0 {
+		return slices.Clone(allowed), nil
+	}
+	for _, s := range scopes {
+		if !slices.Contains(allowed, s) {
+			return nil, fmt.Errorf("%w: %s", enum.ErrOAuthInvalidScope, s)
+		}
+	}
+	slices.Sort(scopes)
+	return slices.Compact(scopes), nil
+}
+
+// verifyPKCE checks an RFC 7636 code verifier against its S256 challenge.
+func verifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
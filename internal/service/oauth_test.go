@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	cases := []struct {
+		name, verifier string
+		want           bool
+	}{
+		{"match", verifier, true},
+		{"other verifier", strings.Replace(verifier, "d", "e", 1), false},
+		{"too short", verifier[:42], false},
+		{"too long", strings.Repeat("a", 129), false},
+		{"empty", "", false},
+	}
+	for _, tc := range cases {
+		if got := verifyPKCE(tc.verifier, challenge); got != tc.want {
+			t.Errorf("%s: verifyPKCE = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestGrantScopes(t *testing.T) {
+	allowed := []string{"profile", "email"}
+	cases := []struct {
+		requested string
+		want      []string
+		err       error
+	}{
+		{"", []string{"profile", "email"}, nil},
+		{"profile", []string{"profile"}, nil},
+		{"profile email profile", []string{"email", "profile"}, nil},
+		{"admin", nil, enum.ErrOAuthInvalidScope},
+		{"profile admin", nil, enum.ErrOAuthInvalidScope},
+	}
+	for _, tc := range cases {
+		got, err := grantScopes(tc.requested, allowed)
+		if !errors.Is(err, tc.err) || !slices.Equal(got, tc.want) {
+			t.Errorf("grantScopes(%q) = %v, %v; want %v, %v", tc.requested, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestResolveRedirectURI(t *testing.T) {
+	one := &repository.OAuthClientModel{RedirectURIs: []string{"https://app.example/cb?x=1"}}
+	two := &repository.OAuthClientModel{RedirectURIs: []string{"https://app.example/cb", "com.example.app:/cb"}}
+	cases := []struct {
+		name      string
+		client    *repository.OAuthClientModel
+		requested string
+		want      string
+	}{
+		{"single default", one, "", "https://app.example/cb?x=1"},
+		{"exact", two, "com.example.app:/cb", "com.example.app:/cb"},
+		{"omitted with several", two, "", ""},
+		{"prefix only", two, "https://app.example/cb/evil", ""},
+		{"unregistered", one, "https://evil.example/cb", ""},
+	}
+	for _, tc := range cases {
+		u, err := resolveRedirectURI(tc.client, tc.requested)
+		if tc.want == "" {
+			if !errors.Is(err, enum.ErrOAuthInvalidRequest) {
+				t.Errorf("%s: expected invalid request, got %v", tc.name, err)
+			}
+			continue
+		}
+		if err != nil || u.String() != tc.want {
+			t.Errorf("%s: resolveRedirectURI = %v, %v; want %s", tc.name, u, err, tc.want)
+		}
+	}
+}
+
+func TestAllowsGrant(t *testing.T) {
+	code := &repository.OAuthClientModel{GrantTypes: []string{GrantAuthorizationCode}}
+	machine := &repository.OAuthClientModel{GrantTypes: []string{GrantClientCredentials}}
+	cases := []struct {
+		client *repository.OAuthClientModel
+		grant  string
+		want   bool
+	}{
+		{code, GrantAuthorizationCode, true},
+		{code, GrantRefreshToken, true},
+		{code, GrantClientCredentials, false},
+		{machine, GrantClientCredentials, true},
+		{machine, GrantRefreshToken, false},
+	}
+	for _, tc := range cases {
+		if got := allowsGrant(tc.client, tc.grant); got != tc.want {
+			t.Errorf("allowsGrant(%v, %s) = %v, want %v", tc.client.GrantTypes, tc.grant, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// OpenID Connect scopes. ScopeProfile is shared with the plain OAuth API.
+const (
+	ScopeOpenID = "openid"
+	ScopeEmail  = "email"
+)
+
+// Authentication method references (RFC 8176) recorded on sessions.
+const (
+	AMRPassword    = "pwd"
+	AMROTP         = "otp"
+	AMRMFA         = "mfa"
+	AMRHardwareKey = "hwk"
+	// AMRFederated is not registered in RFC 8176; it marks a login through
+	// an external identity provider, as Azure AD does.
+	AMRFederated = "fed"
+	// AMREmail is not registered either; it marks a login with a code or
+	// link sent by email.
+	AMREmail = "email"
+)
+
+// IDClaims is the payload of an OpenID Connect ID token. Email claims are
+// only set when the email scope was granted; profile claims are served by
+// UserInfo.
+type IDClaims struct {
+	AuthTime      int64    `json:"auth_time"`
+	Nonce         string   `json:"nonce,omitempty"`
+	AMR           []string `json:"amr,omitempty"`
+	Email         string   `json:"email,omitempty"`
+	EmailVerified *bool    `json:"email_verified,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// UserInfo is the OpenID Connect UserInfo response, filtered by scope.
+type UserInfo struct {
+	Sub           string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	UpdatedAt     int64  `json:"updated_at,omitempty"`
+}
+
+// OIDCDiscovery is the OpenID Provider Metadata document.
+type OIDCDiscovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// OIDCEnabled reports whether the server acts as an OpenID Provider. Relying
+// parties verify ID tokens against the JWKS, so this takes an asymmetric
+// signing key (JWT_KEY_FILES); with the HS256 secrets the openid scope is
+// refused and there is no discovery document.
+func (as *AuthService) OIDCEnabled() bool {
+	return as.jwtCfg.AccessKeys.Asymmetric()
+}
+
+// checkOIDCScope refuses the openid scope while OIDCEnabled is false.
+func (as *AuthService) checkOIDCScope(scopes []string) error {
+	if slices.Contains(scopes, ScopeOpenID) && !as.OIDCEnabled() {
+		return fmt.Errorf("%w: %s: %v", enum.ErrOAuthInvalidScope, ScopeOpenID, enum.ErrOIDCDisabled)
+	}
+	return nil
+}
+
+// Discovery describes the provider. Endpoint URLs are built from
+// JWTConfig.OIDCIssuer, which must be the public URL of this server.
+func (as *AuthService) Discovery() (*OIDCDiscovery, error) {
+	if !as.OIDCEnabled() {
+		return nil, enum.ErrOIDCDisabled
+	}
+	base := strings.TrimSuffix(as.jwtCfg.OIDCIssuer, "/")
+	return &OIDCDiscovery{
+		Issuer:                            base,
+		AuthorizationEndpoint:             base + "/api/v1/oauth/authorize",
+		TokenEndpoint:                     base + "/api/v1/oauth/token",
+		UserinfoEndpoint:                  base + "/api/v1/userinfo",
+		JWKSURI:                           base + "/.well-known/jwks.json",
+		RevocationEndpoint:                base + "/api/v1/oauth/revoke",
+		IntrospectionEndpoint:             base + "/api/v1/oauth/introspect",
+		ScopesSupported:                   []string{ScopeOpenID, ScopeProfile, ScopeEmail},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{GrantAuthorizationCode, GrantRefreshToken, GrantClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  as.jwtCfg.AccessKeys.PublicAlgs(),
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{pkceS256},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "amr", "email", "email_verified", "name", "picture", "updated_at"},
+	}, nil
+}
+
+// makeIDToken signs an ID token for clientID with the active access token
+// key, whose public half is published in the JWKS.
+func (as *AuthService) makeIDToken(u *repository.UserModel, sess *Session, clientID, nonce string) (string, error) {
+	if !as.OIDCEnabled() {
+		return "", enum.ErrOIDCDisabled
+	}
+	now := time.Now().UTC()
+	claims := &IDClaims{
+		AuthTime: sess.AuthTime,
+		Nonce:    nonce,
+		AMR:      sess.AMR,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    strings.TrimSuffix(as.jwtCfg.OIDCIssuer, "/"),
+			Subject:   u.ID.String(),
+			Audience:  jwt.ClaimStrings{clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(as.jwtCfg.AccessTTL)),
+		},
+	}
+	if slices.Contains(sess.Grant, ScopeEmail) {
+		verified := u.EmailVerifiedAt != nil
+		claims.Email, claims.EmailVerified = u.Email, &verified
+	}
+	return as.jwtCfg.AccessKeys.Sign(claims)
+}
+
+// UserInfo returns the claims the scopes of the caller's token allow. The
+// token must carry the openid scope.
+func (as *AuthService) UserInfo(ctx context.Context, userID string, scopes []string) (*UserInfo, error) {
+	if !slices.Contains(scopes, ScopeOpenID) {
+		return nil, enum.ErrInsufficientScope
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	info := &UserInfo{Sub: u.ID.String()}
+	if slices.Contains(scopes, ScopeEmail) {
+		verified := u.EmailVerifiedAt != nil
+		info.Email, info.EmailVerified = u.Email, &verified
+	}
+	if slices.Contains(scopes, ScopeProfile) {
+		info.Name, info.Picture, info.UpdatedAt = u.FullName, u.AvatarURL, u.UpdatedAt.Unix()
+	}
+	return info, nil
+}
+
+// authTime returns when the user behind sess authenticated. Sessions
+// created before auth_time was recorded fall back to their creation time.
+func authTime(sess *Session) int64 {
+	if sess.AuthTime != 0 {
+		return sess.AuthTime
+	}
+	if t, err := time.Parse(time.RFC3339, sess.CreatedAt); err == nil {
+		return t.Unix()
+	}
+	return time.Now().Unix()
+}
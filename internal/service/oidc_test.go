@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/keyring"
+	"seno-blackdragon/pkg/oidcclient"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// oidcTestService signs with an Ed25519 key, as OpenID Connect needs a key
+// relying parties can fetch from the JWKS.
+func oidcTestService(t *testing.T) *AuthService {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ed.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	kr, err := keyring.LoadPEMFiles("ed="+path, "ed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &AuthService{jwtCfg: JWTConfig{
+		AccessKeys: kr,
+		AccessTTL:  time.Minute,
+		OIDCIssuer: "http://localhost:8080/",
+	}}
+}
+
+func TestMakeIDToken(t *testing.T) {
+	as := oidcTestService(t)
+	u := &repository.UserModel{ID: uuid.New(), Email: "a@example.com"}
+	sess := &Session{AuthTime: 1700000000, AMR: []string{AMRPassword, AMROTP, AMRMFA}}
+	cases := []struct {
+		name      string
+		grant     []string
+		nonce     string
+		wantEmail bool
+	}{
+		{"openid only", []string{ScopeOpenID}, "n-1", false},
+		{"with email", []string{ScopeEmail, ScopeOpenID}, "", true},
+	}
+	for _, tc := range cases {
+		sess.Grant = tc.grant
+		raw, err := as.makeIDToken(u, sess, "cli_1", tc.nonce)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		var claims IDClaims
+		_, err = jwt.ParseWithClaims(raw, &claims, as.jwtCfg.AccessKeys.Keyfunc,
+			jwt.WithIssuer("http://localhost:8080"), jwt.WithAudience("cli_1"))
+		if err != nil {
+			t.Fatalf("%s: parse: %v", tc.name, err)
+		}
+		if claims.Subject != u.ID.String() || claims.Nonce != tc.nonce || claims.AuthTime != sess.AuthTime || !slices.Equal(claims.AMR, sess.AMR) {
+			t.Errorf("%s: unexpected claims %+v", tc.name, claims)
+		}
+		if gotEmail := claims.Email != ""; gotEmail != tc.wantEmail {
+			t.Errorf("%s: email claim present = %v, want %v", tc.name, gotEmail, tc.wantEmail)
+		}
+		if tc.wantEmail && (claims.EmailVerified == nil || *claims.EmailVerified) {
+			t.Errorf("%s: expected email_verified=false", tc.name)
+		}
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	d, err := oidcTestService(t).Discovery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Issuer != "http://localhost:8080" {
+		t.Errorf("issuer = %q", d.Issuer)
+	}
+	if d.JWKSURI != "http://localhost:8080/.well-known/jwks.json" || d.TokenEndpoint != "http://localhost:8080/api/v1/oauth/token" {
+		t.Errorf("unexpected endpoints %+v", d)
+	}
+	if !slices.Equal(d.IDTokenSigningAlgValuesSupported, []string{"EdDSA"}) {
+		t.Errorf("algs = %v", d.IDTokenSigningAlgValuesSupported)
+	}
+}
+
+func TestOIDCNeedsAsymmetricKey(t *testing.T) {
+	as := &AuthService{jwtCfg: JWTConfig{
+		AccessKeys: keyring.NewHMAC("test", []byte("secret")),
+		AccessTTL:  time.Minute,
+		OIDCIssuer: "http://localhost:8080/",
+	}}
+	if as.OIDCEnabled() {
+		t.Fatal("OIDC enabled with an HS256 secret")
+	}
+	if _, err := as.Discovery(); !errors.Is(err, enum.ErrOIDCDisabled) {
+		t.Errorf("Discovery: got %v, want ErrOIDCDisabled", err)
+	}
+	if err := as.checkOIDCScope([]string{ScopeProfile, ScopeOpenID}); !errors.Is(err, enum.ErrOAuthInvalidScope) {
+		t.Errorf("openid scope: got %v, want ErrOAuthInvalidScope", err)
+	}
+	if err := as.checkOIDCScope([]string{ScopeProfile}); err != nil {
+		t.Errorf("profile scope: %v", err)
+	}
+	sess := &Session{Grant: []string{ScopeOpenID}}
+	if _, err := as.makeIDToken(&repository.UserModel{ID: uuid.New()}, sess, "cli_1", ""); !errors.Is(err, enum.ErrOIDCDisabled) {
+		t.Errorf("makeIDToken: got %v, want ErrOIDCDisabled", err)
+	}
+}
+
+// TestIDTokenRelyingParty verifies an ID token the way a relying party does:
+// discovery, then the JWKS, then the signature and claims.
+func TestIDTokenRelyingParty(t *testing.T) {
+	as := oidcTestService(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		d, err := as.Discovery()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(d)
+	})
+	mux.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(as.jwtCfg.AccessKeys.JWKS())
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	as.jwtCfg.OIDCIssuer = srv.URL
+
+	verified := time.Now()
+	u := &repository.UserModel{ID: uuid.New(), Email: "a@example.com", EmailVerifiedAt: &verified}
+	sess := &Session{AuthTime: time.Now().Unix(), Grant: []string{ScopeOpenID, ScopeEmail}}
+	raw, err := as.makeIDToken(u, sess, "cli_1", "n-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rp := oidcclient.New(oidcclient.Config{Issuer: srv.URL, ClientID: "cli_1"}, srv.Client())
+	claims, err := rp.VerifyIDToken(context.Background(), raw, "n-1")
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if claims.Subject != u.ID.String() || claims.Email != u.Email || !claims.EmailVerified {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	other := oidcclient.New(oidcclient.Config{Issuer: srv.URL, ClientID: "cli_2"}, srv.Client())
+	if _, err := other.VerifyIDToken(context.Background(), raw, "n-1"); !errors.Is(err, oidcclient.ErrInvalidIDToken) {
+		t.Errorf("other audience: got %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestAuthTime(t *testing.T) {
+	cases := []struct {
+		sess *Session
+		want int64
+	}{
+		{&Session{AuthTime: 42, CreatedAt: "2026-01-01T00:00:00Z"}, 42},
+		{&Session{CreatedAt: "2026-01-01T00:00:00Z"}, 1767225600},
+	}
+	for _, tc := range cases {
+		if got := authTime(tc.sess); got != tc.want {
+			t.Errorf("authTime(%+v) = %d, want %d", tc.sess, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/webauthn"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	webauthnChallengeTTL = 5 * time.Minute
+	defaultPasskeyName   = "Passkey"
+)
+
+// Ceremony kinds stored with a WebAuthn challenge.
+const (
+	ceremonyRegister = "register"
+	ceremonyLogin    = "login"
+)
+
+// webauthnCeremony is parked in Redis under its challenge until the browser
+// answers. UserID is the user registering a passkey.
+type webauthnCeremony struct {
+	Kind   string `json:"kind"`
+	UserID string `json:"user_id,omitempty"`
+}
+
+// Passkey is a passkey registered by the user.
+type Passkey struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Transports     []string   `json:"transports,omitempty"`
+	BackupEligible bool       `json:"backup_eligible"` // synced between the user's devices
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+}
+
+func newPasskeyView(m *repository.PasskeyModel) Passkey {
+	return Passkey{
+		ID:             m.ID.String(),
+		Name:           m.Name,
+		Transports:     m.Transports,
+		BackupEligible: m.BackupEligible,
+		CreatedAt:      m.CreatedAt,
+		LastUsedAt:     m.LastUsedAt,
+	}
+}
+
+// newCeremony stores a fresh challenge for c.
+func (as *AuthService) newCeremony(ctx context.Context, c webauthnCeremony) ([]byte, error) {
+	challenge, err := randomToken("", 32)
+	if err != nil {
+		return nil, err
+	}
+	b, _ := json.Marshal(c)
+	if err := as.redis.Set(ctx, keys.WebAuthnChallenge(challenge), b, webauthnChallengeTTL).Err(); err != nil {
+		return nil, err
+	}
+	return base64.RawURLEncoding.DecodeString(challenge)
+}
+
+// takeCeremony consumes the ceremony whose challenge the browser signed in
+// clientDataJSON, so each challenge is good for one attempt.
+func (as *AuthService) takeCeremony(ctx context.Context, clientDataJSON []byte, kind string) (*webauthnCeremony, []byte, error) {
+	cd, err := webauthn.ParseClientData(clientDataJSON)
+	if err != nil {
+		return nil, nil, enum.ErrPasskeyInvalid
+	}
+	raw, err := as.redis.GetDel(ctx, keys.WebAuthnChallenge(cd.Challenge)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil, enum.ErrPasskeyChallengeInvalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var c webauthnCeremony
+	if err := json.Unmarshal(raw, &c); err != nil || c.Kind != kind {
+		return nil, nil, enum.ErrPasskeyChallengeInvalid
+	}
+	challenge, err := base64.RawURLEncoding.DecodeString(cd.Challenge)
+	if err != nil {
+		return nil, nil, enum.ErrPasskeyChallengeInvalid
+	}
+	return &c, challenge, nil
+}
+
+// BeginPasskeyRegistration returns the options for
+// navigator.credentials.create. The user's existing passkeys are excluded so
+// an authenticator is not registered twice.
+func (as *AuthService) BeginPasskeyRegistration(ctx context.Context, userID string) (*webauthn.CreationOptions, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := as.passkeyRepo.ListByUserID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	exclude := make([]webauthn.CredentialDescriptor, 0, len(existing))
+	for _, k := range existing {
+		exclude = append(exclude, webauthn.CredentialDescriptor{Type: "public-key", ID: k.CredentialID, Transports: k.Transports})
+	}
+	challenge, err := as.newCeremony(ctx, webauthnCeremony{Kind: ceremonyRegister, UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	displayName := u.FullName
+	if displayName == "" {
+		displayName = u.Email
+	}
+	rp := as.policy.WebAuthn
+	return &webauthn.CreationOptions{
+		Challenge:          challenge,
+		RP:                 webauthn.RPEntity{ID: rp.ID, Name: rp.Name},
+		User:               webauthn.UserEntity{ID: uid[:], Name: u.Email, DisplayName: displayName},
+		PubKeyCredParams:   webauthn.PublicKeyParams(),
+		Timeout:            webauthnChallengeTTL.Milliseconds(),
+		ExcludeCredentials: exclude,
+		// discoverable, so the login needs no email
+		AuthenticatorSelection: webauthn.AuthenticatorSelection{ResidentKey: "required", UserVerification: "preferred"},
+		Attestation:            "none",
+	}, nil
+}
+
+// FinishPasskeyRegistration verifies the browser's response and stores the
+// new passkey.
+func (as *AuthService) FinishPasskeyRegistration(ctx context.Context, userID string, cmd model.PasskeyRegistrationCmd) (*Passkey, error) {
+	c, challenge, err := as.takeCeremony(ctx, cmd.ClientDataJSON, ceremonyRegister)
+	if err != nil {
+		return nil, err
+	}
+	if c.UserID != userID {
+		return nil, enum.ErrPasskeyChallengeInvalid
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	cred, err := as.policy.WebAuthn.VerifyRegistration(challenge, cmd.ClientDataJSON, cmd.AttestationObject)
+	if err != nil {
+		as.log.Warn("passkey_registration_invalid", zap.String("user_id", userID), zap.Error(err))
+		return nil, enum.ErrPasskeyInvalid
+	}
+	name := strings.TrimSpace(cmd.Name)
+	if name == "" {
+		name = defaultPasskeyName
+	}
+	key, err := as.passkeyRepo.Create(ctx, repository.PasskeyModel{
+		UserID:         uid,
+		CredentialID:   cred.ID,
+		PublicKey:      cred.PublicKey,
+		SignCount:      cred.SignCount,
+		Transports:     cmd.Transports,
+		AAGUID:         cred.AAGUID,
+		BackupEligible: cred.BackupEligible,
+		Name:           name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	as.audit(ctx, AuditEvent{
+		Type:     AuditPasskeyRegister,
+		ActorID:  userID,
+		Metadata: map[string]any{"passkey_id": key.ID.String(), "name": name},
+	})
+	view := newPasskeyView(key)
+	return &view, nil
+}
+
+// BeginPasskeyLogin returns the options for navigator.credentials.get. No
+// credentials are listed: the authenticator offers the user's discoverable
+// passkeys for this relying party.
+func (as *AuthService) BeginPasskeyLogin(ctx context.Context) (*webauthn.RequestOptions, error) {
+	challenge, err := as.newCeremony(ctx, webauthnCeremony{Kind: ceremonyLogin})
+	if err != nil {
+		return nil, err
+	}
+	return &webauthn.RequestOptions{
+		Challenge:        challenge,
+		Timeout:          webauthnChallengeTTL.Milliseconds(),
+		RPID:             as.policy.WebAuthn.ID,
+		AllowCredentials: []webauthn.CredentialDescriptor{},
+		UserVerification: "preferred",
+	}, nil
+}
+
+// FinishPasskeyLogin verifies an assertion and logs the owner of the passkey
+// in. A passkey that verified the user (PIN or biometrics) is two factors on
+// its own, so the session gets MFA without a TOTP challenge.
+func (as *AuthService) FinishPasskeyLogin(ctx context.Context, assertion model.PasskeyLoginCmd, cmd model.LoginCmd) (*model.LoginResult, error) {
+	_, challenge, err := as.takeCeremony(ctx, assertion.ClientDataJSON, ceremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+	key, err := as.passkeyRepo.GetByCredentialID(ctx, assertion.CredentialID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		as.auditLoginFailure(ctx, cmd, "", "unknown_passkey")
+		return nil, enum.ErrPasskeyInvalid
+	}
+	if assertion.UserHandle != nil && string(assertion.UserHandle) != string(key.UserID[:]) {
+		as.auditLoginFailure(ctx, cmd, key.UserID.String(), "bad_passkey")
+		return nil, enum.ErrPasskeyInvalid
+	}
+	res, err := as.policy.WebAuthn.VerifyAssertion(challenge, assertion.ClientDataJSON, assertion.AuthenticatorData, assertion.Signature, key.PublicKey, key.SignCount)
+	if err != nil {
+		as.log.Warn("passkey_assertion_invalid", zap.String("user_id", key.UserID.String()), zap.String("passkey_id", key.ID.String()), zap.Error(err))
+		as.auditLoginFailure(ctx, cmd, key.UserID.String(), "bad_passkey")
+		return nil, enum.ErrPasskeyInvalid
+	}
+	if err := as.passkeyRepo.UpdateUsage(ctx, key.ID, res.SignCount); err != nil {
+		return nil, err
+	}
+	u, err := as.userRepo.GetUserByID(ctx, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Email = u.Email
+	grant := sessionGrant{AMR: []string{AMRHardwareKey}}
+	if res.UserVerified {
+		grant.MFA = true
+		grant.AMR = append(grant.AMR, AMRMFA)
+	}
+	return as.completeLogin(ctx, u, cmd, grant)
+}
+
+func (as *AuthService) ListPasskeys(ctx context.Context, userID string) ([]Passkey, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	passkeys, err := as.passkeyRepo.ListByUserID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]Passkey, 0, len(passkeys))
+	for i := range passkeys {
+		views = append(views, newPasskeyView(&passkeys[i]))
+	}
+	return views, nil
+}
+
+func (as *AuthService) RenamePasskey(ctx context.Context, userID, passkeyID, name string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return enum.ErrUserNotFound
+	}
+	id, err := uuid.Parse(passkeyID)
+	if err != nil {
+		return enum.ErrPasskeyNotFound
+	}
+	ok, err := as.passkeyRepo.Rename(ctx, uid, id, strings.TrimSpace(name))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return enum.ErrPasskeyNotFound
+	}
+	return nil
+}
+
+// DeletePasskey removes one of the user's passkeys, unless it is the only
+// way left to log in.
+func (as *AuthService) DeletePasskey(ctx context.Context, userID, passkeyID string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return enum.ErrUserNotFound
+	}
+	id, err := uuid.Parse(passkeyID)
+	if err != nil {
+		return enum.ErrPasskeyNotFound
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	passkeys, err := as.passkeyRepo.ListByUserID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(passkeys, func(m repository.PasskeyModel) bool { return m.ID == id }) {
+		return enum.ErrPasskeyNotFound
+	}
+	if err := as.checkLastLoginMethod(ctx, u); err != nil {
+		return err
+	}
+	ok, err := as.passkeyRepo.Delete(ctx, uid, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return enum.ErrPasskeyNotFound
+	}
+	as.audit(ctx, AuditEvent{
+		Type:     AuditPasskeyDelete,
+		ActorID:  userID,
+		Metadata: map[string]any{"passkey_id": passkeyID},
+	})
+	return nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/notify"
+	"seno-blackdragon/pkg/pass"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	passwordResetTTL      = 30 * time.Minute
+	passwordResetThrottle = time.Minute // one reset mail per user per minute
+)
+
+// checkPassword verifies a password with the hasher that produced the stored
+// hash. Hashes from an older algorithm or weaker parameters are upgraded to
+// the configured hasher on the spot; failing to do so does not fail the login.
+func (as *AuthService) checkPassword(ctx context.Context, u *repository.UserModel, password string) bool {
+	ok, _ := pass.Detect(u.PasswordHash, as.argon2id, as.bcrypt).Verify(password, u.PasswordHash)
+	if !ok {
+		return false
+	}
+	if as.hasher.RehashNeeded(u.PasswordHash) {
+		hash, err := as.hasher.Hash(password)
+		if err == nil {
+			err = as.userRepo.UpdatePassword(ctx, u.ID, hash)
+		}
+		if err != nil {
+			as.log.Warn("password rehash failed", zap.String("user_id", u.ID.String()), zap.Error(err))
+		} else {
+			u.PasswordHash = hash
+		}
+	}
+	return true
+}
+
+// ChangePassword replaces the password of a logged-in user after checking the
+// current one. Every other session and all personal access tokens are
+// revoked; the caller's session stays alive.
+func (as *AuthService) ChangePassword(ctx context.Context, userID, currentSID, currentPassword, newPassword string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return enum.ErrUserNotFound
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if ok, _ := pass.Detect(u.PasswordHash, as.argon2id, as.bcrypt).Verify(currentPassword, u.PasswordHash); !ok {
+		return enum.ErrInvalidCredentials
+	}
+	if err := pass.VerifyPassword(newPassword); err != nil {
+		return fmt.Errorf("%w: %v", enum.ErrWeakPassword, err)
+	}
+	hash, err := as.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := as.userRepo.UpdatePassword(ctx, uid, hash); err != nil {
+		return err
+	}
+	revoked, err := as.revokeOtherSessions(ctx, userID, currentSID)
+	if err != nil {
+		return err
+	}
+	tokens, err := as.revokePersonalTokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditPasswordChange, SubjectID: userID, Metadata: map[string]any{"revoked_sessions": revoked, "api_tokens": tokens}})
+	return nil
+}
+
+// ForgotPassword issues a single-use reset token and hands it to the notifier.
+// Unknown emails succeed silently so the endpoint cannot be used to probe
+// which accounts exist.
+func (as *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	u, err := as.userRepo.GetUserByEmail(ctx, email)
+	if errors.Is(err, enum.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	uid := u.ID.String()
+	fresh, err := as.redis.SetNX(ctx, keys.PasswordResetThrottle(uid), "1", passwordResetThrottle).Result()
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return nil
+	}
+	return as.sendPasswordReset(ctx, u)
+}
+
+// sendPasswordReset stores a new reset token for the user, replacing any
+// earlier one, and mails it.
+func (as *AuthService) sendPasswordReset(ctx context.Context, u *repository.UserModel) error {
+	uid := u.ID.String()
+	token, err := randomToken("pwr_", 32)
+	if err != nil {
+		return err
+	}
+	// only the latest token stays valid
+	if old, err := as.redis.GetDel(ctx, keys.PasswordResetUser(uid)).Result(); err == nil {
+		_ = as.redis.Del(ctx, keys.PasswordReset(old)).Err()
+	}
+	h := hashToken(token)
+	pipe := as.redis.TxPipeline()
+	pipe.Set(ctx, keys.PasswordReset(h), uid, passwordResetTTL)
+	pipe.Set(ctx, keys.PasswordResetUser(uid), h, passwordResetTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	return as.notifier.Send(ctx, notify.Message{
+		Kind: notify.KindPasswordReset,
+		To:   u.Email,
+		Data: map[string]string{
+			"token":      token,
+			"expires_at": time.Now().UTC().Add(passwordResetTTL).Format(time.RFC3339),
+		},
+	})
+}
+
+// ResetPassword consumes a reset token, stores the new hash and logs the user
+// out everywhere so refresh tokens obtained with the old password die.
+func (as *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if err := pass.VerifyPassword(newPassword); err != nil {
+		return fmt.Errorf("%w: %v", enum.ErrWeakPassword, err)
+	}
+	uid, err := as.redis.GetDel(ctx, keys.PasswordReset(hashToken(token))).Result()
+	if errors.Is(err, redis.Nil) {
+		return enum.ErrResetTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+	_ = as.redis.Del(ctx, keys.PasswordResetUser(uid)).Err()
+
+	id, err := uuid.Parse(uid)
+	if err != nil {
+		return enum.ErrResetTokenInvalid
+	}
+	hash, err := as.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := as.userRepo.UpdatePassword(ctx, id, hash); err != nil {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditPasswordReset, SubjectID: uid})
+	return as.LogoutAll(ctx, uid)
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/notify"
+
+	cryptoRand "crypto/rand"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	loginCodeTTL         = 10 * time.Minute
+	loginCodeThrottle    = time.Minute // one login mail per email per minute
+	loginCodeMaxAttempts = 5
+	loginCodeDigits      = 6
+)
+
+// loginCode is the pending email login of an address. Only hashes are
+// stored; the code is bound to the email so equal codes hash differently.
+type loginCode struct {
+	CodeHash  string `json:"code_hash"`
+	TokenHash string `json:"token_hash"`
+}
+
+func hashLoginCode(email, code string) string { return hashToken(email + ":" + code) }
+
+// randomDigits returns n random decimal digits.
+func randomDigits(n int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+	v, err := cryptoRand.Int(cryptoRand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", n, v), nil
+}
+
+// StartEmailLogin mails a single-use login code and link to email. Unknown
+// emails only get one when the policy allows signing up that way; either way
+// the call succeeds, so it cannot be used to probe which accounts exist.
+func (as *AuthService) StartEmailLogin(ctx context.Context, email string) error {
+	email = repository.NormalizeEmail(email)
+	_, err := as.userRepo.GetUserByEmail(ctx, email)
+	if errors.Is(err, enum.ErrUserNotFound) {
+		if !as.policy.PasswordlessSignup {
+			return nil
+		}
+	} else if err != nil {
+		return err
+	}
+	fresh, err := as.redis.SetNX(ctx, keys.LoginCodeThrottle(email), "1", loginCodeThrottle).Result()
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return nil
+	}
+	code, err := randomDigits(loginCodeDigits)
+	if err != nil {
+		return err
+	}
+	token, err := randomToken("lgn_", 32)
+	if err != nil {
+		return err
+	}
+	// only the latest code and link stay valid
+	if raw, err := as.redis.Get(ctx, keys.LoginCode(email)).Bytes(); err == nil {
+		var old loginCode
+		if json.Unmarshal(raw, &old) == nil {
+			_ = as.redis.Del(ctx, keys.LoginCodeLink(old.TokenHash)).Err()
+		}
+	}
+	st := loginCode{CodeHash: hashLoginCode(email, code), TokenHash: hashToken(token)}
+	b, _ := json.Marshal(st)
+	pipe := as.redis.TxPipeline()
+	pipe.Set(ctx, keys.LoginCode(email), b, loginCodeTTL)
+	pipe.Set(ctx, keys.LoginCodeLink(st.TokenHash), email, loginCodeTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	return as.notifier.Send(ctx, notify.Message{
+		Kind: notify.KindLoginCode,
+		To:   email,
+		Data: map[string]string{
+			"code":       code,
+			"token":      token,
+			"expires_at": time.Now().UTC().Add(loginCodeTTL).Format(time.RFC3339),
+		},
+	})
+}
+
+// VerifyEmailLogin redeems a code or link sent by StartEmailLogin and logs
+// the user in, creating the account on first use when the policy allows.
+func (as *AuthService) VerifyEmailLogin(ctx context.Context, lc model.LoginCodeCmd, cmd model.LoginCmd) (*model.LoginResult, error) {
+	email, err := as.redeemEmailLogin(ctx, lc, cmd.IP)
+	if err != nil {
+		if errors.Is(err, enum.ErrLoginCodeInvalid) {
+			cmd.Email = email
+			as.auditLoginFailure(ctx, cmd, "", "bad_login_code")
+		}
+		return nil, err
+	}
+	u, err := as.emailLoginUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Email = u.Email
+	return as.completeLogin(ctx, u, cmd, sessionGrant{AMR: []string{AMREmail}})
+}
+
+// redeemEmailLogin returns the email a code or link was sent to. Codes are
+// short, so wrong ones count as failed logins of the email: they feed the
+// lockout and its progressive delay, and a new mail does not reset them.
+// Links are not guessable and only count against the IP.
+func (as *AuthService) redeemEmailLogin(ctx context.Context, lc model.LoginCodeCmd, ip string) (string, error) {
+	email := ""
+	if lc.Token == "" {
+		email = repository.NormalizeEmail(lc.Email)
+	}
+	if err := as.checkLockout(ctx, email, ip); err != nil {
+		return email, err
+	}
+	var err error
+	if lc.Token != "" {
+		email, err = as.redeemLoginLink(ctx, lc.Token)
+	} else {
+		err = as.redeemLoginCode(ctx, email, lc.Code)
+	}
+	if errors.Is(err, enum.ErrLoginCodeInvalid) {
+		as.recordLoginFailure(ctx, email, ip)
+	}
+	if err != nil {
+		return email, err
+	}
+	as.clearLoginFailures(ctx, email)
+	return email, nil
+}
+
+func (as *AuthService) redeemLoginLink(ctx context.Context, token string) (string, error) {
+	email, err := as.redis.GetDel(ctx, keys.LoginCodeLink(hashToken(token))).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", enum.ErrLoginCodeInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+	_ = as.redis.Del(ctx, keys.LoginCode(email), keys.LoginCodeAttempts(email)).Err()
+	return email, nil
+}
+
+func (as *AuthService) redeemLoginCode(ctx context.Context, email, code string) error {
+	raw, err := as.redis.Get(ctx, keys.LoginCode(email)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return enum.ErrLoginCodeInvalid
+	}
+	if err != nil {
+		return err
+	}
+	var st loginCode
+	if err := json.Unmarshal(raw, &st); err != nil {
+		return enum.ErrLoginCodeInvalid
+	}
+	// the counter outlives the code: asking for a new one does not buy more
+	// guesses, only a successful login or the end of the window resets it
+	attempts, err := as.redis.Incr(ctx, keys.LoginCodeAttempts(email)).Result()
+	if err != nil {
+		return err
+	}
+	if attempts == 1 {
+		_ = as.redis.Expire(ctx, keys.LoginCodeAttempts(email), loginCodeTTL).Err()
+	}
+	if attempts > loginCodeMaxAttempts {
+		_ = as.redis.Del(ctx, keys.LoginCode(email), keys.LoginCodeLink(st.TokenHash)).Err()
+		return enum.ErrLoginCodeInvalid
+	}
+	if subtle.ConstantTimeCompare([]byte(hashLoginCode(email, code)), []byte(st.CodeHash)) != 1 {
+		return enum.ErrLoginCodeInvalid
+	}
+	// the code is single use
+	if n, err := as.redis.Del(ctx, keys.LoginCode(email)).Result(); err != nil || n == 0 {
+		return enum.ErrLoginCodeInvalid
+	}
+	_ = as.redis.Del(ctx, keys.LoginCodeLink(st.TokenHash), keys.LoginCodeAttempts(email)).Err()
+	return nil
+}
+
+// emailLoginUser returns the account of email, signing up a new one without
+// a password if the policy allows. Redeeming the code proves the address, so
+// the email counts as verified.
+func (as *AuthService) emailLoginUser(ctx context.Context, email string) (*repository.UserModel, error) {
+	u, err := as.userRepo.GetUserByEmail(ctx, email)
+	if err == nil {
+		if u.EmailVerifiedAt == nil {
+			if _, err := as.userRepo.MarkEmailVerified(ctx, u.ID); err != nil {
+				return nil, err
+			}
+			return as.userRepo.GetUserByID(ctx, u.ID)
+		}
+		return u, nil
+	}
+	if !errors.Is(err, enum.ErrUserNotFound) {
+		return nil, err
+	}
+	if !as.policy.PasswordlessSignup {
+		return nil, enum.ErrLoginCodeInvalid
+	}
+	id, err := as.userRepo.CreateUser(ctx, &repository.UserModel{Email: email})
+	if errors.Is(err, enum.ErrEmailAlready) {
+		// signed up by a concurrent request
+		return as.emailLoginUser(ctx, email)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := as.rbacRepo.AssignRole(ctx, id, DefaultRole); err != nil {
+		as.log.Warn("assign_default_role_failed", zap.String("user_id", id.String()), zap.Error(err))
+	}
+	if _, err := as.userRepo.MarkEmailVerified(ctx, id); err != nil {
+		return nil, err
+	}
+	return as.userRepo.GetUserByID(ctx, id)
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"seno-blackdragon/internal/db/dbtest"
+	"seno-blackdragon/internal/db/user"
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/notify"
+	"seno-blackdragon/pkg/pass"
+	"seno-blackdragon/pkg/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func TestRandomDigits(t *testing.T) {
+	for range 50 {
+		code, err := randomDigits(loginCodeDigits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != loginCodeDigits {
+			t.Fatalf("Expected %d digits, got %q", loginCodeDigits, code)
+		}
+		for _, c := range code {
+			if c < '0' || c > '9' {
+				t.Fatalf("Expected only digits, got %q", code)
+			}
+		}
+	}
+}
+
+func TestHashLoginCodeBindsEmail(t *testing.T) {
+	if hashLoginCode("a@example.com", "123456") == hashLoginCode("b@example.com", "123456") {
+		t.Error("Expected the same code to hash differently for different emails")
+	}
+}
+
+// mailbox is a notifier that keeps what it was asked to send.
+type mailbox struct {
+	mu   sync.Mutex
+	msgs []notify.Message
+}
+
+func (m *mailbox) Send(_ context.Context, msg notify.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.msgs = append(m.msgs, msg)
+	return nil
+}
+
+func (m *mailbox) last(t *testing.T) notify.Message {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.msgs) == 0 {
+		t.Fatal("no message sent")
+	}
+	return m.msgs[len(m.msgs)-1]
+}
+
+// newTestService is an AuthService over miniredis whose repositories all
+// read from db.
+func newTestService(t *testing.T, db *dbtest.DB, policy Policy) (*AuthService, *miniredis.Miniredis, *mailbox) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	mb := &mailbox{}
+	as := NewAuthService(repository.NewUserRepo(db), repository.NewMFARepo(db), repository.NewRBACRepo(db), nil,
+		repository.NewOAuthRepo(db), repository.NewIdentityRepo(db), repository.NewPasskeyRepo(db), repository.NewAPITokenRepo(db),
+		pass.Config{Algo: pass.AlgoBcrypt, BcryptCost: 4}, rdb, mb, JWTConfig{
+			AccessSecret:  []byte("access-secret-for-tests"),
+			RefreshSecret: []byte("refresh-secret-for-tests"),
+			AccessTTL:     15 * time.Minute,
+			RefreshTTL:    time.Hour,
+			Issuer:        "seno-blackdragon",
+		}, policy, zap.NewNop())
+	return as, mr, mb
+}
+
+const testEmail = "a@example.com"
+
+func emailLoginService(t *testing.T, lockout LockoutPolicy) (*AuthService, *miniredis.Miniredis, *mailbox) {
+	t.Helper()
+	db := dbtest.New().On("GetUserByEmail", dbtest.Row(user.User{
+		ID:       utils.PgUUIDFromUUID(uuid.New()),
+		Email:    pgtype.Text{String: testEmail, Valid: true},
+		IsActive: true,
+	}))
+	return newTestService(t, db, Policy{Lockout: lockout})
+}
+
+// mailCode asks for a login mail and returns its code and link token.
+func mailCode(t *testing.T, as *AuthService, mb *mailbox) (string, string) {
+	t.Helper()
+	if err := as.StartEmailLogin(context.Background(), testEmail); err != nil {
+		t.Fatal(err)
+	}
+	msg := mb.last(t)
+	return msg.Data["code"], msg.Data["token"]
+}
+
+func redeemCode(as *AuthService, code string) error {
+	_, err := as.redeemEmailLogin(context.Background(), model.LoginCodeCmd{Email: testEmail, Code: code}, "198.51.100.7")
+	return err
+}
+
+// wrongCode differs from code in every digit.
+func wrongCode(code string) string {
+	b := []byte(code)
+	for i := range b {
+		b[i] = '0' + (b[i]-'0'+1)%10
+	}
+	return string(b)
+}
+
+func TestEmailLoginCodeSingleUse(t *testing.T) {
+	as, _, mb := emailLoginService(t, LockoutPolicy{})
+	code, token := mailCode(t, as, mb)
+	if err := redeemCode(as, code); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := redeemCode(as, code); !errors.Is(err, enum.ErrLoginCodeInvalid) {
+		t.Errorf("reused code: got %v, want ErrLoginCodeInvalid", err)
+	}
+	// the link of the same mail went with the code
+	if _, err := as.redeemEmailLogin(context.Background(), model.LoginCodeCmd{Token: token}, ""); !errors.Is(err, enum.ErrLoginCodeInvalid) {
+		t.Errorf("link after code: got %v, want ErrLoginCodeInvalid", err)
+	}
+}
+
+func TestEmailLoginAttemptCapSurvivesNewCode(t *testing.T) {
+	as, mr, mb := emailLoginService(t, LockoutPolicy{})
+	code, _ := mailCode(t, as, mb)
+	for i := range loginCodeMaxAttempts {
+		if err := redeemCode(as, wrongCode(code)); !errors.Is(err, enum.ErrLoginCodeInvalid) {
+			t.Fatalf("wrong code %d: got %v", i+1, err)
+		}
+	}
+	if err := redeemCode(as, code); !errors.Is(err, enum.ErrLoginCodeInvalid) {
+		t.Fatalf("right code past the cap: got %v, want ErrLoginCodeInvalid", err)
+	}
+
+	// a new mail after the throttle does not buy new guesses
+	mr.FastForward(loginCodeThrottle + time.Second)
+	code, _ = mailCode(t, as, mb)
+	if err := redeemCode(as, code); !errors.Is(err, enum.ErrLoginCodeInvalid) {
+		t.Fatalf("new code within the window: got %v, want ErrLoginCodeInvalid", err)
+	}
+
+	mr.FastForward(loginCodeTTL)
+	code, _ = mailCode(t, as, mb)
+	if err := redeemCode(as, code); err != nil {
+		t.Fatalf("new code after the window: %v", err)
+	}
+}
+
+func TestEmailLoginFeedsLockout(t *testing.T) {
+	as, mr, mb := emailLoginService(t, LockoutPolicy{DelayAfter: 2, MaxAttempts: 10, Window: time.Hour, Duration: time.Hour})
+	code, _ := mailCode(t, as, mb)
+	for range 2 {
+		if err := redeemCode(as, wrongCode(code)); !errors.Is(err, enum.ErrLoginCodeInvalid) {
+			t.Fatalf("wrong code: got %v", err)
+		}
+	}
+	var lockErr *LockoutError
+	if err := redeemCode(as, code); !errors.As(err, &lockErr) {
+		t.Fatalf("during the delay: got %v, want a LockoutError", err)
+	}
+
+	mr.FastForward(2 * time.Second)
+	if err := redeemCode(as, code); err != nil {
+		t.Fatalf("after the delay: %v", err)
+	}
+	if mr.Exists(keys.LoginFail(LockoutKindEmail, testEmail)) {
+		t.Error("a successful login kept the email failure count")
+	}
+}
+
+func TestEmailLoginLink(t *testing.T) {
+	as, mr, mb := emailLoginService(t, LockoutPolicy{DelayAfter: 5, Window: time.Hour, Duration: time.Hour})
+	code, token := mailCode(t, as, mb)
+	ctx := context.Background()
+
+	email, err := as.redeemEmailLogin(ctx, model.LoginCodeCmd{Token: token}, "198.51.100.7")
+	if err != nil || email != testEmail {
+		t.Fatalf("link: got %q, %v", email, err)
+	}
+	if _, err := as.redeemEmailLogin(ctx, model.LoginCodeCmd{Token: token}, "198.51.100.7"); !errors.Is(err, enum.ErrLoginCodeInvalid) {
+		t.Errorf("reused link: got %v, want ErrLoginCodeInvalid", err)
+	}
+	if err := redeemCode(as, code); !errors.Is(err, enum.ErrLoginCodeInvalid) {
+		t.Errorf("code after link: got %v, want ErrLoginCodeInvalid", err)
+	}
+	// a bad link names no email, so only the address is charged
+	if !mr.Exists(keys.LoginFail(LockoutKindIP, "198.51.100.7")) {
+		t.Error("bad link not counted against the IP")
+	}
+	if mr.Exists(keys.LoginFail(LockoutKindEmail, "")) {
+		t.Error("bad link counted against an empty email")
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/notify"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Profile is the current user's own account.
+type Profile struct {
+	UserView
+	PendingEmail string `json:"pending_email,omitempty"` // waiting for confirmation
+}
+
+// ProfileUpdate carries the self-editable fields; nil means unchanged.
+// UpdatedAt is the version the client read; when set, the update fails with
+// enum.ErrUserModified if the account changed since.
+type ProfileUpdate struct {
+	FullName  *string
+	Bio       *string
+	AvatarURL *string
+	Email     *string
+	UpdatedAt *time.Time
+	// CurrentPassword confirms an email change; see confirmIdentity.
+	CurrentPassword string
+}
+
+// emailChange is parked in Redis until the new address is confirmed.
+// SessionID is the session that asked for it, the only one that survives
+// the confirmation.
+type emailChange struct {
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid"`
+}
+
+func (as *AuthService) GetProfile(ctx context.Context, userID string) (*Profile, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return &Profile{UserView: newUserView(u), PendingEmail: as.pendingEmail(ctx, userID)}, nil
+}
+
+// UpdateProfile applies profile edits made in session sid right away. A new
+// email needs the user's identity confirmed first and is not stored until
+// ConfirmEmailChange proves the user owns it; until then it is shown as
+// pending_email.
+func (as *AuthService) UpdateProfile(ctx context.Context, userID, sid string, upd ProfileUpdate) (*Profile, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if upd.UpdatedAt != nil {
+		if !upd.UpdatedAt.Equal(u.UpdatedAt) {
+			return nil, enum.ErrUserModified
+		}
+		u.UpdatedAt = *upd.UpdatedAt
+	}
+	changed := []string{}
+	if upd.FullName != nil && *upd.FullName != u.FullName {
+		u.FullName = *upd.FullName
+		changed = append(changed, "full_name")
+	}
+	if upd.Bio != nil && *upd.Bio != u.Bio {
+		u.Bio = *upd.Bio
+		changed = append(changed, "bio")
+	}
+	if upd.AvatarURL != nil && *upd.AvatarURL != u.AvatarURL {
+		u.AvatarURL = *upd.AvatarURL
+		changed = append(changed, "avatar_url")
+	}
+	if upd.Email != nil {
+		email := repository.NormalizeEmail(*upd.Email)
+		upd.Email = &email
+	}
+	if upd.Email != nil && *upd.Email != u.Email {
+		if err := as.checkEmailFree(ctx, *upd.Email, uid); err != nil {
+			return nil, err
+		}
+		if err := as.confirmIdentity(ctx, u, sid, upd.CurrentPassword); err != nil {
+			return nil, err
+		}
+	}
+	if len(changed) > 0 {
+		if u, err = as.userRepo.UpdateProfile(ctx, u); err != nil {
+			return nil, err
+		}
+		as.audit(ctx, AuditEvent{Type: AuditUserUpdate, SubjectID: userID, Metadata: map[string]any{"fields": changed}})
+	}
+	if upd.Email != nil && *upd.Email != u.Email {
+		if err := as.requestEmailChange(ctx, u, sid, *upd.Email); err != nil {
+			return nil, err
+		}
+	}
+	return &Profile{UserView: newUserView(u), PendingEmail: as.pendingEmail(ctx, userID)}, nil
+}
+
+// requestEmailChange mails a confirmation token to the new address and a
+// notice to the current one, so that a change the owner did not ask for does
+// not go unnoticed. Only the latest request per user stays valid.
+func (as *AuthService) requestEmailChange(ctx context.Context, u *repository.UserModel, sid, email string) error {
+	userID := u.ID.String()
+	token, err := randomToken("ect_", 32)
+	if err != nil {
+		return err
+	}
+	if old, err := as.redis.GetDel(ctx, keys.EmailChangeUser(userID)).Result(); err == nil {
+		_ = as.redis.Del(ctx, keys.EmailChange(old)).Err()
+	}
+	b, _ := json.Marshal(emailChange{UserID: userID, Email: email, SessionID: sid})
+	h := hashToken(token)
+	pipe := as.redis.TxPipeline()
+	pipe.Set(ctx, keys.EmailChange(h), b, emailVerifyTTL)
+	pipe.Set(ctx, keys.EmailChangeUser(userID), h, emailVerifyTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditEmailChangeRequest, SubjectID: userID, Metadata: map[string]any{"email": email}})
+	if u.Email != "" {
+		ri := RequestInfoFrom(ctx)
+		err := as.notifier.Send(ctx, notify.Message{
+			Kind: notify.KindEmailChangeNotice,
+			To:   u.Email,
+			Data: map[string]string{
+				"new_email":    email,
+				"ip":           ri.IP,
+				"ua":           ri.UA,
+				"requested_at": nowISO(),
+			},
+		})
+		if err != nil {
+			as.log.Warn("send_email_change_notice_failed", zap.String("user_id", userID), zap.Error(err))
+		}
+	}
+	return as.notifier.Send(ctx, notify.Message{
+		Kind: notify.KindEmailChange,
+		To:   email,
+		Data: map[string]string{
+			"token":      token,
+			"expires_at": time.Now().UTC().Add(emailVerifyTTL).Format(time.RFC3339),
+		},
+	})
+}
+
+// ConfirmEmailChange consumes a confirmation token and commits the new
+// address, which counts as verified. Every session but the one that asked
+// for the change is revoked, as after a password change.
+func (as *AuthService) ConfirmEmailChange(ctx context.Context, token string) error {
+	raw, err := as.redis.GetDel(ctx, keys.EmailChange(hashToken(token))).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return enum.ErrVerifyTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+	var ch emailChange
+	if err := json.Unmarshal(raw, &ch); err != nil {
+		return enum.ErrVerifyTokenInvalid
+	}
+	_ = as.redis.Del(ctx, keys.EmailChangeUser(ch.UserID)).Err()
+	uid, err := uuid.Parse(ch.UserID)
+	if err != nil {
+		return enum.ErrVerifyTokenInvalid
+	}
+	// the address may have been taken while the mail was in flight
+	if err := as.checkEmailFree(ctx, ch.Email, uid); err != nil {
+		return err
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	oldEmail := u.Email
+	now := time.Now().UTC()
+	u.Email, u.EmailVerifiedAt = ch.Email, &now
+	if _, err := as.userRepo.UpdateProfile(ctx, u); err != nil {
+		return err
+	}
+	revoked, err := as.revokeOtherSessions(ctx, ch.UserID, ch.SessionID)
+	if err != nil {
+		as.log.Warn("email_change_revoke_sessions_failed", zap.String("user_id", ch.UserID), zap.Error(err))
+	}
+	as.audit(ctx, AuditEvent{Type: AuditEmailChange, ActorID: ch.UserID, Metadata: map[string]any{"old_email": oldEmail, "email": ch.Email, "revoked_sessions": revoked}})
+	return nil
+}
+
+func (as *AuthService) pendingEmail(ctx context.Context, userID string) string {
+	h, err := as.redis.Get(ctx, keys.EmailChangeUser(userID)).Result()
+	if err != nil {
+		return ""
+	}
+	raw, err := as.redis.Get(ctx, keys.EmailChange(h)).Bytes()
+	if err != nil {
+		return ""
+	}
+	var ch emailChange
+	if json.Unmarshal(raw, &ch) != nil {
+		return ""
+	}
+	return ch.Email
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"seno-blackdragon/internal/db/dbtest"
+	"seno-blackdragon/internal/db/user"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/notify"
+	"seno-blackdragon/pkg/utils"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const newEmail = "b@example.com"
+
+// profileService has one user, with password if it is not empty, logged in
+// on SID_1 authTime ago and on SID_2.
+func profileService(t *testing.T, password string, authTime time.Duration) (*AuthService, *mailbox, string) {
+	t.Helper()
+	uid := uuid.New()
+	db := dbtest.New()
+	as, _, mb := newTestService(t, db, Policy{})
+	row := user.User{
+		ID:       utils.PgUUIDFromUUID(uid),
+		Email:    pgtype.Text{String: testEmail, Valid: true},
+		IsActive: true,
+	}
+	if password != "" {
+		hash, err := as.hasher.Hash(password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		row.PasswordHash = pgtype.Text{String: hash, Valid: true}
+	}
+	db.On("GetUserByID", dbtest.Row(row)).On("UpdateUser", dbtest.Row(row))
+
+	ctx := context.Background()
+	for _, sid := range []string{"SID_1", "SID_2"} {
+		sess := &Session{UserID: uid.String(), DeviceID: "DEV_1", Status: model.Active, AuthTime: time.Now().Add(-authTime).Unix()}
+		if err := as.SaveSession(ctx, sid, sess, 3600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return as, mb, uid.String()
+}
+
+func TestEmailChangeConfirmsIdentity(t *testing.T) {
+	const password = "Curr3nt-Passw0rd!"
+	email := newEmail
+	cases := []struct {
+		name     string
+		password string // of the account
+		given    string
+		authTime time.Duration
+		want     error
+	}{
+		{"right password", password, password, time.Hour, nil},
+		{"no password given", password, "", time.Minute, enum.ErrInvalidCredentials},
+		{"wrong password", password, "wrong", time.Minute, enum.ErrInvalidCredentials},
+		{"passwordless, fresh login", "", "", time.Minute, nil},
+		{"passwordless, stale login", "", "", time.Hour, enum.ErrReauthRequired},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			as, mb, uid := profileService(t, tc.password, tc.authTime)
+			_, err := as.UpdateProfile(context.Background(), uid, "SID_1", ProfileUpdate{Email: &email, CurrentPassword: tc.given})
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got %v, want %v", err, tc.want)
+			}
+			if tc.want != nil {
+				if len(mb.msgs) != 0 {
+					t.Errorf("sent %d messages for a refused change", len(mb.msgs))
+				}
+				if p := as.pendingEmail(context.Background(), uid); p != "" {
+					t.Errorf("pending email %q after a refused change", p)
+				}
+			}
+		})
+	}
+}
+
+func TestEmailChangeNoticeAndSessions(t *testing.T) {
+	const password = "Curr3nt-Passw0rd!"
+	ctx := context.Background()
+	as, mb, uid := profileService(t, password, time.Minute)
+	email := newEmail
+	if _, err := as.UpdateProfile(ctx, uid, "SID_1", ProfileUpdate{Email: &email, CurrentPassword: password}); err != nil {
+		t.Fatal(err)
+	}
+
+	var notice, confirm *notify.Message
+	for i := range mb.msgs {
+		switch m := &mb.msgs[i]; m.Kind {
+		case notify.KindEmailChangeNotice:
+			notice = m
+		case notify.KindEmailChange:
+			confirm = m
+		}
+	}
+	if notice == nil || notice.To != testEmail || notice.Data["new_email"] != newEmail {
+		t.Fatalf("expected a notice to the old address, got %+v", notice)
+	}
+	if confirm == nil || confirm.To != newEmail {
+		t.Fatalf("expected a confirmation to the new address, got %+v", confirm)
+	}
+	if _, ok := notice.Data["token"]; ok {
+		t.Error("the notice to the old address carries the confirmation token")
+	}
+
+	if err := as.ConfirmEmailChange(ctx, confirm.Data["token"]); err != nil {
+		t.Fatal(err)
+	}
+	if sess, _ := as.GetSession(ctx, "SID_1"); sess == nil {
+		t.Error("the session that asked for the change was revoked")
+	}
+	if sess, _ := as.GetSession(ctx, "SID_2"); sess != nil {
+		t.Error("another session survived the email change")
+	}
+}
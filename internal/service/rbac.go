@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultRole = "user"
+
+	// rolePermsTTL bounds how long a permission change on a role takes to
+	// reach requests; role assignments reach tokens at the next refresh.
+	rolePermsTTL = 5 * time.Minute
+)
+
+func (as *AuthService) ListRoles(ctx context.Context) ([]repository.RoleModel, error) {
+	return as.rbacRepo.ListRoles(ctx)
+}
+
+func (as *AuthService) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return as.rbacRepo.GetUserRoles(ctx, userID)
+}
+
+func (as *AuthService) AssignRole(ctx context.Context, userID uuid.UUID, role string) error {
+	if err := as.rbacRepo.AssignRole(ctx, userID, role); err != nil {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditRoleAssign, SubjectID: userID.String(), Metadata: map[string]any{"role": role}})
+	return nil
+}
+
+func (as *AuthService) RevokeRole(ctx context.Context, userID uuid.UUID, role string) error {
+	if err := as.rbacRepo.RevokeRole(ctx, userID, role); err != nil {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditRoleRevoke, SubjectID: userID.String(), Metadata: map[string]any{"role": role}})
+	return nil
+}
+
+// PermissionsForRoles resolves the union of permissions granted to roles.
+// Each role's permission list is cached in Redis for rolePermsTTL.
+func (as *AuthService) PermissionsForRoles(ctx context.Context, roles []string) ([]string, error) {
+	if len(roles) == 0 {
+		return []string{}, nil
+	}
+	cacheKeys := make([]string, len(roles))
+	for i, r := range roles {
+		cacheKeys[i] = keys.RolePerms(r)
+	}
+	cached, err := as.redis.MGet(ctx, cacheKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	var perms []string
+	for i, raw := range cached {
+		var rolePerms []string
+		if str, ok := raw.(string); ok && json.Unmarshal([]byte(str), &rolePerms) == nil {
+			perms = append(perms, rolePerms...)
+			continue
+		}
+		rolePerms, err := as.rbacRepo.GetPermissionsByRoles(ctx, []string{roles[i]})
+		if err != nil {
+			return nil, err
+		}
+		b, _ := json.Marshal(rolePerms)
+		_ = as.redis.Set(ctx, cacheKeys[i], b, rolePermsTTL).Err()
+		perms = append(perms, rolePerms...)
+	}
+	slices.Sort(perms)
+	return slices.Compact(perms), nil
+}
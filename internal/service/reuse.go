@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/pkg/notify"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// handleRefreshReuse runs when a refresh token that was already rotated is
+// presented again. Either the client or an attacker holds a stolen copy and
+// there is no telling which, so the response is deliberately broad: the
+// family is blacklisted, every session of the device is revoked, the user
+// version is bumped so all outstanding access and refresh tokens of the user
+// fail validation, personal access tokens are deleted, and the user is told.
+// Each step is best effort; the caller rejects the request regardless.
+func (as *AuthService) handleRefreshReuse(ctx context.Context, claims *RefreshClaims) {
+	userID, did, fam := claims.Subject, claims.DeviceID, claims.Fam
+	log := as.log.With(zap.String("user_id", userID), zap.String("device_id", did), zap.String("fam", fam))
+	log.Warn("refresh_token_reuse")
+
+	if err := as.redis.Set(ctx, keys.FamBlack(fam), "1", as.jwtCfg.RefreshTTL).Err(); err != nil {
+		log.Warn("refresh_reuse_blacklist_failed", zap.Error(err))
+	}
+	revoked, err := as.revokeDevice(ctx, userID, did)
+	if err != nil {
+		log.Warn("refresh_reuse_revoke_device_failed", zap.Error(err))
+	}
+	uv, err := as.redis.Incr(ctx, keys.UserVer(userID)).Result()
+	if err != nil {
+		log.Warn("refresh_reuse_bump_version_failed", zap.Error(err))
+	}
+	// whoever holds the stolen refresh token may have minted API tokens too
+	tokens, err := as.revokePersonalTokens(ctx, userID)
+	if err != nil {
+		log.Warn("refresh_reuse_revoke_api_tokens_failed", zap.Error(err))
+	}
+
+	ri := RequestInfoFrom(ctx)
+	as.audit(ctx, AuditEvent{
+		Type:      AuditRefreshReuse,
+		ActorID:   userID,
+		DeviceID:  did,
+		SessionID: claims.SessionID,
+		Metadata: map[string]any{
+			"fam":              fam,
+			"jti":              claims.ID,
+			"revoked_sessions": revoked,
+			"api_tokens":       tokens,
+			"user_version":     uv,
+		},
+	})
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return
+	}
+	u, err := as.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		log.Warn("refresh_reuse_notify_failed", zap.Error(err))
+		return
+	}
+	err = as.notifier.Send(ctx, notify.Message{
+		Kind: notify.KindTokenReuse,
+		To:   u.Email,
+		Data: map[string]string{
+			"device_id":   did,
+			"ip":          ri.IP,
+			"ua":          ri.UA,
+			"detected_at": nowISO(),
+		},
+	})
+	if err != nil {
+		log.Warn("refresh_reuse_notify_failed", zap.Error(err))
+	}
+}
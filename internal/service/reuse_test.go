@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"seno-blackdragon/internal/db/dbtest"
+	"seno-blackdragon/internal/db/user"
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/notify"
+	"seno-blackdragon/pkg/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// loggedIn is a service with one user logged in on DEV_1.
+func loggedIn(t *testing.T) (*AuthService, *miniredis.Miniredis, *mailbox, string, *model.TokenPair) {
+	t.Helper()
+	uid := uuid.New()
+	db := dbtest.New().On("GetUserByID", dbtest.Row(user.User{
+		ID:       utils.PgUUIDFromUUID(uid),
+		Email:    pgtype.Text{String: testEmail, Valid: true},
+		IsActive: true,
+	}))
+	as, mr, mb := newTestService(t, db, Policy{})
+	ctx := context.Background()
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := as.issueTokens(ctx, u, model.LoginCmd{DeviceID: "DEV_1"}, sessionGrant{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return as, mr, mb, uid.String(), pair
+}
+
+func TestRefreshReplay(t *testing.T) {
+	ctx := context.Background()
+	as, mr, mb, uid, pair := loggedIn(t)
+	rotated, err := as.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uvBefore, _ := as.GetUserVersion(ctx, uid)
+
+	if _, err := as.Refresh(ctx, pair.RefreshToken); !errors.Is(err, enum.ErrRefreshRevoked) {
+		t.Fatalf("replay: got %v, want ErrRefreshRevoked", err)
+	}
+	claims, err := as.parseRefreshToken(pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists(keys.FamBlack(claims.Fam)) {
+		t.Error("the refresh family was not blacklisted")
+	}
+	if sess, _ := as.GetSession(ctx, rotated.SessionID); sess != nil {
+		t.Error("the session of the device survived the replay")
+	}
+	if uv, _ := as.GetUserVersion(ctx, uid); uv != uvBefore+1 {
+		t.Errorf("user version %d, want %d", uv, uvBefore+1)
+	}
+	if msg := mb.last(t); msg.Kind != notify.KindTokenReuse || msg.To != testEmail {
+		t.Errorf("unexpected notice %+v", msg)
+	}
+	// the legitimate rotation is gone with the family
+	if _, err := as.Refresh(ctx, rotated.RefreshToken); err == nil {
+		t.Error("the rotated token still refreshes after reuse was detected")
+	}
+}
+
+func TestRefreshReuseRevokesDevice(t *testing.T) {
+	ctx := context.Background()
+	as, _, _, uid, pair := loggedIn(t)
+	// a second login on the same device and one on another device
+	u, err := as.userRepo.GetUserByID(ctx, uuid.MustParse(uid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, err := as.issueTokens(ctx, u, model.LoginCmd{DeviceID: "DEV_1"}, sessionGrant{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := as.issueTokens(ctx, u, model.LoginCmd{DeviceID: "DEV_2"}, sessionGrant{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := as.parseRefreshToken(pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	as.handleRefreshReuse(ctx, claims)
+
+	for _, sid := range []string{pair.SessionID, same.SessionID} {
+		if sess, _ := as.GetSession(ctx, sid); sess != nil {
+			t.Errorf("session %s of the device survived", sid)
+		}
+	}
+	if sess, _ := as.GetSession(ctx, other.SessionID); sess == nil {
+		t.Error("the session of another device was revoked")
+	}
+	if _, err := as.Refresh(ctx, same.RefreshToken); !errors.Is(err, enum.ErrFamilyBlocked) {
+		t.Errorf("refresh on the revoked device: got %v, want ErrFamilyBlocked", err)
+	}
+	// the user version bump retires the other device's tokens as well
+	if _, err := as.Refresh(ctx, other.RefreshToken); !errors.Is(err, enum.ErrInvalidToken) {
+		t.Errorf("refresh on another device: got %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestRefreshConcurrentRotation(t *testing.T) {
+	ctx := context.Background()
+	for range 20 {
+		as, mr, _, _, pair := loggedIn(t)
+		const n = 8
+		var (
+			wg  sync.WaitGroup
+			mu  sync.Mutex
+			won int
+		)
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := as.Refresh(ctx, pair.RefreshToken)
+				switch {
+				case err == nil:
+					mu.Lock()
+					won++
+					mu.Unlock()
+				case errors.Is(err, enum.ErrRotationRace), errors.Is(err, enum.ErrRefreshRevoked),
+					errors.Is(err, enum.ErrRefreshNotActive), errors.Is(err, enum.ErrFamilyBlocked):
+				default:
+					t.Errorf("unexpected error %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if won > 1 {
+			t.Fatalf("%d rotations of one refresh token succeeded", won)
+		}
+		claims, _ := as.parseRefreshToken(pair.RefreshToken)
+		if live, _ := mr.SMembers(keys.FamActive(claims.Fam)); len(live) > 1 {
+			t.Fatalf("the family forked into %d live tokens", len(live))
+		}
+	}
+}
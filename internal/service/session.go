@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/pass"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// sessionTouchInterval throttles LastSeen writes so that every request does
+// not turn into a Redis SET.
+const sessionTouchInterval = time.Minute
+
+// freshAuthMaxAge is how recently the user must have logged in for actions
+// that hand out lasting access.
+const freshAuthMaxAge = 10 * time.Minute
+
+// SessionItem is a session as shown to its owner.
+type SessionItem struct {
+	ID string `json:"id"`
+	Session
+	Current bool `json:"current"`
+}
+
+// ListSessions returns the live sessions of a user, most recently used first.
+// Sessions that already expired are pruned from the user's index on the way.
+func (as *AuthService) ListSessions(ctx context.Context, userID, currentSID string) ([]SessionItem, error) {
+	sids, err := as.redis.SMembers(ctx, keys.UserSession(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(sids) == 0 {
+		return []SessionItem{}, nil
+	}
+	sessKeys := make([]string, len(sids))
+	for i, sid := range sids {
+		sessKeys[i] = keys.Session(sid)
+	}
+	raws, err := as.redis.MGet(ctx, sessKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]SessionItem, 0, len(sids))
+	var stale []any
+	for i, raw := range raws {
+		str, ok := raw.(string)
+		if !ok {
+			stale = append(stale, sids[i])
+			continue
+		}
+		var s Session
+		if err := json.Unmarshal([]byte(str), &s); err != nil {
+			continue
+		}
+		items = append(items, SessionItem{ID: sids[i], Session: s, Current: sids[i] == currentSID})
+	}
+	if len(stale) > 0 {
+		_ = as.redis.SRem(ctx, keys.UserSession(userID), stale...).Err()
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].LastSeen > items[j].LastSeen })
+	return items, nil
+}
+
+// RevokeSession ends one session of the user and blacklists its refresh family.
+func (as *AuthService) RevokeSession(ctx context.Context, userID, sid string) error {
+	if err := as.revokeSession(ctx, userID, sid); err != nil {
+		return err
+	}
+	as.audit(ctx, AuditEvent{Type: AuditSessionRevoke, SubjectID: userID, SessionID: sid})
+	return nil
+}
+
+func (as *AuthService) revokeSession(ctx context.Context, userID, sid string) error {
+	sess, err := as.GetSession(ctx, sid)
+	if err != nil {
+		return err
+	}
+	if sess == nil || sess.UserID != userID {
+		return enum.ErrSessionRevoked
+	}
+	if sess.Fam != "" {
+		if err := as.revokeFamilies(ctx, sess.Fam); err != nil {
+			return err
+		}
+	}
+	return as.DelSession(ctx, userID, sid)
+}
+
+// TouchSession records activity on a session. Writes are throttled by
+// sessionTouchInterval, keep the session's remaining TTL and only land while
+// the key still exists, so a touch racing a revocation cannot bring the
+// session back.
+func (as *AuthService) TouchSession(ctx context.Context, sid string, s *Session, ip string) error {
+	last, err := time.Parse(time.RFC3339, s.LastSeen)
+	if err == nil && time.Since(last) < sessionTouchInterval {
+		return nil
+	}
+	s.LastSeen = nowISO()
+	if ip != "" {
+		s.IP = ip
+	}
+	b, _ := json.Marshal(s)
+	err = as.redis.SetArgs(ctx, keys.Session(sid), b, redis.SetArgs{KeepTTL: true, Mode: "XX"}).Err()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
+
+// requireFreshAuth fails with enum.ErrReauthRequired unless the user logged
+// in to session sid within freshAuthMaxAge. Refresh keeps the session's
+// auth_time, so a stolen refresh token cannot make a session fresh.
+func (as *AuthService) requireFreshAuth(ctx context.Context, userID, sid string) error {
+	sess, err := as.GetSession(ctx, sid)
+	if err != nil {
+		return err
+	}
+	if sess == nil || sess.UserID != userID {
+		return enum.ErrSessionRevoked
+	}
+	if time.Since(time.Unix(authTime(sess), 0)) > freshAuthMaxAge {
+		return enum.ErrReauthRequired
+	}
+	return nil
+}
+
+// confirmIdentity re-checks the user behind session sid before a change that
+// would let a stolen bearer token take the account over. Accounts with a
+// password must give it; passwordless ones must have logged in, by passkey,
+// emailed code or social login, within freshAuthMaxAge.
+func (as *AuthService) confirmIdentity(ctx context.Context, u *repository.UserModel, sid, password string) error {
+	if u.PasswordHash != "" {
+		if ok, _ := pass.Detect(u.PasswordHash, as.argon2id, as.bcrypt).Verify(password, u.PasswordHash); !ok {
+			return enum.ErrInvalidCredentials
+		}
+		return nil
+	}
+	return as.requireFreshAuth(ctx, u.ID.String(), sid)
+}
+
+// revokeOtherSessions ends every session of the user except keepSID and
+// reports how many went.
+func (as *AuthService) revokeOtherSessions(ctx context.Context, userID, keepSID string) (int, error) {
+	sessions, err := as.ListSessions(ctx, userID, keepSID)
+	if err != nil {
+		return 0, err
+	}
+	revoked := 0
+	for _, s := range sessions {
+		if s.Current {
+			continue
+		}
+		if err := as.revokeSession(ctx, userID, s.ID); err != nil && !errors.Is(err, enum.ErrSessionRevoked) {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
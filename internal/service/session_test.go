@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"seno-blackdragon/internal/db/dbtest"
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/model"
+)
+
+func TestTouchSession(t *testing.T) {
+	ctx := context.Background()
+	as, mr, _ := newTestService(t, dbtest.New(), Policy{})
+	const uid, sid = "6a3c1d2e-8f4b-4e2a-b1c0-9d8e7f6a5b4c", "SID_1"
+	stale := time.Now().Add(-2 * sessionTouchInterval).UTC().Format(time.RFC3339)
+	if err := as.SaveSession(ctx, sid, &Session{UserID: uid, DeviceID: "DEV_1", Status: model.Active}, 3600); err != nil {
+		t.Fatal(err)
+	}
+
+	sess := &Session{UserID: uid, DeviceID: "DEV_1", Status: model.Active, LastSeen: stale}
+	if err := as.TouchSession(ctx, sid, sess, "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := as.GetSession(ctx, sid)
+	if err != nil || got == nil {
+		t.Fatalf("Expected the session to survive a touch, got %v, %v", got, err)
+	}
+	if got.IP != "203.0.113.7" || got.LastSeen == stale {
+		t.Errorf("Expected the touch to record activity, got %+v", got)
+	}
+	if ttl := mr.TTL(keys.Session(sid)); ttl <= 0 {
+		t.Errorf("Expected the touch to keep the session TTL, got %v", ttl)
+	}
+
+	// A request that loaded the session before it was revoked must not
+	// bring it back.
+	if err := as.RevokeSession(ctx, uid, sid); err != nil {
+		t.Fatal(err)
+	}
+	sess.LastSeen = stale
+	if err := as.TouchSession(ctx, sid, sess, "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(keys.Session(sid)) {
+		t.Error("Expected a touch after revocation to leave the session deleted")
+	}
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"seno-blackdragon/internal/keys"
+	"seno-blackdragon/internal/model"
+	"seno-blackdragon/internal/repository"
+	"seno-blackdragon/pkg/enum"
+	"seno-blackdragon/pkg/oidcclient"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// SocialStateTTL is how long a started social login can be completed; the
+// browser binding cookie lives as long.
+const SocialStateTTL = 10 * time.Minute
+
+// socialState is parked in Redis between the redirect to the provider and
+// the callback. UserID and SessionID are set when a logged-in user links a
+// provider instead of logging in with it.
+type socialState struct {
+	Provider  string `json:"provider"`
+	Verifier  string `json:"verifier"`
+	Nonce     string `json:"nonce"`
+	DeviceID  string `json:"device_id,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	Uv        int    `json:"uv,omitempty"`
+}
+
+// Identity is an external account linked to the user.
+type Identity struct {
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// SocialResult is the outcome of a provider callback: a login result, or
+// the identity that was linked when the flow was started by StartSocialLink.
+type SocialResult struct {
+	Login  *model.LoginResult
+	Linked *Identity
+}
+
+func newIdentityView(m *repository.IdentityModel) Identity {
+	return Identity{Provider: m.Provider, Email: m.Email, CreatedAt: m.CreatedAt, LastLoginAt: m.LastLoginAt}
+}
+
+// SocialProviders lists the names of the configured providers.
+func (as *AuthService) SocialProviders() []string {
+	names := make([]string, 0, len(as.policy.SocialProviders))
+	for name := range as.policy.SocialProviders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SocialStart is where to send the browser, and the binding the browser must
+// present at the callback (in a cookie) so that a state cannot be completed
+// in someone else's browser.
+type SocialStart struct {
+	RedirectTo string
+	Binding    string
+}
+
+// StartSocialLogin returns the provider URL that starts a login. deviceID is
+// used for the session once the user comes back.
+func (as *AuthService) StartSocialLogin(ctx context.Context, provider, deviceID string) (*SocialStart, error) {
+	return as.startSocial(ctx, provider, socialState{DeviceID: deviceID})
+}
+
+// StartSocialLink returns the provider URL that links an external account to
+// the logged-in user. The session must still be live at the callback.
+func (as *AuthService) StartSocialLink(ctx context.Context, userID, sid, provider string) (*SocialStart, error) {
+	uv, err := as.GetUserVersion(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return as.startSocial(ctx, provider, socialState{UserID: userID, SessionID: sid, Uv: uv})
+}
+
+func (as *AuthService) startSocial(ctx context.Context, provider string, st socialState) (*SocialStart, error) {
+	p, ok := as.policy.SocialProviders[provider]
+	if !ok {
+		return nil, enum.ErrSocialProviderNotFound
+	}
+	state, err := randomToken("", 32)
+	if err != nil {
+		return nil, err
+	}
+	if st.Verifier, err = randomToken("", 32); err != nil {
+		return nil, err
+	}
+	if st.Nonce, err = randomToken("", 16); err != nil {
+		return nil, err
+	}
+	st.Provider = provider
+	redirect, err := p.AuthCodeURL(ctx, state, st.Nonce, oidcclient.S256Challenge(st.Verifier))
+	if err != nil {
+		as.log.Warn("social_discovery_failed", zap.String("provider", provider), zap.Error(err))
+		return nil, enum.ErrSocialLoginFailed
+	}
+	b, _ := json.Marshal(st)
+	if err := as.redis.Set(ctx, keys.SocialState(state), b, SocialStateTTL).Err(); err != nil {
+		return nil, err
+	}
+	return &SocialStart{RedirectTo: redirect, Binding: hashToken(state)}, nil
+}
+
+// SocialCallback completes a flow started by StartSocialLogin or
+// StartSocialLink; binding is the SocialStart.Binding the browser kept. A
+// login with an unknown external account creates a new user, unless a local
+// account already has the email: that user has to log in and link the
+// provider, so nobody can take over an account by registering its email at a
+// provider.
+func (as *AuthService) SocialCallback(ctx context.Context, provider, code, state, binding string, cmd model.LoginCmd) (*SocialResult, error) {
+	// a state from another browser would log the victim into the attacker's
+	// account, or link the attacker's identity to the victim's
+	if subtle.ConstantTimeCompare([]byte(hashToken(state)), []byte(binding)) != 1 {
+		return nil, enum.ErrSocialStateInvalid
+	}
+	raw, err := as.redis.GetDel(ctx, keys.SocialState(state)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, enum.ErrSocialStateInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st socialState
+	if err := json.Unmarshal(raw, &st); err != nil || st.Provider != provider {
+		return nil, enum.ErrSocialStateInvalid
+	}
+	p, ok := as.policy.SocialProviders[provider]
+	if !ok {
+		return nil, enum.ErrSocialProviderNotFound
+	}
+	tok, err := p.Exchange(ctx, code, st.Verifier)
+	if err != nil {
+		as.log.Warn("social_exchange_failed", zap.String("provider", provider), zap.Error(err))
+		return nil, enum.ErrSocialLoginFailed
+	}
+	claims, err := p.VerifyIDToken(ctx, tok.IDToken, st.Nonce)
+	if err != nil {
+		as.log.Warn("social_id_token_invalid", zap.String("provider", provider), zap.Error(err))
+		return nil, enum.ErrSocialLoginFailed
+	}
+	if st.UserID != "" {
+		linked, err := as.linkIdentity(ctx, st, claims)
+		if err != nil {
+			return nil, err
+		}
+		return &SocialResult{Linked: linked}, nil
+	}
+
+	cmd.DeviceID = st.DeviceID
+	u, err := as.socialUser(ctx, provider, claims)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Email = u.Email
+	result, err := as.completeLogin(ctx, u, cmd, sessionGrant{AMR: []string{AMRFederated}})
+	if err != nil {
+		return nil, err
+	}
+	return &SocialResult{Login: result}, nil
+}
+
+// socialUser returns the user linked to the external account, signing up a
+// new one on first login.
+func (as *AuthService) socialUser(ctx context.Context, provider string, claims *oidcclient.Claims) (*repository.UserModel, error) {
+	ident, err := as.identityRepo.Get(ctx, provider, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if ident != nil {
+		if err := as.identityRepo.Touch(ctx, ident.ID, claims.Email); err != nil {
+			as.log.Warn("identity_touch_failed", zap.String("user_id", ident.UserID.String()), zap.Error(err))
+		}
+		return as.userRepo.GetUserByID(ctx, ident.UserID)
+	}
+	if claims.Email == "" {
+		as.log.Warn("social_login_without_email", zap.String("provider", provider))
+		return nil, enum.ErrSocialLoginFailed
+	}
+	_, err = as.userRepo.GetUserByEmail(ctx, claims.Email)
+	if err == nil {
+		return nil, enum.ErrSocialAccountExists
+	}
+	if !errors.Is(err, enum.ErrUserNotFound) {
+		return nil, err
+	}
+	id, err := as.userRepo.CreateUser(ctx, &repository.UserModel{FullName: claims.Name, Email: claims.Email})
+	if err != nil {
+		if errors.Is(err, enum.ErrEmailAlready) {
+			return nil, enum.ErrSocialAccountExists
+		}
+		return nil, err
+	}
+	if err := as.rbacRepo.AssignRole(ctx, id, DefaultRole); err != nil {
+		as.log.Warn("assign_default_role_failed", zap.String("user_id", id.String()), zap.Error(err))
+	}
+	if _, err := as.identityRepo.Create(ctx, repository.IdentityModel{
+		UserID: id, Provider: provider, Subject: claims.Subject, Email: claims.Email,
+	}); err != nil {
+		return nil, err
+	}
+	as.audit(ctx, AuditEvent{
+		Type:      AuditIdentityLink,
+		SubjectID: id.String(),
+		Metadata:  map[string]any{"provider": provider, "signup": true},
+	})
+	// the provider vouches for the address; otherwise verify it ourselves
+	if claims.EmailVerified {
+		if _, err := as.userRepo.MarkEmailVerified(ctx, id); err != nil {
+			return nil, err
+		}
+	} else if err := as.sendEmailVerification(ctx, id.String(), repository.NormalizeEmail(claims.Email)); err != nil {
+		as.log.Warn("send_email_verification_failed", zap.String("user_id", id.String()), zap.Error(err))
+	}
+	return as.userRepo.GetUserByID(ctx, id)
+}
+
+func (as *AuthService) linkIdentity(ctx context.Context, st socialState, claims *oidcclient.Claims) (*Identity, error) {
+	sess, err := as.liveSession(ctx, st.SessionID, st.UserID, st.Uv)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		return nil, enum.ErrSessionRevoked
+	}
+	uid, err := uuid.Parse(st.UserID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	ident, err := as.identityRepo.Create(ctx, repository.IdentityModel{
+		UserID: uid, Provider: st.Provider, Subject: claims.Subject, Email: claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	as.audit(ctx, AuditEvent{
+		Type:      AuditIdentityLink,
+		ActorID:   st.UserID,
+		SessionID: st.SessionID,
+		Metadata:  map[string]any{"provider": st.Provider},
+	})
+	view := newIdentityView(ident)
+	return &view, nil
+}
+
+func (as *AuthService) ListIdentities(ctx context.Context, userID string) ([]Identity, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, enum.ErrUserNotFound
+	}
+	idents, err := as.identityRepo.ListByUserID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]Identity, 0, len(idents))
+	for i := range idents {
+		views = append(views, newIdentityView(&idents[i]))
+	}
+	return views, nil
+}
+
+// UnlinkIdentity removes the user's identity at provider. Accounts created
+// through a provider have no password, so the last identity of such an
+// account stays until the user sets a password or registers a passkey.
+func (as *AuthService) UnlinkIdentity(ctx context.Context, userID, provider string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return enum.ErrUserNotFound
+	}
+	u, err := as.userRepo.GetUserByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	idents, err := as.identityRepo.ListByUserID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(idents, func(m repository.IdentityModel) bool { return m.Provider == provider }) {
+		return enum.ErrIdentityNotFound
+	}
+	if err := as.checkLastLoginMethod(ctx, u); err != nil {
+		return err
+	}
+	ok, err := as.identityRepo.Delete(ctx, uid, provider)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return enum.ErrIdentityNotFound
+	}
+	as.audit(ctx, AuditEvent{
+		Type:     AuditIdentityUnlink,
+		ActorID:  userID,
+		Metadata: map[string]any{"provider": provider},
+	})
+	return nil
+}
+
+// checkLastLoginMethod refuses to remove a way to log in (a linked identity
+// or a passkey) when it is the only one u has left.
+func (as *AuthService) checkLastLoginMethod(ctx context.Context, u *repository.UserModel) error {
+	n := 0
+	if u.PasswordHash != "" {
+		n++
+	}
+	idents, err := as.identityRepo.ListByUserID(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	passkeys, err := as.passkeyRepo.ListByUserID(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if n+len(idents)+len(passkeys) <= 1 {
+		return enum.ErrLastLoginMethod
+	}
+	return nil
+}
@@ -36,6 +36,7 @@ type DBConfig struct {
 
 var DBCache = []DBConfig{
 	{Name: "Token", DB: 0},
+	{Name: "RateLimit", DB: 1},
 }
 
 // Init creates and health-checks all redis clients.
@@ -1,8 +1,8 @@
 package dto
 
 type PaginationRequest struct {
-	Page     int    `json:"page" form:"page" binding:"gte=1"`
-	PageSize int    `json:"page_size" form:"page_size" binding:"gte=1,lte=100"`
+	Page     int    `json:"page" form:"page,default=1" binding:"gte=1"`
+	PageSize int    `json:"page_size" form:"page_size,default=20" binding:"gte=1,lte=100"`
 	SortBy   string `json:"sort_by,omitempty" form:"sort_by"`
 	OrderBy  string `json:"order_by,omitempty" form:"order_by"`
 	Query    string `json:"query,omitempty" form:"query"`
@@ -16,3 +16,34 @@ type PaginationResponse[T any] struct {
 	TotalPages  int   `json:"total_pages"`
 	HasNextPage bool  `json:"has_next_page"`
 }
+
+// Offset returns the number of items to skip for the requested page.
+func (p PaginationRequest) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// NewPaginationResponse fills the page metadata for one page of items.
+func NewPaginationResponse[T any](items []T, total int64, page, pageSize int) PaginationResponse[T] {
+	if items == nil {
+		items = []T{}
+	}
+	totalPages := 0
+	if pageSize > 0 {
+		totalPages = int((total + int64(pageSize) - 1) / int64(pageSize))
+	}
+	return PaginationResponse[T]{
+		Items:       items,
+		Total:       total,
+		Page:        page,
+		PageSize:    pageSize,
+		TotalPages:  totalPages,
+		HasNextPage: page < totalPages,
+	}
+}
+
+// Paginate slices an in-memory list according to the request.
+func Paginate[T any](all []T, req PaginationRequest) PaginationResponse[T] {
+	start := min(req.Offset(), len(all))
+	end := min(start+req.PageSize, len(all))
+	return NewPaginationResponse(all[start:end], int64(len(all)), req.Page, req.PageSize)
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "testing"
+
+func TestPaginate(t *testing.T) {
+	all := []int{1, 2, 3, 4, 5}
+
+	page := Paginate(all, PaginationRequest{Page: 2, PageSize: 2})
+	if len(page.Items) != 2 || page.Items[0] != 3 || page.Items[1] != 4 {
+		t.Errorf("Expected items [3 4], got %v", page.Items)
+	}
+	if page.Total != 5 || page.TotalPages != 3 || !page.HasNextPage {
+		t.Errorf("Unexpected metadata: %+v", page)
+	}
+
+	last := Paginate(all, PaginationRequest{Page: 3, PageSize: 2})
+	if len(last.Items) != 1 || last.HasNextPage {
+		t.Errorf("Expected a single item on the last page, got %+v", last)
+	}
+
+	beyond := Paginate(all, PaginationRequest{Page: 9, PageSize: 2})
+	if beyond.Items == nil || len(beyond.Items) != 0 {
+		t.Errorf("Expected empty non-nil items beyond the last page, got %v", beyond.Items)
+	}
+}
@@ -9,6 +9,13 @@ var (
 	ErrWrongType          = errors.New("wrong token type")          // access != refresh
 	ErrWrongAlgorithm     = errors.New("unexpected signing method") // HS256 vs RS256...
 	ErrInvalidCredentials = errors.New("invalid credentials")       // wrong email/password
+	ErrMissingToken       = errors.New("missing bearer token")      // no/garbled Authorization header
+
+	// Session / Device
+	ErrSessionRevoked = errors.New("session revoked or expired") // sid not found or not active
+	ErrDeviceBlocked  = errors.New("device is blocked")          // device status = block
+	ErrDeviceNotFound = errors.New("device not found")           // unknown or not owned by user
+	ErrReauthRequired = errors.New("recent login required")      // session authenticated too long ago
 
 	// Refresh flow
 	ErrRefreshNotActive = errors.New("refresh token not active")                // not in allow-list
@@ -16,9 +23,75 @@ var (
 	ErrFamilyBlocked    = errors.New("refresh family is blocked")               // block whole family
 	ErrRotationRace     = errors.New("refresh rotation in progress, try again") // lock conflict
 
+	// MFA
+	ErrMFAInvalidCode      = errors.New("invalid mfa code")                 // wrong/replayed TOTP or recovery code
+	ErrMFAChallengeInvalid = errors.New("mfa challenge invalid or expired") // unknown, used or too many attempts
+	ErrMFANotEnrolled      = errors.New("mfa not enrolled")
+	ErrMFAAlreadyEnabled   = errors.New("mfa already enabled")
+
+	// Email verification
+	ErrEmailNotVerified   = errors.New("email not verified")
+	ErrVerifyTokenInvalid = errors.New("verification token invalid or expired")
+
+	// Password
+	ErrResetTokenInvalid = errors.New("password reset token invalid or expired")
+	ErrWeakPassword      = errors.New("password does not meet the policy")
+
+	// AuthZ
+	ErrForbidden    = errors.New("insufficient role or permission")
+	ErrRoleNotFound = errors.New("role not found")
+
+	// OAuth2; the handlers map these to the RFC 6749 error codes
+	ErrOAuthInvalidRequest          = errors.New("invalid oauth request")
+	ErrOAuthInvalidClient           = errors.New("oauth client authentication failed")
+	ErrOAuthInvalidGrant            = errors.New("authorization grant invalid, expired or revoked")
+	ErrOAuthUnauthorizedClient      = errors.New("client is not allowed to use this grant")
+	ErrOAuthUnsupportedGrant        = errors.New("unsupported grant type")
+	ErrOAuthUnsupportedResponseType = errors.New("unsupported response type")
+	ErrOAuthInvalidScope            = errors.New("requested scope is invalid or not allowed")
+	ErrOAuthClientNotFound          = errors.New("oauth client not found")
+	ErrInsufficientScope            = errors.New("token scope does not cover this resource")
+	ErrOIDCDisabled                 = errors.New("openid connect needs an asymmetric signing key")
+
+	// Social login
+	ErrSocialProviderNotFound = errors.New("unknown identity provider")
+	ErrSocialStateInvalid     = errors.New("social login state invalid or expired")
+	ErrSocialLoginFailed      = errors.New("identity provider rejected the login")
+	ErrSocialAccountExists    = errors.New("an account with this email exists, log in and link the provider")
+	ErrIdentityLinked         = errors.New("external account is already linked")
+	ErrIdentityNotFound       = errors.New("identity not linked")
+	ErrLastLoginMethod        = errors.New("cannot remove the last way to log in")
+
+	// Passkeys
+	ErrPasskeyChallengeInvalid = errors.New("passkey challenge invalid or expired")
+	ErrPasskeyInvalid          = errors.New("passkey verification failed")
+	ErrPasskeyNotFound         = errors.New("passkey not found")
+	ErrPasskeyExists           = errors.New("passkey is already registered")
+
+	// Passwordless
+	ErrLoginCodeInvalid = errors.New("login code invalid or expired") // wrong, used or too many attempts
+
+	// API tokens
+	ErrAPITokenInvalid  = errors.New("api token invalid or expired")
+	ErrAPITokenNotFound = errors.New("api token not found")
+	ErrAPITokenKind     = errors.New("unknown api token kind")
+	ErrAPITokenScope    = errors.New("api token scope is not held by the user")
+	ErrAPITokenExpiry   = errors.New("api token lifetime missing or over the limit")
+
+	// Abuse protection
+	ErrRateLimited    = errors.New("rate limit exceeded")
+	ErrAccountLocked  = errors.New("too many failed logins, temporarily locked")
+	ErrIPBlocked      = errors.New("ip address is blocked")
+	ErrInvalidCIDR    = errors.New("invalid ip address or cidr")
+	ErrInvalidIPList  = errors.New("ip list must be allow or deny")
+	ErrIPRuleNotFound = errors.New("ip rule not found")
+
 	// User / Business
-	ErrUserNotFound = errors.New("user not found")
-	ErrEmailAlready = errors.New("email already registered")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrEmailAlready    = errors.New("email already registered")
+	ErrInvalidSort     = errors.New("unsupported sort field or order")
+	ErrUserModified    = errors.New("user was modified by another request")
+	ErrAccountDisabled = errors.New("account is deactivated or deleted")
 )
 
 // ===== Error codes (machine-readable) =====
@@ -35,6 +108,13 @@ const (
 	CodeWrongTokenType     = "WRONG_TOKEN_TYPE"
 	CodeUnexpectedAlg      = "UNEXPECTED_SIGNING_METHOD"
 	CodeInvalidCredentials = "INVALID_CREDENTIALS"
+	CodeMissingToken       = "MISSING_TOKEN"
+
+	// Session / Device
+	CodeSessionRevoked = "SESSION_REVOKED"
+	CodeDeviceBlocked  = "DEVICE_BLOCKED"
+	CodeDeviceNotFound = "DEVICE_NOT_FOUND"
+	CodeReauthRequired = "REAUTH_REQUIRED"
 
 	// Refresh
 	CodeRefreshNotActive = "REFRESH_NOT_ACTIVE"
@@ -42,7 +122,67 @@ const (
 	CodeFamilyBlocked    = "FAMILY_BLOCKED"
 	CodeRotationRace     = "ROTATION_RACE"
 
+	// MFA
+	CodeMFAInvalidCode      = "MFA_INVALID_CODE"
+	CodeMFAChallengeInvalid = "MFA_CHALLENGE_INVALID"
+	CodeMFANotEnrolled      = "MFA_NOT_ENROLLED"
+	CodeMFAAlreadyEnabled   = "MFA_ALREADY_ENABLED"
+
+	// Email verification
+	CodeEmailNotVerified   = "EMAIL_NOT_VERIFIED"
+	CodeVerifyTokenInvalid = "VERIFY_TOKEN_INVALID"
+
+	// Password
+	CodeResetTokenInvalid = "RESET_TOKEN_INVALID"
+	CodeWeakPassword      = "WEAK_PASSWORD"
+
+	// AuthZ
+	CodeForbidden    = "FORBIDDEN"
+	CodeRoleNotFound = "ROLE_NOT_FOUND"
+
+	// OAuth2
+	CodeOAuthInvalidRequest     = "OAUTH_INVALID_REQUEST"
+	CodeOAuthInvalidClient      = "OAUTH_INVALID_CLIENT"
+	CodeOAuthUnauthorizedClient = "OAUTH_UNAUTHORIZED_CLIENT"
+	CodeOAuthInvalidScope       = "OAUTH_INVALID_SCOPE"
+	CodeOAuthClientNotFound     = "OAUTH_CLIENT_NOT_FOUND"
+	CodeOIDCDisabled            = "OIDC_DISABLED"
+	CodeInsufficientScope       = "INSUFFICIENT_SCOPE"
+
+	// Social login
+	CodeSocialProviderNotFound = "PROVIDER_NOT_FOUND"
+	CodeSocialStateInvalid     = "SOCIAL_STATE_INVALID"
+	CodeSocialLoginFailed      = "SOCIAL_LOGIN_FAILED"
+	CodeSocialAccountExists    = "SOCIAL_ACCOUNT_EXISTS"
+	CodeIdentityLinked         = "IDENTITY_LINKED"
+	CodeIdentityNotFound       = "IDENTITY_NOT_FOUND"
+	CodeLastLoginMethod        = "LAST_LOGIN_METHOD"
+
+	// Passkeys
+	CodePasskeyChallengeInvalid = "PASSKEY_CHALLENGE_INVALID"
+	CodePasskeyInvalid          = "PASSKEY_INVALID"
+	CodePasskeyNotFound         = "PASSKEY_NOT_FOUND"
+	CodePasskeyExists           = "PASSKEY_EXISTS"
+
+	// Passwordless
+	CodeLoginCodeInvalid = "LOGIN_CODE_INVALID"
+
+	// API tokens
+	CodeAPITokenInvalid  = "API_TOKEN_INVALID"
+	CodeAPITokenNotFound = "API_TOKEN_NOT_FOUND"
+	CodeAPITokenKind     = "API_TOKEN_KIND_INVALID"
+	CodeAPITokenScope    = "API_TOKEN_SCOPE_INVALID"
+	CodeAPITokenExpiry   = "API_TOKEN_EXPIRY_INVALID"
+
+	// Abuse protection
+	CodeRateLimited    = "RATE_LIMITED"
+	CodeAccountLocked  = "ACCOUNT_LOCKED"
+	CodeIPBlocked      = "IP_BLOCKED"
+	CodeIPRuleNotFound = "IP_RULE_NOT_FOUND"
+
 	// User
-	CodeUserNotFound  = "USER_NOT_FOUND"
-	CodeEmailConflict = "EMAIL_ALREADY_REGISTERED"
+	CodeUserNotFound    = "USER_NOT_FOUND"
+	CodeEmailConflict   = "EMAIL_ALREADY_REGISTERED"
+	CodeUserModified    = "USER_MODIFIED"
+	CodeAccountDisabled = "ACCOUNT_DISABLED"
 )
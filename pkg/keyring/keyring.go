@@ -0,0 +1,267 @@
+// Package keyring holds the JWT signing keys of the service. One key is
+// active and signs new tokens; the others are retiring and only verify, so
+// keys can be rotated without invalidating tokens already in circulation.
+package keyring
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey   = errors.New("unknown signing key")
+	ErrAlgMismatch  = errors.New("token algorithm does not match key")
+	ErrNoSigningKey = errors.New("no active signing key")
+)
+
+type Status string
+
+const (
+	StatusActive   Status = "active"   // signs and verifies
+	StatusRetiring Status = "retiring" // verifies only
+)
+
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	Status Status
+
+	sign   any // *rsa.PrivateKey | *ecdsa.PrivateKey | ed25519.PrivateKey | []byte; nil for public-only keys
+	verify any // *rsa.PublicKey | *ecdsa.PublicKey | ed25519.PublicKey | []byte
+}
+
+// Symmetric reports whether the key is an HMAC secret (never published).
+func (k *Key) Symmetric() bool {
+	_, ok := k.verify.([]byte)
+	return ok
+}
+
+type Keyring struct {
+	keys   map[string]*Key
+	active *Key
+}
+
+// NewHMAC builds a single-key HS256 keyring from a shared secret.
+func NewHMAC(kid string, secret []byte) *Keyring {
+	k := &Key{ID: kid, Method: jwt.SigningMethodHS256, Status: StatusActive, sign: secret, verify: secret}
+	return &Keyring{keys: map[string]*Key{kid: k}, active: k}
+}
+
+// LoadPEMFiles loads keys from a "kid=path,kid=path" spec. Private keys may be
+// PKCS#8, PKCS#1 (RSA) or SEC1 (EC); a PKIX public key can be listed to keep
+// verifying tokens after its private half was destroyed. activeKID selects
+// the signing key and must refer to a private key.
+func LoadPEMFiles(spec, activeKID string) (*Keyring, error) {
+	kr := &Keyring{keys: map[string]*Key{}}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok || kid == "" || path == "" {
+			return nil, fmt.Errorf("keyring: invalid entry %q, want kid=path", entry)
+		}
+		raw, err := os.ReadFile(strings.TrimSpace(path))
+		if err != nil {
+			return nil, fmt.Errorf("keyring: read %s: %w", kid, err)
+		}
+		k, err := ParsePEM(strings.TrimSpace(kid), raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := kr.Add(k); err != nil {
+			return nil, err
+		}
+	}
+	if err := kr.SetActive(activeKID); err != nil {
+		return nil, err
+	}
+	return kr, nil
+}
+
+// ParsePEM parses one PEM block into a retiring key; see SetActive.
+func ParsePEM(kid string, raw []byte) (*Key, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("keyring: %s: no PEM block", kid)
+	}
+	var (
+		parsed any
+		err    error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("keyring: %s: unsupported PEM type %q", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keyring: %s: %w", kid, err)
+	}
+	return newKey(kid, parsed)
+}
+
+func newKey(kid string, parsed any) (*Key, error) {
+	k := &Key{ID: kid, Status: StatusRetiring}
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		k.Method, k.sign, k.verify = jwt.SigningMethodRS256, key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.Method, k.verify = jwt.SigningMethodRS256, key
+	case *ecdsa.PrivateKey:
+		m, err := ecdsaMethod(key.Curve)
+		if err != nil {
+			return nil, fmt.Errorf("keyring: %s: %w", kid, err)
+		}
+		k.Method, k.sign, k.verify = m, key, &key.PublicKey
+	case *ecdsa.PublicKey:
+		m, err := ecdsaMethod(key.Curve)
+		if err != nil {
+			return nil, fmt.Errorf("keyring: %s: %w", kid, err)
+		}
+		k.Method, k.verify = m, key
+	case ed25519.PrivateKey:
+		k.Method, k.sign, k.verify = jwt.SigningMethodEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.Method, k.verify = jwt.SigningMethodEdDSA, key
+	default:
+		return nil, fmt.Errorf("keyring: %s: unsupported key type %T", kid, parsed)
+	}
+	return k, nil
+}
+
+func ecdsaMethod(curve elliptic.Curve) (jwt.SigningMethod, error) {
+	switch curve {
+	case elliptic.P256():
+		return jwt.SigningMethodES256, nil
+	case elliptic.P384():
+		return jwt.SigningMethodES384, nil
+	case elliptic.P521():
+		return jwt.SigningMethodES512, nil
+	default:
+		return nil, fmt.Errorf("unsupported curve %s", curve.Params().Name)
+	}
+}
+
+// Add registers a key. Keys are retiring until SetActive picks one.
+func (kr *Keyring) Add(k *Key) error {
+	if _, dup := kr.keys[k.ID]; dup {
+		return fmt.Errorf("keyring: duplicate kid %q", k.ID)
+	}
+	kr.keys[k.ID] = k
+	return nil
+}
+
+// SetActive makes kid the signing key and demotes the previous one.
+func (kr *Keyring) SetActive(kid string) error {
+	k, ok := kr.keys[kid]
+	if !ok {
+		return fmt.Errorf("keyring: active kid %q: %w", kid, ErrUnknownKey)
+	}
+	if k.sign == nil {
+		return fmt.Errorf("keyring: active kid %q has no private key", kid)
+	}
+	if kr.active != nil {
+		kr.active.Status = StatusRetiring
+	}
+	k.Status = StatusActive
+	kr.active = k
+	return nil
+}
+
+// Sign signs claims with the active key and sets the kid header.
+func (kr *Keyring) Sign(claims jwt.Claims) (string, error) {
+	if kr.active == nil {
+		return "", ErrNoSigningKey
+	}
+	tok := jwt.NewWithClaims(kr.active.Method, claims)
+	tok.Header["kid"] = kr.active.ID
+	return tok.SignedString(kr.active.sign)
+}
+
+// Asymmetric reports whether the active key is a key pair, so that anyone
+// holding the JWKS can verify what it signs.
+func (kr *Keyring) Asymmetric() bool {
+	return kr.active != nil && !kr.active.Symmetric()
+}
+
+// PublicAlgs lists the algorithms of the keys published in the JWKS.
+func (kr *Keyring) PublicAlgs() []string {
+	var algs []string
+	for _, k := range kr.keys {
+		if !k.Symmetric() && !slices.Contains(algs, k.Method.Alg()) {
+			algs = append(algs, k.Method.Alg())
+		}
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// Algs lists the algorithms of all keys, for jwt.WithValidMethods.
+func (kr *Keyring) Algs() []string {
+	var algs []string
+	for _, k := range kr.keys {
+		if !slices.Contains(algs, k.Method.Alg()) {
+			algs = append(algs, k.Method.Alg())
+		}
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// Keyfunc picks the verification key by the token's kid header. Tokens
+// without kid (issued before key ids existed) fall back to the active key.
+func (kr *Keyring) Keyfunc(t *jwt.Token) (any, error) {
+	k := kr.active
+	if kid, _ := t.Header["kid"].(string); kid != "" {
+		var ok bool
+		if k, ok = kr.keys[kid]; !ok {
+			return nil, ErrUnknownKey
+		}
+	}
+	if k == nil {
+		return nil, ErrUnknownKey
+	}
+	if t.Method == nil || t.Method.Alg() != k.Method.Alg() {
+		return nil, ErrAlgMismatch
+	}
+	return k.verify, nil
+}
+
+// Keys returns all keys ordered by kid.
+func (kr *Keyring) Keys() []*Key {
+	out := make([]*Key, 0, len(kr.keys))
+	for _, k := range kr.keys {
+		out = append(out, k)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Public returns the public half of an asymmetric key.
+func (k *Key) Public() crypto.PublicKey {
+	if k.Symmetric() {
+		return nil
+	}
+	return k.verify
+}
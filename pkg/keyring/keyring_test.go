@@ -0,0 +1,125 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writePEM(t *testing.T, dir, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func claims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{Subject: "u1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+}
+
+func parse(kr *Keyring, token string) error {
+	_, err := jwt.NewParser(jwt.WithValidMethods(kr.Algs())).ParseWithClaims(token, &jwt.RegisteredClaims{}, kr.Keyfunc)
+	return err
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	rsaPub, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+
+	rsaPath := writePEM(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	spec := "old=" + rsaPath + ",ec=" + writePEM(t, dir, "ec.pem", "EC PRIVATE KEY", ecDER) + ",ed=" + writePEM(t, dir, "ed.pem", "PRIVATE KEY", edDER)
+
+	before, err := LoadPEMFiles(spec, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := before.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rotate: ed signs, the old RSA key is kept as a public key only
+	spec = "old=" + writePEM(t, dir, "rsa.pub", "PUBLIC KEY", rsaPub) + ",ec=" + filepath.Join(dir, "ec.pem") + ",ed=" + filepath.Join(dir, "ed.pem")
+	after, err := LoadPEMFiles(spec, "ed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parse(after, oldToken); err != nil {
+		t.Errorf("Expected token signed before rotation to verify, got %v", err)
+	}
+	newToken, err := after.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, _, _ := jwt.NewParser().ParseUnverified(newToken, &jwt.RegisteredClaims{})
+	if tok.Header["kid"] != "ed" || tok.Method.Alg() != "EdDSA" {
+		t.Errorf("Expected EdDSA token with kid ed, got %v %s", tok.Header["kid"], tok.Method.Alg())
+	}
+	if err := parse(after, newToken); err != nil {
+		t.Errorf("Expected new token to verify, got %v", err)
+	}
+
+	if _, err := LoadPEMFiles(spec, "old"); err == nil {
+		t.Error("Expected a public-only key to be refused as the active key")
+	}
+
+	set := after.JWKS()
+	if len(set.Keys) != 3 || set.Keys[0].Kid != "ec" || set.Keys[0].Crv != "P-256" || set.Keys[2].Kty != "RSA" {
+		t.Errorf("Unexpected JWKS: %+v", set)
+	}
+	for _, jwk := range set.Keys {
+		pub, err := jwk.PublicKey()
+		if err != nil {
+			t.Fatalf("Expected %s to decode, got %v", jwk.Kid, err)
+		}
+		if !pub.(interface{ Equal(crypto.PublicKey) bool }).Equal(after.keys[jwk.Kid].Public()) {
+			t.Errorf("Expected %s to round-trip through the JWKS", jwk.Kid)
+		}
+	}
+}
+
+func TestKeyfuncRejects(t *testing.T) {
+	kr := NewHMAC("hs", []byte("secret"))
+	if len(kr.JWKS().Keys) != 0 {
+		t.Error("Expected HMAC secrets to stay out of the JWKS")
+	}
+
+	other := NewHMAC("other", []byte("secret"))
+	token, _ := other.Sign(claims())
+	if err := parse(kr, token); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey for a foreign kid, got %v", err)
+	}
+
+	// a token without kid falls back to the active key
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims()).SignedString([]byte("secret"))
+	if err := parse(kr, legacy); err != nil {
+		t.Errorf("Expected legacy token without kid to verify, got %v", err)
+	}
+
+	// same kid, different alg: never hand an HMAC secret to another verifier
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS384, claims())
+	forged.Header["kid"] = "hs"
+	s, _ := forged.SignedString([]byte("secret"))
+	if _, err := jwt.NewParser().ParseWithClaims(s, &jwt.RegisteredClaims{}, kr.Keyfunc); !errors.Is(err, ErrAlgMismatch) {
+		t.Errorf("Expected ErrAlgMismatch, got %v", err)
+	}
+}
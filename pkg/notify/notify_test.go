@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogNotifierRedactsData(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	n := NewLogNotifier(zap.New(core))
+	err := n.Send(context.Background(), Message{
+		Kind: KindPasswordReset,
+		To:   "a@example.com",
+		Data: map[string]string{"token": "pwr_secret", "link": "https://example.com/reset?token=pwr_secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if got := fmt.Sprint(fields["data_keys"]); got != "[link token]" {
+		t.Errorf("Expected the data keys to be logged, got %s", got)
+	}
+	for k, v := range fields {
+		if strings.Contains(fmt.Sprint(v), "pwr_secret") {
+			t.Errorf("Expected %s to leave out the secret, got %v", k, v)
+		}
+	}
+}
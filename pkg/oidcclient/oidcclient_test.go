@@ -0,0 +1,177 @@
+package oidcclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"seno-blackdragon/pkg/keyring"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIssuer is a local OpenID provider: discovery, JWKS and a token
+// endpoint that answers with the ID token in idToken.
+type mockIssuer struct {
+	srv     *httptest.Server
+	key     *ecdsa.PrivateKey
+	idToken string
+	form    url.Values
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.srv.URL,
+			"authorization_endpoint": m.srv.URL + "/authorize",
+			"token_endpoint":         m.srv.URL + "/token",
+			"jwks_uri":               m.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		b64 := base64.RawURLEncoding.EncodeToString
+		json.NewEncoder(w).Encode(keyring.JWKSet{Keys: []keyring.JWK{{
+			Kty: "EC", Kid: "k1", Use: "sig", Alg: "ES256", Crv: "P-256",
+			X: b64(key.X.FillBytes(make([]byte, 32))), Y: b64(key.Y.FillBytes(make([]byte, 32))),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		m.form = r.PostForm
+		if id, secret, _ := r.BasicAuth(); id != "client" || secret != "s3cret" || r.PostForm.Get("code") != "good-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(Token{AccessToken: "at", TokenType: "Bearer", IDToken: m.idToken})
+	})
+	m.srv = httptest.NewServer(mux)
+	t.Cleanup(m.srv.Close)
+	return m
+}
+
+func (m *mockIssuer) sign(t *testing.T, mutate func(c *Claims)) string {
+	t.Helper()
+	c := &Claims{
+		Nonce: "n-1",
+		Email: "a@example.com", EmailVerified: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.srv.URL,
+			Subject:   "sub-1",
+			Audience:  jwt.ClaimStrings{"client"},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+	if mutate != nil {
+		mutate(c)
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodES256, c)
+	tok.Header["kid"] = "k1"
+	s, err := tok.SignedString(m.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (m *mockIssuer) provider() *Provider {
+	return New(Config{Issuer: m.srv.URL + "/", ClientID: "client", ClientSecret: "s3cret", RedirectURL: "http://app/cb", Scopes: []string{"openid", "email"}}, nil)
+}
+
+func TestLoginFlow(t *testing.T) {
+	m := newMockIssuer(t)
+	p := m.provider()
+	ctx := context.Background()
+
+	raw, err := p.AuthCodeURL(ctx, "st", "n-1", S256Challenge("verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(raw)
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("client_id") != "client" || q.Get("state") != "st" || q.Get("scope") != "openid email" || q.Get("code_challenge_method") != "S256" {
+		t.Errorf("Unexpected authorization URL %s", raw)
+	}
+
+	m.idToken = m.sign(t, nil)
+	tok, err := p.Exchange(ctx, "good-code", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.form.Get("code_verifier") != "verifier" || m.form.Get("redirect_uri") != "http://app/cb" {
+		t.Errorf("Unexpected token request %v", m.form)
+	}
+	claims, err := p.VerifyIDToken(ctx, tok.IDToken, "n-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "sub-1" || claims.Email != "a@example.com" || !claims.EmailVerified {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+
+	if _, err := p.Exchange(ctx, "bad-code", "verifier"); !errors.Is(err, ErrExchange) {
+		t.Errorf("Expected ErrExchange, got %v", err)
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	m := newMockIssuer(t)
+	p := m.provider()
+	ctx := context.Background()
+
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	forged := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+		Issuer: m.srv.URL, Subject: "sub-1", Audience: jwt.ClaimStrings{"client"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	forged.Header["kid"] = "k1"
+	forgedRaw, _ := forged.SignedString(other)
+
+	cases := []struct {
+		name  string
+		raw   string
+		nonce string
+		want  error
+	}{
+		{"nonce mismatch", m.sign(t, nil), "n-2", ErrNonceMismatch},
+		{"wrong audience", m.sign(t, func(c *Claims) { c.Audience = jwt.ClaimStrings{"someone-else"} }), "n-1", ErrInvalidIDToken},
+		{"wrong issuer", m.sign(t, func(c *Claims) { c.Issuer = "https://evil.test" }), "n-1", ErrInvalidIDToken},
+		{"expired", m.sign(t, func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) }), "n-1", ErrInvalidIDToken},
+		{"bad signature", forgedRaw, "", ErrInvalidIDToken},
+		{"hmac", func() string {
+			s, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Issuer: m.srv.URL, Subject: "x"}).SignedString([]byte("client"))
+			return s
+		}(), "", ErrInvalidIDToken},
+	}
+	for _, tc := range cases {
+		if _, err := p.VerifyIDToken(ctx, tc.raw, tc.nonce); !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	m := newMockIssuer(t)
+	// same server, but the metadata names a different issuer
+	p := New(Config{Issuer: strings.Replace(m.srv.URL, "127.0.0.1", "localhost", 1), ClientID: "client"}, nil)
+	if _, err := p.AuthCodeURL(context.Background(), "st", "n", "c"); !errors.Is(err, ErrDiscovery) {
+		t.Errorf("Expected ErrDiscovery, got %v", err)
+	}
+}
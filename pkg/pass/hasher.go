@@ -52,7 +52,7 @@ func VerifyPassword(password string) error {
 	if !specialCharPresent {
 		return errors.New("special character missing")
 	}
-	if !(minPassLength <= passLen && passLen >= maxPassLength) {
+	if passLen < minPassLength || passLen > maxPassLength {
 		return fmt.Errorf("password length must be between %d to %d characters long", minPassLength, maxPassLength)
 	}
 	return nil
@@ -0,0 +1,27 @@
+package pass
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	cases := []struct {
+		password string
+		ok       bool
+	}{
+		{"Str0ng!pw", true},
+		{"Sh0rt!", false},
+		{"nouppercase1!", false},
+		{"NoDigits!!", false},
+		{"NoSpecial123", false},
+		{"L0ng!" + strings.Repeat("a", 59), true},
+		{"L0ng!" + strings.Repeat("a", 60), false},
+	}
+	for _, tc := range cases {
+		err := VerifyPassword(tc.password)
+		if (err == nil) != tc.ok {
+			t.Errorf("VerifyPassword(%q) = %v, want ok=%v", tc.password, err, tc.ok)
+		}
+	}
+}
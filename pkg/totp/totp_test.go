@@ -0,0 +1,57 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B vectors for SHA1, truncated to 6 digits.
+func TestCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tc := range cases {
+		got, err := Code(secret, time.Unix(tc.unix, 0))
+		if err != nil {
+			t.Fatalf("Code(%d) error: %v", tc.unix, err)
+		}
+		if got != tc.want {
+			t.Errorf("Code(%d) = %s, want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	prev, _ := Code(secret, now.Add(-Period*time.Second))
+	if step, ok := Validate(secret, prev, now, 1); !ok || step != Step(now)-1 {
+		t.Errorf("Expected previous step code to validate with skew 1, got step=%d ok=%v", step, ok)
+	}
+	if _, ok := Validate(secret, prev, now, 0); ok {
+		t.Error("Expected previous step code to fail without skew")
+	}
+	if _, ok := Validate(secret, "12345", now, 1); ok {
+		t.Error("Expected short code to fail")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("seno-blackdragon", "a@b.c", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/seno-blackdragon:a@b.c?") || !strings.Contains(uri, "secret=ABC") {
+		t.Errorf("Unexpected URI: %s", uri)
+	}
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -47,3 +49,12 @@ func PgUUIDFromString(s string) pgtype.UUID {
 	}
 	return PgUUIDFromUUID(u)
 }
+
+// TimePtrFromPgTimestamptz returns nil for a NULL timestamp.
+func TimePtrFromPgTimestamptz(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
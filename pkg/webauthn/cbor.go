@@ -0,0 +1,143 @@
+package webauthn
+
+import (
+	"errors"
+	"math"
+)
+
+var errCBOR = errors.New("webauthn: malformed CBOR")
+
+const cborMaxDepth = 16
+
+// decodeCBOR decodes the first CBOR item of b and returns it with the number
+// of bytes it took. Only what authenticators emit is supported: integers
+// (int64), byte and text strings, arrays ([]any), maps (map[any]any with
+// int64 or string keys), booleans and null. Tags are skipped; floats and
+// indefinite lengths are rejected.
+func decodeCBOR(b []byte) (any, int, error) {
+	d := &cborDecoder{b: b}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, d.off, nil
+}
+
+type cborDecoder struct {
+	b   []byte
+	off int
+}
+
+func (d *cborDecoder) value(depth int) (any, error) {
+	if depth > cborMaxDepth || d.off >= len(d.b) {
+		return nil, errCBOR
+	}
+	ib := d.b[d.off]
+	d.off++
+	major, info := ib>>5, ib&0x1f
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23: // null, undefined
+			return nil, nil
+		}
+		return nil, errCBOR
+	}
+	n, err := d.arg(info)
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case 0:
+		if n > math.MaxInt64 {
+			return nil, errCBOR
+		}
+		return int64(n), nil
+	case 1:
+		if n > math.MaxInt64 {
+			return nil, errCBOR
+		}
+		return -1 - int64(n), nil
+	case 2, 3:
+		if n > uint64(len(d.b)-d.off) {
+			return nil, errCBOR
+		}
+		raw := d.b[d.off : d.off+int(n)]
+		d.off += int(n)
+		if major == 3 {
+			return string(raw), nil
+		}
+		return append([]byte(nil), raw...), nil
+	case 4:
+		// every item takes at least one byte
+		if n > uint64(len(d.b)-d.off) {
+			return nil, errCBOR
+		}
+		arr := make([]any, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case 5:
+		if n > uint64(len(d.b)-d.off)/2 {
+			return nil, errCBOR
+		}
+		m := make(map[any]any, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, errCBOR
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	case 6:
+		return d.value(depth + 1)
+	}
+	return nil, errCBOR
+}
+
+// arg reads the argument of an initial byte: the value, length or count.
+func (d *cborDecoder) arg(info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	size := 0
+	switch info {
+	case 24:
+		size = 1
+	case 25:
+		size = 2
+	case 26:
+		size = 4
+	case 27:
+		size = 8
+	default:
+		return 0, errCBOR
+	}
+	if len(d.b)-d.off < size {
+		return 0, errCBOR
+	}
+	var n uint64
+	for _, c := range d.b[d.off : d.off+size] {
+		n = n<<8 | uint64(c)
+	}
+	d.off += size
+	return n, nil
+}
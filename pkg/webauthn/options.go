@@ -0,0 +1,60 @@
+package webauthn
+
+// Options for navigator.credentials.create and .get in the JSON form
+// browsers parse with PublicKeyCredential.parseCreationOptionsFromJSON and
+// parseRequestOptionsFromJSON.
+
+type RPEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          Base64URL `json:"id" swaggertype:"string"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"` // always "public-key"
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string    `json:"type"`
+	ID         Base64URL `json:"id" swaggertype:"string"`
+	Transports []string  `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`      // discouraged | preferred | required
+	UserVerification string `json:"userVerification"` // discouraged | preferred | required
+}
+
+type CreationOptions struct {
+	Challenge              Base64URL              `json:"challenge" swaggertype:"string"`
+	RP                     RPEntity               `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"` // milliseconds
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+type RequestOptions struct {
+	Challenge        Base64URL              `json:"challenge" swaggertype:"string"`
+	Timeout          int64                  `json:"timeout"` // milliseconds
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// PublicKeyParams lists Algorithms as creation parameters.
+func PublicKeyParams() []CredentialParameter {
+	params := make([]CredentialParameter, 0, len(Algorithms))
+	for _, alg := range Algorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+	return params
+}
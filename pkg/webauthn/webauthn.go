@@ -0,0 +1,336 @@
+// Package webauthn verifies WebAuthn registration and assertion ceremonies
+// (passkeys) for a relying party. Attestation statements are not verified:
+// the service asks for "none" and does not rely on the make or model of the
+// authenticator. ES256, EdDSA and RS256 credentials are supported.
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// COSE algorithm identifiers.
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// Algorithms lists the supported algorithms in order of preference.
+var Algorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// Authenticator data flags.
+const (
+	FlagUserPresent    byte = 0x01
+	FlagUserVerified   byte = 0x04
+	FlagBackupEligible byte = 0x08
+	FlagBackupState    byte = 0x10
+	FlagAttestedData   byte = 0x40
+	FlagExtensions     byte = 0x80
+)
+
+var (
+	ErrInvalidClientData = errors.New("webauthn: invalid client data")
+	ErrChallengeMismatch = errors.New("webauthn: challenge mismatch")
+	ErrOriginMismatch    = errors.New("webauthn: origin not allowed")
+	ErrInvalidAuthData   = errors.New("webauthn: invalid authenticator data")
+	ErrRPIDMismatch      = errors.New("webauthn: rp id hash mismatch")
+	ErrUserNotPresent    = errors.New("webauthn: user not present")
+	ErrUnsupportedKey    = errors.New("webauthn: unsupported public key")
+	ErrInvalidSignature  = errors.New("webauthn: invalid signature")
+	// ErrCounterRegressed means the signature counter did not grow, a sign
+	// that the authenticator was cloned.
+	ErrCounterRegressed = errors.New("webauthn: signature counter regressed")
+)
+
+// RelyingParty is this service as seen by authenticators. ID is the
+// registrable domain credentials are scoped to; Origins are the web origins
+// allowed to run ceremonies for it.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// Base64URL is binary data that travels as unpadded base64url in JSON, as in
+// the WebAuthn JSON serialisation used by browsers.
+type Base64URL []byte
+
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = raw
+	return nil
+}
+
+// ClientData is the collected client data the browser signs over.
+type ClientData struct {
+	Type        string `json:"type"` // webauthn.create | webauthn.get
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// ParseClientData decodes clientDataJSON. Callers use the challenge to find
+// the ceremony before verifying it.
+func ParseClientData(raw []byte) (*ClientData, error) {
+	var cd ClientData
+	if err := json.Unmarshal(raw, &cd); err != nil || cd.Challenge == "" {
+		return nil, ErrInvalidClientData
+	}
+	return &cd, nil
+}
+
+func (rp RelyingParty) checkClientData(raw []byte, typ string, challenge []byte) error {
+	cd, err := ParseClientData(raw)
+	if err != nil {
+		return err
+	}
+	if cd.Type != typ {
+		return ErrInvalidClientData
+	}
+	got, err := base64.RawURLEncoding.DecodeString(cd.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+	if cd.CrossOrigin || !slices.Contains(rp.Origins, cd.Origin) {
+		return ErrOriginMismatch
+	}
+	return nil
+}
+
+// AuthenticatorData is the parsed authenticator data structure.
+// CredentialID, AAGUID and PublicKey (COSE) are set on registration only.
+type AuthenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func ParseAuthenticatorData(b []byte) (*AuthenticatorData, error) {
+	if len(b) < 37 {
+		return nil, ErrInvalidAuthData
+	}
+	ad := &AuthenticatorData{
+		RPIDHash:  b[:32],
+		Flags:     b[32],
+		SignCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+	rest := b[37:]
+	if ad.Flags&FlagAttestedData != 0 {
+		if len(rest) < 18 {
+			return nil, ErrInvalidAuthData
+		}
+		ad.AAGUID = rest[:16]
+		n := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if n == 0 || n > 1023 || len(rest) < n {
+			return nil, ErrInvalidAuthData
+		}
+		ad.CredentialID, rest = rest[:n], rest[n:]
+		_, size, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidAuthData
+		}
+		ad.PublicKey, rest = rest[:size], rest[size:]
+	}
+	if ad.Flags&FlagExtensions != 0 {
+		_, size, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidAuthData
+		}
+		rest = rest[size:]
+	}
+	if len(rest) != 0 {
+		return nil, ErrInvalidAuthData
+	}
+	return ad, nil
+}
+
+func (ad *AuthenticatorData) UserVerified() bool   { return ad.Flags&FlagUserVerified != 0 }
+func (ad *AuthenticatorData) BackupEligible() bool { return ad.Flags&FlagBackupEligible != 0 }
+
+func (rp RelyingParty) checkAuthData(ad *AuthenticatorData) error {
+	want := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(ad.RPIDHash, want[:]) != 1 {
+		return ErrRPIDMismatch
+	}
+	if ad.Flags&FlagUserPresent == 0 {
+		return ErrUserNotPresent
+	}
+	return nil
+}
+
+// Credential is a verified new credential, ready to be stored.
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte // COSE_Key
+	AAGUID         []byte
+	SignCount      uint32
+	UserVerified   bool
+	BackupEligible bool
+}
+
+// VerifyRegistration checks the response to navigator.credentials.create
+// for challenge and returns the new credential.
+func (rp RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	if err := rp.checkClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+	v, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, err
+	}
+	att, ok := v.(map[any]any)
+	if !ok {
+		return nil, errCBOR
+	}
+	raw, ok := att["authData"].([]byte)
+	if !ok {
+		return nil, ErrInvalidAuthData
+	}
+	ad, err := ParseAuthenticatorData(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.checkAuthData(ad); err != nil {
+		return nil, err
+	}
+	if ad.CredentialID == nil {
+		return nil, ErrInvalidAuthData
+	}
+	if _, _, err := ParsePublicKey(ad.PublicKey); err != nil {
+		return nil, err
+	}
+	return &Credential{
+		ID:             ad.CredentialID,
+		PublicKey:      ad.PublicKey,
+		AAGUID:         ad.AAGUID,
+		SignCount:      ad.SignCount,
+		UserVerified:   ad.UserVerified(),
+		BackupEligible: ad.BackupEligible(),
+	}, nil
+}
+
+// Assertion is the outcome of a verified login.
+type Assertion struct {
+	SignCount    uint32
+	UserVerified bool
+}
+
+// VerifyAssertion checks the response to navigator.credentials.get for
+// challenge against the stored COSE public key and signature counter.
+func (rp RelyingParty) VerifyAssertion(challenge, clientDataJSON, authenticatorData, signature, publicKey []byte, storedCount uint32) (*Assertion, error) {
+	if err := rp.checkClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return nil, err
+	}
+	ad, err := ParseAuthenticatorData(authenticatorData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.checkAuthData(ad); err != nil {
+		return nil, err
+	}
+	pub, _, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(clientDataJSON)
+	msg := append(append([]byte(nil), authenticatorData...), sum[:]...)
+	if err := verifySignature(pub, msg, signature); err != nil {
+		return nil, err
+	}
+	// authenticators without a counter always send 0 (passkeys synced
+	// between devices do)
+	if (ad.SignCount != 0 || storedCount != 0) && ad.SignCount <= storedCount {
+		return nil, ErrCounterRegressed
+	}
+	return &Assertion{SignCount: ad.SignCount, UserVerified: ad.UserVerified()}, nil
+}
+
+// ParsePublicKey decodes a COSE_Key (RFC 9053) and returns it with its
+// algorithm.
+func ParsePublicKey(cose []byte) (crypto.PublicKey, int64, error) {
+	v, n, err := decodeCBOR(cose)
+	if err != nil || n != len(cose) {
+		return nil, 0, ErrUnsupportedKey
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, 0, ErrUnsupportedKey
+	}
+	kty, _ := m[int64(1)].(int64)
+	alg, _ := m[int64(3)].(int64)
+	crv, _ := m[int64(-1)].(int64)
+	switch {
+	case kty == 2 && alg == AlgES256 && crv == 1:
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if len(x) != 32 || len(y) != 32 {
+			return nil, 0, ErrUnsupportedKey
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return pub, alg, nil
+	case kty == 1 && alg == AlgEdDSA && crv == 6:
+		x, _ := m[int64(-2)].([]byte)
+		if len(x) != ed25519.PublicKeySize {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return ed25519.PublicKey(x), alg, nil
+	case kty == 3 && alg == AlgRS256:
+		nb, _ := m[int64(-1)].([]byte)
+		eb, _ := m[int64(-2)].([]byte)
+		if len(nb) < 256 || len(eb) == 0 || len(eb) > 4 {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: int(new(big.Int).SetBytes(eb).Int64())}, alg, nil
+	}
+	return nil, 0, fmt.Errorf("%w: kty %d alg %d", ErrUnsupportedKey, kty, alg)
+}
+
+func verifySignature(pub crypto.PublicKey, msg, sig []byte) error {
+	ok := false
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(msg)
+		ok = ecdsa.VerifyASN1(k, sum[:], sig)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, msg, sig)
+	case *rsa.PublicKey:
+		sum := sha256.Sum256(msg)
+		ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig) == nil
+	}
+	if !ok {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+)
+
+// encodeCBOR is the encoder side of decodeCBOR for the types the tests need.
+func encodeCBOR(v any) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 1<<8:
+			return []byte{major<<5 | 24, byte(n)}
+		case n < 1<<16:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		default:
+			return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+		}
+	}
+	switch x := v.(type) {
+	case int:
+		if x < 0 {
+			return head(1, uint64(-1-x))
+		}
+		return head(0, uint64(x))
+	case []byte:
+		return append(head(2, uint64(len(x))), x...)
+	case string:
+		return append(head(3, uint64(len(x))), x...)
+	case map[any]any:
+		keys := make([]any, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return string(encodeCBOR(keys[i])) < string(encodeCBOR(keys[j])) })
+		out := head(5, uint64(len(x)))
+		for _, k := range keys {
+			out = append(append(out, encodeCBOR(k)...), encodeCBOR(x[k])...)
+		}
+		return out
+	}
+	panic("unsupported")
+}
+
+// authenticator is a software passkey holding one P-256 credential.
+type authenticator struct {
+	rpID  string
+	key   *ecdsa.PrivateKey
+	id    []byte
+	count uint32
+	flags byte
+}
+
+func newAuthenticator(rpID string) *authenticator {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	return &authenticator{rpID: rpID, key: key, id: []byte("credential-1"), flags: FlagUserPresent | FlagUserVerified}
+}
+
+func (a *authenticator) cose() []byte {
+	return encodeCBOR(map[any]any{
+		1: 2, 3: int(AlgES256), -1: 1,
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+}
+
+func (a *authenticator) authData(attested bool) []byte {
+	h := sha256.Sum256([]byte(a.rpID))
+	flags := a.flags
+	if attested {
+		flags |= FlagAttestedData
+	}
+	out := append(h[:], flags)
+	out = binary.BigEndian.AppendUint32(out, a.count)
+	if attested {
+		out = append(out, make([]byte, 16)...)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(a.id)))
+		out = append(append(out, a.id...), a.cose()...)
+	}
+	return out
+}
+
+func clientData(typ string, challenge []byte, origin string) []byte {
+	b, _ := json.Marshal(ClientData{Type: typ, Challenge: base64.RawURLEncoding.EncodeToString(challenge), Origin: origin})
+	return b
+}
+
+func (a *authenticator) create(challenge []byte, origin string) (cdj, attObj []byte) {
+	cdj = clientData("webauthn.create", challenge, origin)
+	attObj = encodeCBOR(map[any]any{"fmt": "none", "attStmt": map[any]any{}, "authData": a.authData(true)})
+	return cdj, attObj
+}
+
+func (a *authenticator) get(challenge []byte, origin string) (cdj, authData, sig []byte) {
+	a.count++
+	cdj = clientData("webauthn.get", challenge, origin)
+	authData = a.authData(false)
+	sum := sha256.Sum256(cdj)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), sum[:]...))
+	sig, _ = ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	return cdj, authData, sig
+}
+
+var rp = RelyingParty{ID: "example.com", Name: "Example", Origins: []string{"https://example.com"}}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	a := newAuthenticator("example.com")
+	challenge := []byte("registration-challenge")
+
+	cdj, attObj := a.create(challenge, "https://example.com")
+	cred, err := rp.VerifyRegistration(challenge, cdj, attObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cred.ID) != "credential-1" || !cred.UserVerified || cred.SignCount != 0 {
+		t.Errorf("Unexpected credential %+v", cred)
+	}
+
+	login := []byte("login-challenge")
+	cdj, ad, sig := a.get(login, "https://example.com")
+	res, err := rp.VerifyAssertion(login, cdj, ad, sig, cred.PublicKey, cred.SignCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SignCount != 1 || !res.UserVerified {
+		t.Errorf("Unexpected assertion %+v", res)
+	}
+
+	// replaying the same counter looks like a cloned authenticator
+	a.count--
+	cdj, ad, sig = a.get(login, "https://example.com")
+	if _, err := rp.VerifyAssertion(login, cdj, ad, sig, cred.PublicKey, res.SignCount); !errors.Is(err, ErrCounterRegressed) {
+		t.Errorf("Expected ErrCounterRegressed, got %v", err)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	a := newAuthenticator("example.com")
+	challenge := []byte("challenge")
+	cdj, attObj := a.create(challenge, "https://example.com")
+	cred, err := rp.VerifyRegistration(challenge, cdj, attObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rp.VerifyRegistration([]byte("other"), cdj, attObj); !errors.Is(err, ErrChallengeMismatch) {
+		t.Errorf("Expected ErrChallengeMismatch, got %v", err)
+	}
+	cdj, attObj = a.create(challenge, "https://evil.example")
+	if _, err := rp.VerifyRegistration(challenge, cdj, attObj); !errors.Is(err, ErrOriginMismatch) {
+		t.Errorf("Expected ErrOriginMismatch, got %v", err)
+	}
+	other := newAuthenticator("evil.example")
+	cdj, attObj = other.create(challenge, "https://example.com")
+	if _, err := rp.VerifyRegistration(challenge, cdj, attObj); !errors.Is(err, ErrRPIDMismatch) {
+		t.Errorf("Expected ErrRPIDMismatch, got %v", err)
+	}
+
+	// a different key signing for the same credential id
+	impostor := newAuthenticator("example.com")
+	cdj, ad, sig := impostor.get(challenge, "https://example.com")
+	if _, err := rp.VerifyAssertion(challenge, cdj, ad, sig, cred.PublicKey, 0); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+	// client data of a registration replayed as an assertion
+	_, ad, sig = a.get(challenge, "https://example.com")
+	if _, err := rp.VerifyAssertion(challenge, clientData("webauthn.create", challenge, "https://example.com"), ad, sig, cred.PublicKey, 0); !errors.Is(err, ErrInvalidClientData) {
+		t.Errorf("Expected ErrInvalidClientData, got %v", err)
+	}
+	a.flags = 0
+	cdj, ad, sig = a.get(challenge, "https://example.com")
+	if _, err := rp.VerifyAssertion(challenge, cdj, ad, sig, cred.PublicKey, 0); !errors.Is(err, ErrUserNotPresent) {
+		t.Errorf("Expected ErrUserNotPresent, got %v", err)
+	}
+}
+
+func TestParsePublicKeyEd25519(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	key, alg, err := ParsePublicKey(encodeCBOR(map[any]any{1: 1, 3: int(AlgEdDSA), -1: 6, -2: []byte(pub)}))
+	if err != nil || alg != AlgEdDSA || !pub.Equal(key) {
+		t.Errorf("Expected Ed25519 key, got %v %d %v", key, alg, err)
+	}
+	if _, _, err := ParsePublicKey(encodeCBOR(map[any]any{1: 2, 3: int(AlgES256), -1: 1, -2: []byte{1}, -3: []byte{2}})); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("Expected ErrUnsupportedKey for a short EC key, got %v", err)
+	}
+}
+
+func TestDecodeCBORRejectsTruncated(t *testing.T) {
+	full := encodeCBOR(map[any]any{"authData": make([]byte, 64)})
+	for i := range len(full) {
+		if _, _, err := decodeCBOR(full[:i]); err == nil {
+			t.Fatalf("Expected an error for %d of %d bytes", i, len(full))
+		}
+	}
+}